@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// 坐标系：GPS 与 EXIF 为 WGS-84，高德、腾讯等国内地图为 GCJ-02（国测局加偏），百度地图为 BD-09
+const (
+	CoordWGS84 = "wgs84"
+	CoordGCJ02 = "gcj02"
+	CoordBD09  = "bd09"
+)
+
+// storageCoordSystem 为数据库中保存坐标使用的坐标系，写入前统一转换
+const storageCoordSystem = CoordWGS84
+
+// GCJ-02 加偏算法使用的克拉索夫斯基椭球参数
+const (
+	krasovskyA  = 6378245.0
+	krasovskyEE = 0.00669342162296594323
+	bd09XPi     = math.Pi * 3000.0 / 180.0
+)
+
+// parseCoordSystem 解析坐标系名称，忽略大小写与连字符，空值为 WGS-84
+func parseCoordSystem(value string) (string, error) {
+	switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(value)), "-", "") {
+	case "", "wgs84", "gps":
+		return CoordWGS84, nil
+	case "gcj02", "amap":
+		return CoordGCJ02, nil
+	case "bd09", "bd09ll", "baidu":
+		return CoordBD09, nil
+	}
+	return "", fmt.Errorf("invalid coord_system %q (wgs84, gcj02, bd09)", value)
+}
+
+// coordSystemParam 读取请求的 coord_system 参数
+func coordSystemParam(r *http.Request) (string, error) {
+	return parseCoordSystem(r.URL.Query().Get("coord_system"))
+}
+
+// convertCoordinate 将坐标从 from 坐标系转换到 to 坐标系，结果与数据库一致保留 5 位小数；
+// 国外坐标不加偏，原样返回
+func convertCoordinate(lon, lat float64, from, to string) (float64, float64) {
+	if from == to || outOfChina(lon, lat) {
+		return lon, lat
+	}
+	switch from {
+	case CoordWGS84:
+		lon, lat = wgs84ToGCJ02(lon, lat)
+	case CoordBD09:
+		lon, lat = bd09ToGCJ02(lon, lat)
+	}
+	switch to {
+	case CoordWGS84:
+		lon, lat = gcj02ToWGS84(lon, lat)
+	case CoordBD09:
+		lon, lat = gcj02ToBD09(lon, lat)
+	}
+	return roundCoordinate(lon, 5), roundCoordinate(lat, 5)
+}
+
+// outOfChina 粗略判断坐标是否在中国境外
+func outOfChina(lon, lat float64) bool {
+	return lon < 72.004 || lon > 137.8347 || lat < 0.8293 || lat > 55.8271
+}
+
+func wgs84ToGCJ02(lon, lat float64) (float64, float64) {
+	dLat := gcj02TransformLat(lon-105.0, lat-35.0)
+	dLon := gcj02TransformLon(lon-105.0, lat-35.0)
+	radLat := lat / 180.0 * math.Pi
+	magic := math.Sin(radLat)
+	magic = 1 - krasovskyEE*magic*magic
+	sqrtMagic := math.Sqrt(magic)
+	dLat = (dLat * 180.0) / ((krasovskyA * (1 - krasovskyEE)) / (magic * sqrtMagic) * math.Pi)
+	dLon = (dLon * 180.0) / (krasovskyA / sqrtMagic * math.Cos(radLat) * math.Pi)
+	return lon + dLon, lat + dLat
+}
+
+// gcj02ToWGS84 加偏没有解析逆运算，迭代逼近直到正向加偏结果与输入相差不足 1e-9 度
+func gcj02ToWGS84(lon, lat float64) (float64, float64) {
+	wgsLon, wgsLat := lon, lat
+	for i := 0; i < 30; i++ {
+		gcjLon, gcjLat := wgs84ToGCJ02(wgsLon, wgsLat)
+		dLon, dLat := gcjLon-lon, gcjLat-lat
+		wgsLon, wgsLat = wgsLon-dLon, wgsLat-dLat
+		if math.Abs(dLon) < 1e-9 && math.Abs(dLat) < 1e-9 {
+			break
+		}
+	}
+	return wgsLon, wgsLat
+}
+
+func gcj02ToBD09(lon, lat float64) (float64, float64) {
+	z := math.Sqrt(lon*lon+lat*lat) + 0.00002*math.Sin(lat*bd09XPi)
+	theta := math.Atan2(lat, lon) + 0.000003*math.Cos(lon*bd09XPi)
+	return z*math.Cos(theta) + 0.0065, z*math.Sin(theta) + 0.006
+}
+
+func bd09ToGCJ02(lon, lat float64) (float64, float64) {
+	x, y := lon-0.0065, lat-0.006
+	z := math.Sqrt(x*x+y*y) - 0.00002*math.Sin(y*bd09XPi)
+	theta := math.Atan2(y, x) - 0.000003*math.Cos(x*bd09XPi)
+	return z * math.Cos(theta), z * math.Sin(theta)
+}
+
+func gcj02TransformLat(x, y float64) float64 {
+	ret := -100.0 + 2.0*x + 3.0*y + 0.2*y*y + 0.1*x*y + 0.2*math.Sqrt(math.Abs(x))
+	ret += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	ret += (20.0*math.Sin(y*math.Pi) + 40.0*math.Sin(y/3.0*math.Pi)) * 2.0 / 3.0
+	ret += (160.0*math.Sin(y/12.0*math.Pi) + 320*math.Sin(y*math.Pi/30.0)) * 2.0 / 3.0
+	return ret
+}
+
+func gcj02TransformLon(x, y float64) float64 {
+	ret := 300.0 + x + 2.0*y + 0.1*x*x + 0.1*x*y + 0.1*math.Sqrt(math.Abs(x))
+	ret += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	ret += (20.0*math.Sin(x*math.Pi) + 40.0*math.Sin(x/3.0*math.Pi)) * 2.0 / 3.0
+	ret += (150.0*math.Sin(x/12.0*math.Pi) + 300.0*math.Sin(x/30.0*math.Pi)) * 2.0 / 3.0
+	return ret
+}
+
+// inCoordSystem 返回以 system 坐标系表示的站点，站点在内存中均为 storageCoordSystem
+func (s Station) inCoordSystem(system string) Station {
+	s.Longitude, s.Latitude = convertCoordinate(s.Longitude, s.Latitude, storageCoordSystem, system)
+	s.CoordSystem = system
+	return s
+}
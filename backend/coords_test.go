@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseCoordSystem(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"", CoordWGS84, false},
+		{"WGS-84", CoordWGS84, false},
+		{"gcj02", CoordGCJ02, false},
+		{"GCJ-02", CoordGCJ02, false},
+		{"bd09ll", CoordBD09, false},
+		{"BD-09", CoordBD09, false},
+		{"cgcs2000", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseCoordSystem(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseCoordSystem(%q) = %q, %v; want %q, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCoordinateTransforms(t *testing.T) {
+	// 参考值来自常用的 coordtransform 实现
+	tests := []struct {
+		name             string
+		transform        func(float64, float64) (float64, float64)
+		lon, lat         float64
+		wantLon, wantLat float64
+	}{
+		{"wgs84 to gcj02", wgs84ToGCJ02, 116.404, 39.915, 116.41024449916938, 39.91640428150164},
+		{"gcj02 to bd09", gcj02ToBD09, 116.404, 39.915, 116.41036949371029, 39.92133699351021},
+		{"bd09 to gcj02", bd09ToGCJ02, 116.404, 39.915, 116.39762729119315, 39.90865673957631},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lon, lat := tt.transform(tt.lon, tt.lat)
+			if math.Abs(lon-tt.wantLon) > 1e-9 || math.Abs(lat-tt.wantLat) > 1e-9 {
+				t.Errorf("got (%.9f, %.9f), want (%.9f, %.9f)", lon, lat, tt.wantLon, tt.wantLat)
+			}
+		})
+	}
+}
+
+func TestConvertCoordinateRoundTrip(t *testing.T) {
+	systems := []string{CoordWGS84, CoordGCJ02, CoordBD09}
+	// 无锡本站
+	lon, lat := 120.3544, 31.6128
+	for _, from := range systems {
+		for _, to := range systems {
+			x, y := convertCoordinate(lon, lat, from, to)
+			backLon, backLat := convertCoordinate(x, y, to, from)
+			// 5 位小数约 1 米，往返误差不超过两个单位
+			if math.Abs(backLon-lon) > 2e-5 || math.Abs(backLat-lat) > 2e-5 {
+				t.Errorf("%s -> %s -> %s: (%f, %f), want (%f, %f)", from, to, from, backLon, backLat, lon, lat)
+			}
+		}
+	}
+
+	// WGS-84 与 GCJ-02 在无锡相差约 500 米
+	gcjLon, gcjLat := convertCoordinate(lon, lat, CoordWGS84, CoordGCJ02)
+	if d := haversineDistance(lon, lat, gcjLon, gcjLat); d < 0.3 || d > 0.8 {
+		t.Errorf("unexpected WGS-84/GCJ-02 offset %.3f km", d)
+	}
+
+	// 境外坐标不加偏
+	if x, y := convertCoordinate(139.6917, 35.6895, CoordWGS84, CoordBD09); x != 139.6917 || y != 35.6895 {
+		t.Errorf("expected coordinates outside China unchanged, got (%f, %f)", x, y)
+	}
+}
+
+func TestStationInCoordSystem(t *testing.T) {
+	station := Station{ID: "58354", Longitude: 120.3544, Latitude: 31.6128, CoordSystem: CoordWGS84}
+	bd := station.inCoordSystem(CoordBD09)
+	if bd.CoordSystem != CoordBD09 || bd.Longitude == station.Longitude {
+		t.Errorf("unexpected station: %+v", bd)
+	}
+	if same := station.inCoordSystem(CoordWGS84); same != station {
+		t.Errorf("expected unchanged station, got %+v", same)
+	}
+}
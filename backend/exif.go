@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// maxCameraFieldLen 为 images.camera_make / camera_model 的列长度（VARCHAR(100)）
+const maxCameraFieldLen = 100
+
+// errNoEXIF 表示图片中没有可读取的 EXIF 数据
+var errNoEXIF = errors.New("no EXIF data")
+
+// EXIF / TIFF 标签
+const (
+	tagMake              = 0x010F
+	tagModel             = 0x0110
+	tagOrientation       = 0x0112
+	tagDateTime          = 0x0132
+	tagExifIFD           = 0x8769
+	tagGPSIFD            = 0x8825
+	tagDateTimeOriginal  = 0x9003
+	tagDateTimeDigitized = 0x9004
+	tagOffsetTimeOrig    = 0x9011
+	tagGPSLatitudeRef    = 0x0001
+	tagGPSLatitude       = 0x0002
+	tagGPSLongitudeRef   = 0x0003
+	tagGPSLongitude      = 0x0004
+)
+
+// EXIFData 为从图片 EXIF 中读取的拍摄信息，缺失字段为零值
+type EXIFData struct {
+	CaptureTime *time.Time
+	Latitude    *float64 // WGS-84
+	Longitude   *float64 // WGS-84
+	Make        string
+	Model       string
+	Orientation int
+}
+
+// readEXIFFile 读取 JPEG 或 PNG 文件中的 EXIF
+func readEXIFFile(imagePath string) (*EXIFData, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseEXIF(data)
+}
+
+// parseEXIF 定位图片中的 TIFF 块并解析常用标签
+func parseEXIF(data []byte) (*EXIFData, error) {
+	var tiff []byte
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		tiff = findJPEGExif(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		tiff = findPNGExif(data)
+	}
+	if tiff == nil {
+		return nil, errNoEXIF
+	}
+
+	t, err := newTIFFReader(tiff)
+	if err != nil {
+		return nil, err
+	}
+	ifd0, err := t.readIFD(t.firstIFD)
+	if err != nil {
+		return nil, err
+	}
+
+	exif := &EXIFData{
+		Make:        truncateRunes(t.ascii(ifd0[tagMake]), maxCameraFieldLen),
+		Model:       truncateRunes(t.ascii(ifd0[tagModel]), maxCameraFieldLen),
+		Orientation: int(t.uint(ifd0[tagOrientation])),
+	}
+
+	var exifIFD map[uint16]tiffEntry
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		// 子 IFD 解析失败时保留已读取的字段
+		exifIFD, _ = t.readIFD(t.uint(entry))
+	}
+	exif.CaptureTime = parseEXIFTime(t, ifd0, exifIFD)
+
+	if entry, ok := ifd0[tagGPSIFD]; ok {
+		if gps, err := t.readIFD(t.uint(entry)); err == nil {
+			lat, latOK := t.degrees(gps[tagGPSLatitude], t.ascii(gps[tagGPSLatitudeRef]), "S")
+			lon, lonOK := t.degrees(gps[tagGPSLongitude], t.ascii(gps[tagGPSLongitudeRef]), "W")
+			// 无定位时部分设备写入 0,0
+			if latOK && lonOK && (lat != 0 || lon != 0) && math.Abs(lat) <= 90 && math.Abs(lon) <= 180 {
+				exif.Latitude = &lat
+				exif.Longitude = &lon
+			}
+		}
+	}
+
+	return exif, nil
+}
+
+// parseEXIFTime 依次尝试 DateTimeOriginal、DateTimeDigitized、DateTime，无时区信息时按本地时间解析
+func parseEXIFTime(t *tiffReader, ifd0, exifIFD map[uint16]tiffEntry) *time.Time {
+	candidates := []string{
+		t.ascii(exifIFD[tagDateTimeOriginal]),
+		t.ascii(exifIFD[tagDateTimeDigitized]),
+		t.ascii(ifd0[tagDateTime]),
+	}
+	loc := time.Local
+	if offset := t.ascii(exifIFD[tagOffsetTimeOrig]); offset != "" {
+		if ref, err := time.Parse("-07:00", offset); err == nil {
+			_, secs := ref.Zone()
+			loc = time.FixedZone(offset, secs)
+		}
+	}
+	for _, value := range candidates {
+		if value == "" || strings.HasPrefix(value, "0000") {
+			continue
+		}
+		if parsed, err := time.ParseInLocation(exifTimeLayout, value, loc); err == nil {
+			return &parsed
+		}
+	}
+	return nil
+}
+
+// findJPEGExif 在 JPEG 的 APP1 段中查找 Exif 数据
+func findJPEGExif(data []byte) []byte {
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil
+		}
+		marker := data[pos+1]
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			pos += 2
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// 图像数据开始，EXIF 只会出现在此之前
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		segment := data[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		pos = end
+	}
+	return nil
+}
+
+// findPNGExif 查找 PNG 的 eXIf 块
+func findPNGExif(data []byte) []byte {
+	pos := 8
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 8 + length
+		if length < 0 || end+4 > len(data) {
+			return nil
+		}
+		if chunkType == "eXIf" {
+			return data[pos+8 : end]
+		}
+		if chunkType == "IEND" {
+			return nil
+		}
+		pos = end + 4 // 跳过 CRC
+	}
+	return nil
+}
+
+type tiffEntry struct {
+	typ   uint16
+	count uint32
+	value []byte // 已定位的原始数据
+}
+
+type tiffReader struct {
+	data     []byte
+	order    binary.ByteOrder
+	firstIFD uint32
+}
+
+func newTIFFReader(data []byte) (*tiffReader, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("TIFF header too short")
+	}
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid TIFF byte order %q", data[:2])
+	}
+	if t.order.Uint16(data[2:]) != 42 {
+		return nil, fmt.Errorf("invalid TIFF magic")
+	}
+	t.firstIFD = t.order.Uint32(data[4:])
+	return t, nil
+}
+
+// tiffTypeSize 返回 TIFF 字段类型的单个元素字节数
+func tiffTypeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11:
+		return 4
+	case 5, 10, 12:
+		return 8
+	}
+	return 0
+}
+
+func (t *tiffReader) readIFD(offset uint32) (map[uint16]tiffEntry, error) {
+	if int(offset)+2 > len(t.data) {
+		return nil, fmt.Errorf("IFD offset %d out of range", offset)
+	}
+	count := int(t.order.Uint16(t.data[offset:]))
+	entries := make(map[uint16]tiffEntry, count)
+	for i := 0; i < count; i++ {
+		pos := int(offset) + 2 + i*12
+		if pos+12 > len(t.data) {
+			return nil, fmt.Errorf("IFD entry %d out of range", i)
+		}
+		tag := t.order.Uint16(t.data[pos:])
+		entry := tiffEntry{
+			typ:   t.order.Uint16(t.data[pos+2:]),
+			count: t.order.Uint32(t.data[pos+4:]),
+		}
+		size := tiffTypeSize(entry.typ) * int(entry.count)
+		if size <= 0 || size > len(t.data) {
+			continue
+		}
+		if size <= 4 {
+			entry.value = t.data[pos+8 : pos+8+size]
+		} else {
+			start := int(t.order.Uint32(t.data[pos+8:]))
+			if start+size > len(t.data) {
+				continue
+			}
+			entry.value = t.data[start : start+size]
+		}
+		entries[tag] = entry
+	}
+	return entries, nil
+}
+
+func (t *tiffReader) ascii(entry tiffEntry) string {
+	if entry.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(entry.value), "\x00"))
+}
+
+func (t *tiffReader) uint(entry tiffEntry) uint32 {
+	switch entry.typ {
+	case 3:
+		if len(entry.value) >= 2 {
+			return uint32(t.order.Uint16(entry.value))
+		}
+	case 4:
+		if len(entry.value) >= 4 {
+			return t.order.Uint32(entry.value)
+		}
+	}
+	return 0
+}
+
+func (t *tiffReader) rational(entry tiffEntry, index int) (float64, bool) {
+	if entry.typ != 5 || len(entry.value) < (index+1)*8 {
+		return 0, false
+	}
+	num := t.order.Uint32(entry.value[index*8:])
+	den := t.order.Uint32(entry.value[index*8+4:])
+	if den == 0 {
+		return 0, false
+	}
+	return float64(num) / float64(den), true
+}
+
+// degrees 将度、分、秒三个有理数转换为十进制度，negativeRef 为南纬或西经的标识
+func (t *tiffReader) degrees(entry tiffEntry, ref, negativeRef string) (float64, bool) {
+	d, ok1 := t.rational(entry, 0)
+	m, ok2 := t.rational(entry, 1)
+	s, ok3 := t.rational(entry, 2)
+	if !ok1 || !ok2 || !ok3 {
+		return 0, false
+	}
+	value := d + m/60 + s/3600
+	if strings.EqualFold(ref, negativeRef) {
+		value = -value
+	}
+	return value, true
+}
+
+// applyEXIF 用 EXIF 拍摄时间补全或交叉校验 OCR 时间：
+// 模型未识别出时间时填入 EXIF 时间；两者相差超过 timeCheckConfig.EXIFTolerance 时降级为待复核
+func applyEXIF(result *OCRResult, exif *EXIFData) {
+	if exif == nil || exif.CaptureTime == nil {
+		return
+	}
+	exifTime := exif.CaptureTime.In(time.Local)
+
+	if result.Time == "" {
+		result.Time = exifTime.Format(ocrTimeLayout)
+		result.DateTime = &exifTime
+		result.Notes = appendNote(result.Notes, "时间取自 EXIF 拍摄时间")
+		return
+	}
+
+	if result.DateTime == nil {
+		return
+	}
+	diff := absDuration(result.DateTime.Sub(exifTime))
+	if diff <= timeCheckConfig.EXIFTolerance {
+		return
+	}
+	result.Notes = appendNote(result.Notes,
+		fmt.Sprintf("水印时间与 EXIF 拍摄时间 %s 相差 %s", exifTime.Format(ocrTimeLayout), diff.Round(time.Minute)))
+	if result.Status == OCRStatusStandard {
+		result.Status = OCRStatusUncertain
+		result.IsStandard = false
+	}
+}
+
+// truncateRunes 将字符串截断为最多 n 个字符，非法 UTF-8 字节先被移除
+func truncateRunes(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func appendNote(notes, note string) string {
+	if notes == "" {
+		return note
+	}
+	return notes + "；" + note
+}
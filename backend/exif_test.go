@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testIFDEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func asciiEntry(tag uint16, value string) testIFDEntry {
+	return testIFDEntry{tag, 2, uint32(len(value) + 1), append([]byte(value), 0)}
+}
+
+func shortEntry(order binary.ByteOrder, tag, value uint16) testIFDEntry {
+	data := make([]byte, 2)
+	order.PutUint16(data, value)
+	return testIFDEntry{tag, 3, 1, data}
+}
+
+func rationalEntry(order binary.ByteOrder, tag uint16, values ...[2]uint32) testIFDEntry {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		order.PutUint32(data[i*8:], v[0])
+		order.PutUint32(data[i*8+4:], v[1])
+	}
+	return testIFDEntry{tag, 5, uint32(len(values)), data}
+}
+
+// buildTestTIFF 依次排列 IFD0、Exif IFD、GPS IFD 与数据区
+func buildTestTIFF(order binary.ByteOrder, ifd0, exifIFD, gpsIFD []testIFDEntry) []byte {
+	ifdSize := func(n int) int { return 2 + 12*n + 4 }
+	pointer := func(tag uint16, offset int) testIFDEntry {
+		data := make([]byte, 4)
+		order.PutUint32(data, uint32(offset))
+		return testIFDEntry{tag, 4, 1, data}
+	}
+
+	n0 := len(ifd0)
+	if len(exifIFD) > 0 {
+		n0++
+	}
+	if len(gpsIFD) > 0 {
+		n0++
+	}
+	exifOffset := 8 + ifdSize(n0)
+	gpsOffset := exifOffset + ifdSize(len(exifIFD))
+	dataOffset := gpsOffset + ifdSize(len(gpsIFD))
+	if len(exifIFD) > 0 {
+		ifd0 = append(ifd0, pointer(tagExifIFD, exifOffset))
+	}
+	if len(gpsIFD) > 0 {
+		ifd0 = append(ifd0, pointer(tagGPSIFD, gpsOffset))
+	}
+
+	buf := make([]byte, dataOffset)
+	if order == binary.LittleEndian {
+		copy(buf, "II")
+	} else {
+		copy(buf, "MM")
+	}
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], 8)
+
+	var data []byte
+	writeIFD := func(offset int, entries []testIFDEntry) {
+		order.PutUint16(buf[offset:], uint16(len(entries)))
+		for i, e := range entries {
+			p := offset + 2 + 12*i
+			order.PutUint16(buf[p:], e.tag)
+			order.PutUint16(buf[p+2:], e.typ)
+			order.PutUint32(buf[p+4:], e.count)
+			if len(e.data) <= 4 {
+				copy(buf[p+8:], e.data)
+			} else {
+				order.PutUint32(buf[p+8:], uint32(dataOffset+len(data)))
+				data = append(data, e.data...)
+			}
+		}
+	}
+	writeIFD(8, ifd0)
+	writeIFD(exifOffset, exifIFD)
+	writeIFD(gpsOffset, gpsIFD)
+	return append(buf, data...)
+}
+
+// wrapJPEG 将 TIFF 数据放入 JPEG 的 APP1 段
+func wrapJPEG(tiff []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x04, 'J', 'F'}
+	jpeg = append(jpeg, segment...)
+	jpeg = append(jpeg, payload...)
+	return append(jpeg, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9)
+}
+
+func TestParseEXIF(t *testing.T) {
+	le := binary.LittleEndian
+	full := wrapJPEG(buildTestTIFF(le,
+		[]testIFDEntry{asciiEntry(tagMake, "HUAWEI"), asciiEntry(tagModel, "P40"), shortEntry(le, tagOrientation, 6)},
+		[]testIFDEntry{asciiEntry(tagDateTimeOriginal, "2024:01:15 14:30:05"), asciiEntry(tagOffsetTimeOrig, "+08:00")},
+		[]testIFDEntry{
+			asciiEntry(tagGPSLatitudeRef, "N"),
+			rationalEntry(le, tagGPSLatitude, [2]uint32{31, 1}, [2]uint32{30, 1}, [2]uint32{3600, 100}),
+			asciiEntry(tagGPSLongitudeRef, "E"),
+			rationalEntry(le, tagGPSLongitude, [2]uint32{120, 1}, [2]uint32{18, 1}, [2]uint32{0, 1}),
+		},
+	))
+
+	exif, err := parseEXIF(full)
+	if err != nil {
+		t.Fatalf("parseEXIF returned error: %v", err)
+	}
+	if exif.Make != "HUAWEI" || exif.Model != "P40" || exif.Orientation != 6 {
+		t.Errorf("unexpected camera fields: %+v", exif)
+	}
+	want := time.Date(2024, 1, 15, 6, 30, 5, 0, time.UTC)
+	if exif.CaptureTime == nil || !exif.CaptureTime.Equal(want) {
+		t.Errorf("capture time = %v, want %v", exif.CaptureTime, want)
+	}
+	if exif.Latitude == nil || math.Abs(*exif.Latitude-31.51) > 1e-9 {
+		t.Errorf("latitude = %v, want 31.51", exif.Latitude)
+	}
+	if exif.Longitude == nil || math.Abs(*exif.Longitude-120.3) > 1e-9 {
+		t.Errorf("longitude = %v, want 120.3", exif.Longitude)
+	}
+}
+
+func TestParseEXIFBigEndianSouthWest(t *testing.T) {
+	be := binary.BigEndian
+	data := wrapJPEG(buildTestTIFF(be,
+		[]testIFDEntry{asciiEntry(tagDateTime, "2023:07:09 09:30:00")},
+		nil,
+		[]testIFDEntry{
+			asciiEntry(tagGPSLatitudeRef, "S"),
+			rationalEntry(be, tagGPSLatitude, [2]uint32{10, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+			asciiEntry(tagGPSLongitudeRef, "W"),
+			rationalEntry(be, tagGPSLongitude, [2]uint32{20, 1}, [2]uint32{30, 1}, [2]uint32{0, 1}),
+		},
+	))
+
+	exif, err := parseEXIF(data)
+	if err != nil {
+		t.Fatalf("parseEXIF returned error: %v", err)
+	}
+	if exif.CaptureTime == nil || exif.CaptureTime.Format("2006-01-02 15:04:05") != "2023-07-09 09:30:00" {
+		t.Errorf("expected DateTime fallback, got %v", exif.CaptureTime)
+	}
+	if exif.Latitude == nil || *exif.Latitude != -10 || exif.Longitude == nil || *exif.Longitude != -20.5 {
+		t.Errorf("unexpected coordinates: %v, %v", exif.Latitude, exif.Longitude)
+	}
+}
+
+func TestParseEXIFTruncatesCameraFields(t *testing.T) {
+	longMake := strings.Repeat("华", maxCameraFieldLen+20)
+	model := "P40\xff Pro"
+	exif, err := parseEXIF(wrapJPEG(buildTestTIFF(binary.LittleEndian,
+		[]testIFDEntry{asciiEntry(tagMake, longMake), asciiEntry(tagModel, model)}, nil, nil)))
+	if err != nil {
+		t.Fatalf("parseEXIF returned error: %v", err)
+	}
+	if exif.Make != strings.Repeat("华", maxCameraFieldLen) {
+		t.Errorf("expected make truncated to %d characters, got %d", maxCameraFieldLen, len([]rune(exif.Make)))
+	}
+	if exif.Model != "P40 Pro" {
+		t.Errorf("expected invalid UTF-8 removed from model, got %q", exif.Model)
+	}
+}
+
+func TestParseEXIFMissing(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not an image", []byte("fake-image")},
+		{"jpeg without app1", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9}},
+		{"truncated segment", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x40, 0x00, 'E'}},
+	}
+	for _, tt := range tests {
+		if _, err := parseEXIF(tt.data); !errors.Is(err, errNoEXIF) {
+			t.Errorf("%s: expected errNoEXIF, got %v", tt.name, err)
+		}
+	}
+
+	// GPS 为 0,0 视为无定位
+	le := binary.LittleEndian
+	zero := [2]uint32{0, 1}
+	exif, err := parseEXIF(wrapJPEG(buildTestTIFF(le, nil, nil, []testIFDEntry{
+		rationalEntry(le, tagGPSLatitude, zero, zero, zero),
+		rationalEntry(le, tagGPSLongitude, zero, zero, zero),
+	})))
+	if err != nil {
+		t.Fatalf("parseEXIF returned error: %v", err)
+	}
+	if exif.Latitude != nil || exif.CaptureTime != nil {
+		t.Errorf("expected empty fields, got %+v", exif)
+	}
+}
+
+func TestApplyEXIF(t *testing.T) {
+	capture := time.Date(2024, 1, 15, 14, 30, 0, 0, time.Local)
+	exif := &EXIFData{CaptureTime: &capture}
+
+	tests := []struct {
+		name       string
+		result     OCRResult
+		wantTime   string
+		wantStatus string
+		wantNote   string
+	}{
+		{
+			name:       "fills missing time",
+			result:     OCRResult{Status: OCRStatusNonStandard},
+			wantTime:   "2024-01-15 14:30:00",
+			wantStatus: OCRStatusNonStandard,
+			wantNote:   "EXIF",
+		},
+		{
+			name:       "agrees within tolerance",
+			result:     OCRResult{Time: "2024-01-15 15:00:00", Status: OCRStatusStandard, IsStandard: true},
+			wantTime:   "2024-01-15 15:00:00",
+			wantStatus: OCRStatusStandard,
+		},
+		{
+			name:       "disagreement downgrades",
+			result:     OCRResult{Time: "2023-01-15 14:30:00", Status: OCRStatusStandard, IsStandard: true, Notes: "水印清晰"},
+			wantTime:   "2023-01-15 14:30:00",
+			wantStatus: OCRStatusUncertain,
+			wantNote:   "相差",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.result
+			if result.Time != "" {
+				if err := result.setTime(result.Time); err != nil {
+					t.Fatalf("setTime(%q) returned error: %v", result.Time, err)
+				}
+			}
+			applyEXIF(&result, exif)
+			if result.Time != tt.wantTime || result.Status != tt.wantStatus {
+				t.Errorf("got time %q status %q, want %q %q", result.Time, result.Status, tt.wantTime, tt.wantStatus)
+			}
+			if result.IsStandard != (result.Status == OCRStatusStandard) {
+				t.Errorf("IsStandard out of sync with status: %+v", result)
+			}
+			if tt.wantNote == "" && result.Notes != tt.result.Notes {
+				t.Errorf("notes changed unexpectedly: %q", result.Notes)
+			}
+			if tt.wantNote != "" && !strings.Contains(result.Notes, tt.wantNote) {
+				t.Errorf("notes %q missing %q", result.Notes, tt.wantNote)
+			}
+		})
+	}
+}
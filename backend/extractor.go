@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultVLMProvider    = "qwen"
+	defaultOpenAIModel    = "gpt-4o-mini"
+	defaultOllamaBaseURL  = "http://127.0.0.1:11434"
+	defaultOllamaModel    = "qwen2.5vl"
+	defaultVLMTimeoutSecs = 60
+
+	defaultVLMCallTimeoutSecs = 180
+)
+
+// errExtractorNotConfigured 表示后端缺少必需配置（如 API Key），此时跳过 OCR
+var errExtractorNotConfigured = errors.New("metadata extractor not configured")
+
+// MetadataExtractor 从图片中抽取拍摄时间与地点
+type MetadataExtractor interface {
+	Name() string
+	ExtractMetadata(ctx context.Context, imagePath string) (*vlmStructuredResult, error)
+}
+
+// ExtractorConfig 描述一个 VLM 后端的配置
+type ExtractorConfig struct {
+	Provider       string
+	APIKey         string
+	BaseURL        string
+	Model          string
+	EnableThinking bool
+	ThinkingBudget int
+	Timeout        time.Duration // 单次 HTTP 请求超时
+	CallTimeout    time.Duration // 整次识别（含重试与排队）的截止时间，0 表示仅受调用方 ctx 约束
+	FakeResponse   string        // fake 后端固定返回的 JSON
+
+	Preprocess ImagePreprocessConfig // 发送前的裁剪与缩放
+
+	Retry          RetryPolicy // 429/5xx 与网络错误的重试策略
+	RateLimit      float64     // 每秒最多发起的请求数，0 表示不限制
+	RateBurst      int         // 令牌桶容量
+	MaxConcurrency int         // 同时进行的请求上限，0 表示不限制
+
+	limiter *vlmLimiter
+}
+
+// ExtractorFactory 根据配置创建 MetadataExtractor
+type ExtractorFactory func(cfg ExtractorConfig) (MetadataExtractor, error)
+
+var extractorRegistry = map[string]ExtractorFactory{}
+
+// metadataExtractor 为当前生效的后端，nil 表示未配置
+var metadataExtractor MetadataExtractor
+
+// vlmCallTimeout 为 ProcessImageOCR 单次识别的截止时间
+var vlmCallTimeout time.Duration
+
+func init() {
+	RegisterExtractor("qwen", newQwenExtractor)
+	RegisterExtractor("openai", newOpenAIExtractor)
+	RegisterExtractor("ollama", newOllamaExtractor)
+	RegisterExtractor("fake", newFakeExtractor)
+}
+
+// RegisterExtractor 注册一个 VLM 后端，同名注册会覆盖旧值
+func RegisterExtractor(name string, factory ExtractorFactory) {
+	extractorRegistry[strings.ToLower(name)] = factory
+}
+
+// registeredExtractors 返回已注册的后端名称
+func registeredExtractors() []string {
+	names := make([]string, 0, len(extractorRegistry))
+	for name := range extractorRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewMetadataExtractor 按 cfg.Provider 创建后端
+func NewMetadataExtractor(cfg ExtractorConfig) (MetadataExtractor, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	if provider == "" {
+		provider = defaultVLMProvider
+	}
+	factory, ok := extractorRegistry[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown VLM provider %q (available: %s)",
+			cfg.Provider, strings.Join(registeredExtractors(), ", "))
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultVLMTimeoutSecs * time.Second
+	}
+	// 同一后端实例的所有调用方共享限流器
+	cfg.limiter = newVLMLimiter(cfg.RateLimit, cfg.RateBurst, cfg.MaxConcurrency)
+	return factory(cfg)
+}
+
+// loadExtractorConfig 从环境变量读取 VLM 配置。
+// VLM_PROVIDER 选择后端，其余字段按后端读取对应前缀（QWEN_VLM_、OPENAI_VLM_、OLLAMA_VLM_、FAKE_VLM_）。
+func loadExtractorConfig() ExtractorConfig {
+	provider := strings.ToLower(getEnv("VLM_PROVIDER", defaultVLMProvider))
+	prefix := strings.ToUpper(provider) + "_VLM_"
+
+	return ExtractorConfig{
+		Provider:       provider,
+		APIKey:         getEnv(prefix+"API_KEY", ""),
+		BaseURL:        getEnv(prefix+"BASE_URL", ""),
+		Model:          getEnv(prefix+"MODEL", ""),
+		EnableThinking: strings.EqualFold(getEnv(prefix+"ENABLE_THINKING", ""), "true"),
+		ThinkingBudget: getEnvInt(prefix+"THINKING_BUDGET", 0),
+		Timeout:        time.Duration(getEnvInt("VLM_TIMEOUT_SECONDS", defaultVLMTimeoutSecs)) * time.Second,
+		CallTimeout:    time.Duration(getEnvInt("VLM_CALL_TIMEOUT_SECONDS", defaultVLMCallTimeoutSecs)) * time.Second,
+		FakeResponse:   getEnv(prefix+"RESPONSE", ""),
+		Retry: RetryPolicy{
+			MaxRetries:    getEnvInt("VLM_MAX_RETRIES", defaultVLMMaxRetries),
+			BaseDelay:     time.Duration(getEnvInt("VLM_RETRY_BASE_MS", defaultVLMRetryBaseMS)) * time.Millisecond,
+			MaxDelay:      time.Duration(getEnvInt("VLM_RETRY_MAX_MS", defaultVLMRetryMaxMS)) * time.Millisecond,
+			MaxRetryAfter: defaultVLMMaxRetryAfter,
+		},
+		RateLimit:      getEnvFloat("VLM_RATE_LIMIT", 0),
+		RateBurst:      getEnvInt("VLM_RATE_BURST", 1),
+		MaxConcurrency: getEnvInt("VLM_MAX_CONCURRENCY", 0),
+		Preprocess:     loadImagePreprocessConfig(),
+	}
+}
+
+// initExtractor 在启动时根据配置创建 VLM 后端，未配置时 OCR 将被跳过
+func initExtractor() error {
+	cfg := loadExtractorConfig()
+	vlmCallTimeout = cfg.CallTimeout
+	extractor, err := NewMetadataExtractor(cfg)
+	if errors.Is(err, errExtractorNotConfigured) {
+		slog.Warn("VLM provider not configured, OCR processing disabled", "provider", cfg.Provider)
+		metadataExtractor = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	metadataExtractor = extractor
+	slog.Info("VLM provider initialized", "provider", extractor.Name())
+	return nil
+}
+
+func newQwenExtractor(cfg ExtractorConfig) (MetadataExtractor, error) {
+	if cfg.APIKey == "" {
+		return nil, errExtractorNotConfigured
+	}
+	client := NewQwenVLMClient(cfg.APIKey, cfg.BaseURL, cfg.Model, cfg.EnableThinking, cfg.ThinkingBudget)
+	client.transport = newVLMTransport(cfg)
+	client.preprocess = cfg.Preprocess
+	return client, nil
+}
+
+// OpenAICompatibleClient 调用任意兼容 OpenAI chat completions 协议的视觉模型
+type OpenAICompatibleClient struct {
+	APIKey     string
+	BaseURL    string
+	Model      string
+	transport  vlmTransport
+	preprocess ImagePreprocessConfig
+}
+
+func newOpenAIExtractor(cfg ExtractorConfig) (MetadataExtractor, error) {
+	if cfg.BaseURL == "" {
+		return nil, errExtractorNotConfigured
+	}
+	model := cfg.Model
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	return &OpenAICompatibleClient{
+		APIKey:     cfg.APIKey,
+		BaseURL:    cfg.BaseURL,
+		Model:      model,
+		transport:  newVLMTransport(cfg),
+		preprocess: cfg.Preprocess,
+	}, nil
+}
+
+// Name 返回后端名称
+func (c *OpenAICompatibleClient) Name() string {
+	return "openai"
+}
+
+// ExtractMetadata 调用 chat completions 接口识别时间、地点
+func (c *OpenAICompatibleClient) ExtractMetadata(ctx context.Context, imagePath string) (*vlmStructuredResult, error) {
+	dataURL, err := encodeImageToDataURL(imagePath, c.preprocess)
+	if err != nil {
+		return nil, err
+	}
+
+	completion, err := postChatCompletion(ctx, c.transport, c.BaseURL, c.APIKey, qwenChatRequest{
+		Model:    c.Model,
+		Messages: buildVisionMessages(dataURL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseVLMJSON(completion.Content)
+	if err != nil {
+		return nil, &vlmResponseError{Model: c.Model, Usage: completion.Usage, RawResponse: completion.Raw, Err: err}
+	}
+	result.Model = c.Model
+	result.Usage = completion.Usage
+	result.RawResponse = completion.Raw
+	return result, nil
+}
+
+// OllamaClient 调用本地 Ollama 风格的 /api/chat 接口
+type OllamaClient struct {
+	BaseURL    string
+	Model      string
+	transport  vlmTransport
+	preprocess ImagePreprocessConfig
+}
+
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   string          `json:"format,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error,omitempty"`
+}
+
+func newOllamaExtractor(cfg ExtractorConfig) (MetadataExtractor, error) {
+	client := &OllamaClient{
+		BaseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		Model:      cfg.Model,
+		transport:  newVLMTransport(cfg),
+		preprocess: cfg.Preprocess,
+	}
+	if client.BaseURL == "" {
+		client.BaseURL = defaultOllamaBaseURL
+	}
+	if client.Model == "" {
+		client.Model = defaultOllamaModel
+	}
+	return client, nil
+}
+
+// Name 返回后端名称
+func (c *OllamaClient) Name() string {
+	return "ollama"
+}
+
+// ExtractMetadata 调用本地模型识别时间、地点
+func (c *OllamaClient) ExtractMetadata(ctx context.Context, imagePath string) (*vlmStructuredResult, error) {
+	encoded, err := encodeImageToBase64(imagePath, c.preprocess)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(ollamaChatRequest{
+		Model: c.Model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: vlmSystemPrompt},
+			{Role: "user", Content: vlmUserInstruction, Images: []string{encoded}},
+		},
+		Stream: false,
+		Format: "json",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Ollama request: %w", err)
+	}
+
+	status, body, err := c.transport.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/chat", bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Ollama request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Ollama API: %w", err)
+	}
+
+	if status >= http.StatusBadRequest {
+		return nil, fmt.Errorf("Ollama API error: status %d, body %s", status, logRedactor.Redact(string(body)))
+	}
+
+	var chatResp ollamaChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode Ollama response: %w", err)
+	}
+	if chatResp.Error != "" {
+		return nil, fmt.Errorf("Ollama API error: %s", chatResp.Error)
+	}
+
+	rawContent := strings.TrimSpace(chatResp.Message.Content)
+	if rawContent == "" {
+		return nil, fmt.Errorf("Ollama API returned empty content")
+	}
+
+	usage := vlmUsage{
+		PromptTokens:     chatResp.PromptEvalCount,
+		CompletionTokens: chatResp.EvalCount,
+		TotalTokens:      chatResp.PromptEvalCount + chatResp.EvalCount,
+	}
+	result, err := parseVLMJSON(rawContent)
+	if err != nil {
+		return nil, &vlmResponseError{Model: c.Model, Usage: usage, RawResponse: string(body), Err: err}
+	}
+	result.Model = c.Model
+	result.Usage = usage
+	result.RawResponse = string(body)
+	return result, nil
+}
+
+// FakeExtractor 返回固定结果，用于测试和无网络环境
+type FakeExtractor struct {
+	Result vlmStructuredResult
+	Err    error
+}
+
+const defaultFakeResponse = `{"time":"2024-01-15 14:30:00","location":"江苏省无锡市羊尖镇","confidence":0.9,"notes":"fake"}`
+
+func newFakeExtractor(cfg ExtractorConfig) (MetadataExtractor, error) {
+	raw := cfg.FakeResponse
+	if raw == "" {
+		raw = defaultFakeResponse
+	}
+	result, err := parseVLMJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fake VLM response: %w", err)
+	}
+	return &FakeExtractor{Result: *result}, nil
+}
+
+// Name 返回后端名称
+func (f *FakeExtractor) Name() string {
+	return "fake"
+}
+
+// ExtractMetadata 返回预设结果，不读取图片内容
+func (f *FakeExtractor) ExtractMetadata(ctx context.Context, imagePath string) (*vlmStructuredResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	result := f.Result
+	if result.Model == "" {
+		result.Model = "fake"
+	}
+	return &result, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestImage(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sample.jpg")
+	if err := os.WriteFile(path, []byte("fake-image"), 0o644); err != nil {
+		t.Fatalf("failed to write test image: %v", err)
+	}
+	return path
+}
+
+func TestNewMetadataExtractor(t *testing.T) {
+	t.Run("unknown provider", func(t *testing.T) {
+		if _, err := NewMetadataExtractor(ExtractorConfig{Provider: "nope"}); err == nil {
+			t.Fatalf("expected error for unknown provider")
+		}
+	})
+
+	t.Run("qwen without key", func(t *testing.T) {
+		_, err := NewMetadataExtractor(ExtractorConfig{Provider: "qwen"})
+		if !errors.Is(err, errExtractorNotConfigured) {
+			t.Fatalf("expected errExtractorNotConfigured, got %v", err)
+		}
+	})
+
+	t.Run("provider names", func(t *testing.T) {
+		for _, name := range []string{"qwen", "openai", "ollama", "fake"} {
+			extractor, err := NewMetadataExtractor(ExtractorConfig{
+				Provider: name,
+				APIKey:   "key",
+				BaseURL:  "http://127.0.0.1",
+			})
+			if err != nil {
+				t.Fatalf("NewMetadataExtractor(%s) returned error: %v", name, err)
+			}
+			if extractor.Name() != name {
+				t.Errorf("extractor.Name() = %q, want %q", extractor.Name(), name)
+			}
+		}
+	})
+}
+
+func TestOpenAICompatibleClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("unexpected Authorization header: %q", got)
+		}
+		var req qwenChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if req.Model != "vision-model" || req.EnableThinking != nil {
+			t.Errorf("unexpected request: %+v", req)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"{\"time\":\"2024-01-15 14:30\",\"location\":\"无锡\",\"confidence\":0.7}"}}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`))
+	}))
+	defer server.Close()
+
+	extractor, err := NewMetadataExtractor(ExtractorConfig{
+		Provider: "openai",
+		APIKey:   "secret",
+		BaseURL:  server.URL,
+		Model:    "vision-model",
+	})
+	if err != nil {
+		t.Fatalf("NewMetadataExtractor returned error: %v", err)
+	}
+
+	result, err := extractor.ExtractMetadata(context.Background(), writeTestImage(t))
+	if err != nil {
+		t.Fatalf("ExtractMetadata returned error: %v", err)
+	}
+	if result.Location != "无锡" || result.Confidence != 0.7 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.Model != "vision-model" || result.Usage.TotalTokens != 15 || result.RawResponse == "" {
+		t.Errorf("response metadata not populated: %+v", result)
+	}
+}
+
+func TestUnparsableResponseKeepsRawBody(t *testing.T) {
+	body := `{"choices":[{"message":{"content":"图片中没有水印"}}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	previous := metadataExtractor
+	defer func() { metadataExtractor = previous }()
+	metadataExtractor, _ = NewMetadataExtractor(ExtractorConfig{Provider: "openai", BaseURL: server.URL, Model: "vision-model"})
+
+	_, err := ProcessImageOCR(context.Background(), writeTestImage(t))
+	var attemptErr *OCRAttemptError
+	if !errors.As(err, &attemptErr) {
+		t.Fatalf("expected OCRAttemptError, got %v", err)
+	}
+	attempt := newOCRAttempt(OCRJob{ID: 1, ImageID: 2}, nil, err)
+	if attempt.RawResponse != body || attempt.TotalTokens != 15 || attempt.Model != "vision-model" ||
+		attempt.Error == "" {
+		t.Errorf("unparsable response not recorded: %+v", attempt)
+	}
+}
+
+func TestOllamaClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var req ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if len(req.Messages) != 2 || len(req.Messages[1].Images) != 1 {
+			t.Errorf("expected image in user message: %+v", req.Messages)
+		}
+		w.Write([]byte(`{"message":{"content":"{\"time\":\"2024-01-15\",\"location\":\"江阴\",\"confidence\":0.5}"}}`))
+	}))
+	defer server.Close()
+
+	extractor, err := NewMetadataExtractor(ExtractorConfig{Provider: "ollama", BaseURL: server.URL + "/"})
+	if err != nil {
+		t.Fatalf("NewMetadataExtractor returned error: %v", err)
+	}
+
+	result, err := extractor.ExtractMetadata(context.Background(), writeTestImage(t))
+	if err != nil {
+		t.Fatalf("ExtractMetadata returned error: %v", err)
+	}
+	if result.Location != "江阴" {
+		t.Errorf("unexpected location: %s", result.Location)
+	}
+}
+
+func TestProcessImageOCRWithFakeExtractor(t *testing.T) {
+	previous := metadataExtractor
+	defer func() { metadataExtractor = previous }()
+
+	t.Run("not configured", func(t *testing.T) {
+		metadataExtractor = nil
+		result, err := ProcessImageOCR(context.Background(), "unused.jpg")
+		if err != nil {
+			t.Fatalf("ProcessImageOCR returned error: %v", err)
+		}
+		if result.IsStandard {
+			t.Errorf("expected non-standard result without extractor")
+		}
+	})
+
+	t.Run("fake result", func(t *testing.T) {
+		extractor, err := NewMetadataExtractor(ExtractorConfig{
+			Provider:     "fake",
+			FakeResponse: `{"time":"2023/7/9 9:30","location":"  羊尖镇  ","confidence":0.8}`,
+		})
+		if err != nil {
+			t.Fatalf("NewMetadataExtractor returned error: %v", err)
+		}
+		metadataExtractor = extractor
+
+		result, err := ProcessImageOCR(context.Background(), "unused.jpg")
+		if err != nil {
+			t.Fatalf("ProcessImageOCR returned error: %v", err)
+		}
+		if result.Time != "2023-07-09 09:30:00" || result.Location != "羊尖镇" || !result.IsStandard {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if result.LocationParts == nil || result.LocationParts.Normalized != "江苏省无锡市锡山区羊尖镇" {
+			t.Errorf("unexpected location parts: %+v", result.LocationParts)
+		}
+		if result.Provider != "fake" || result.Model != "fake" || result.Confidence != 0.8 {
+			t.Errorf("unexpected metadata: %+v", result)
+		}
+	})
+
+	t.Run("unparsable time", func(t *testing.T) {
+		metadataExtractor = &FakeExtractor{Result: vlmStructuredResult{Time: "下午3点", Location: "羊尖镇", Confidence: 0.9}}
+		result, err := ProcessImageOCR(context.Background(), "unused.jpg")
+		if err != nil {
+			t.Fatalf("ProcessImageOCR returned error: %v", err)
+		}
+		if result.Time != "下午3点" || result.DateTime != nil {
+			t.Errorf("expected raw time kept without DateTime, got %+v", result)
+		}
+		if result.Status != OCRStatusUncertain || result.IsStandard {
+			t.Errorf("expected uncertain status for unparsable time, got %s", result.Status)
+		}
+	})
+
+	t.Run("extractor error", func(t *testing.T) {
+		metadataExtractor = &FakeExtractor{Err: errors.New("boom")}
+		_, err := ProcessImageOCR(context.Background(), "unused.jpg")
+		var attemptErr *OCRAttemptError
+		if !errors.As(err, &attemptErr) || attemptErr.Provider != "fake" {
+			t.Fatalf("expected OCRAttemptError from failing extractor, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// AdminDivision 为一条行政区划记录，Town 为空表示县级记录
+type AdminDivision struct {
+	Province string
+	City     string
+	County   string
+	Town     string
+}
+
+// ParsedLocation 为规范化后的地点
+type ParsedLocation struct {
+	Province   string `json:"province,omitempty"`
+	City       string `json:"city,omitempty"`
+	County     string `json:"county,omitempty"`
+	Town       string `json:"town,omitempty"`
+	Detail     string `json:"detail,omitempty"` // 行政区划之后的剩余部分，如村、路名、站点名
+	Matched    bool   `json:"matched"`          // 是否匹配到行政区划
+	Normalized string `json:"normalized,omitempty"`
+}
+
+// wuxiDivisions 为内置的无锡市乡镇级行政区划，格式为“区县:乡镇,乡镇,...”
+const wuxiDivisions = `梁溪区:崇安寺街道,通江街道,广益街道,广瑞路街道,上马墩街道,江海街道,黄巷街道,北大街街道,惠山街道,山北街道,南禅寺街道,清名桥街道,金星街道,扬名街道,金匮街道
+锡山区:东亭街道,安镇街道,厚桥街道,东北塘街道,云林街道,东港镇,锡北镇,鹅湖镇,羊尖镇
+惠山区:堰桥街道,长安街道,钱桥街道,前洲街道,玉祁街道,洛社镇,阳山镇
+滨湖区:河埒街道,荣巷街道,蠡园街道,蠡湖街道,华庄街道,太湖街道,雪浪街道,马山街道,胡埭镇
+新吴区:旺庄街道,硕放街道,江溪街道,新安街道,梅村街道,鸿山街道
+江阴市:澄江街道,南闸街道,云亭街道,城东街道,夏港街道,申港街道,利港街道,璜土镇,月城镇,青阳镇,徐霞客镇,华士镇,周庄镇,新桥镇,长泾镇,顾山镇,祝塘镇
+宜兴市:宜城街道,屺亭街道,新街街道,新庄街道,芳桥街道,张渚镇,西渚镇,太华镇,徐舍镇,官林镇,杨巷镇,新建镇,和桥镇,高塍镇,万石镇,周铁镇,丁蜀镇,湖㳇镇`
+
+// ocrLocationConfusions 为水印识别中常见的错字
+var ocrLocationConfusions = strings.NewReplacer(
+	"无钖", "无锡", "无踢", "无锡", "元锡", "无锡", "无锡币", "无锡市",
+	"江荫", "江阴", "宣兴", "宜兴", "宜兴币", "宜兴市", "江阴币", "江阴市",
+	"江苏者", "江苏省", "江办省", "江苏省",
+	"锡山匹", "锡山区", "惠山匹", "惠山区", "滨湖匹", "滨湖区", "新吴匹", "新吴区", "梁溪匹", "梁溪区",
+	"街遁", "街道", "街適", "街道",
+)
+
+var townSuffixes = []string{"街道", "镇", "乡"}
+var countySuffixes = []string{"区", "市", "县"}
+
+// suffixConfusions 为区划后缀在水印识别中常见的错字，简称后紧跟这些字时视为全称
+var suffixConfusions = map[string][]string{
+	"街道": {"街遭", "街遁", "街適"},
+	"区":  {"匹"},
+	"市":  {"币"},
+}
+
+// roadSuffixes 出现在简称之后时说明是道路名（如“锡山路”“太湖大道”），不是行政区划
+var roadSuffixes = []string{"大道", "路", "街", "道", "巷"}
+
+// Gazetteer 根据行政区划列表解析地点
+type Gazetteer struct {
+	divisions []AdminDivision
+}
+
+// gazetteer 为当前生效的行政区划表，默认使用内置无锡列表
+var gazetteer = NewGazetteer(defaultWuxiDivisions())
+
+// NewGazetteer 创建行政区划表
+func NewGazetteer(divisions []AdminDivision) *Gazetteer {
+	return &Gazetteer{divisions: divisions}
+}
+
+func defaultWuxiDivisions() []AdminDivision {
+	divisions := []AdminDivision{}
+	for _, line := range strings.Split(wuxiDivisions, "\n") {
+		county, towns, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		divisions = append(divisions, AdminDivision{Province: "江苏省", City: "无锡市", County: county})
+		for _, town := range strings.Split(towns, ",") {
+			divisions = append(divisions, AdminDivision{Province: "江苏省", City: "无锡市", County: county, Town: town})
+		}
+	}
+	return divisions
+}
+
+// loadGazetteer 读取 CSV 行政区划表（province,city,county,town），用于替换内置列表
+func loadGazetteer(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	divisions := []AdminDivision{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected province,city,county[,town]", line)
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "province") {
+			continue
+		}
+		division := AdminDivision{
+			Province: strings.TrimSpace(record[0]),
+			City:     strings.TrimSpace(record[1]),
+			County:   strings.TrimSpace(record[2]),
+		}
+		if len(record) > 3 {
+			division.Town = strings.TrimSpace(record[3])
+		}
+		divisions = append(divisions, division)
+	}
+	if len(divisions) == 0 {
+		return nil, fmt.Errorf("gazetteer %s is empty", path)
+	}
+	return NewGazetteer(divisions), nil
+}
+
+// initGazetteer 在配置了 GAZETTEER_FILE 时替换内置行政区划
+func initGazetteer() error {
+	path := getEnv("GAZETTEER_FILE", "")
+	if path == "" {
+		return nil
+	}
+	g, err := loadGazetteer(path)
+	if err != nil {
+		return fmt.Errorf("failed to load gazetteer: %w", err)
+	}
+	gazetteer = g
+	return nil
+}
+
+// locationMatch 为一条候选行政区划及其在文本中的位置
+type locationMatch struct {
+	division AdminDivision
+	score    int
+	start    int // rune 下标
+	end      int
+}
+
+// Normalize 修正常见错字，解析省、市、区县、乡镇与剩余地址，并补全缺失的上级区划
+func (g *Gazetteer) Normalize(text string) ParsedLocation {
+	text = strings.Join(strings.Fields(toHalfWidth(text)), "")
+	text = ocrLocationConfusions.Replace(text)
+	if text == "" {
+		return ParsedLocation{}
+	}
+	runes := []rune(text)
+
+	var best *locationMatch
+	for _, division := range g.divisions {
+		match := g.match(runes, division)
+		if match == nil {
+			continue
+		}
+		if best == nil || match.score > best.score ||
+			(match.score == best.score && match.end-match.start > best.end-best.start) {
+			best = match
+		}
+	}
+
+	if best == nil {
+		return ParsedLocation{Detail: text, Normalized: text}
+	}
+
+	d := best.division
+	// 匹配位置之前除上级区划以外的文字（如小区名）保留在剩余地址中
+	detail := trimParentNames(string(runes[:best.start]), d) + strings.TrimLeft(string(runes[best.end:]), ",，、 ")
+	parsed := ParsedLocation{
+		Province: d.Province,
+		City:     d.City,
+		County:   d.County,
+		Town:     d.Town,
+		Detail:   detail,
+		Matched:  true,
+	}
+	parsed.Normalized = parsed.Province + parsed.City + parsed.County + parsed.Town + parsed.Detail
+	return parsed
+}
+
+// TrimRegion 去掉文本开头的省、市名称（全称或简称）
+func (g *Gazetteer) TrimRegion(text string) string {
+	for _, d := range g.divisions {
+		for _, name := range []string{d.Province, d.City} {
+			for _, candidate := range []string{name, trimAnySuffix(name, []string{"省", "市"})} {
+				if len([]rune(candidate)) >= 2 && strings.HasPrefix(text, candidate) {
+					text = strings.TrimPrefix(text, candidate)
+				}
+			}
+		}
+	}
+	return text
+}
+
+// match 在文本中查找区划名称：全称优于简称，精确优于一字之差，
+// 文本中同时出现的上级区划会提高得分
+func (g *Gazetteer) match(runes []rune, d AdminDivision) *locationMatch {
+	name := d.Town
+	suffixes := townSuffixes
+	level := 10
+	if name == "" {
+		name = d.County
+		suffixes = countySuffixes
+		level = 0
+	}
+
+	short := trimAnySuffix(name, suffixes)
+	suffix := strings.TrimPrefix(name, short)
+
+	var m *locationMatch
+	if start := runeIndex(runes, []rune(name)); start >= 0 {
+		m = &locationMatch{division: d, score: level + 4, start: start, end: start + len([]rune(name))}
+	} else if suffix != "" && len([]rune(short)) >= 2 {
+		start := runeIndex(runes, []rune(short))
+		end := start + len([]rune(short))
+		rest := ""
+		if start >= 0 {
+			rest = string(runes[end:])
+		}
+		switch {
+		case start < 0:
+		case hasConfusedSuffix(rest, suffix):
+			// 简称之后是识别错的后缀时，一并视为区划名称
+			m = &locationMatch{division: d, score: level + 2, start: start, end: start + len([]rune(name))}
+		case hasAnyPrefix(rest, roadSuffixes):
+		case rest != "" && strings.ContainsRune("区市县", []rune(rest)[0]):
+			// “惠山区”中的“惠山”不能当作“惠山街道”的简称
+		default:
+			m = &locationMatch{division: d, score: level + 2, start: start, end: end}
+		}
+	}
+	if m == nil && len([]rune(name)) >= 3 {
+		// 三字及以上的全称允许一个错字，但后缀必须正确，避免“太湖大道”匹配“太湖街道”
+		if start := fuzzyRuneIndex(runes, []rune(name)); start >= 0 {
+			end := start + len([]rune(name))
+			if found := string(runes[end-len([]rune(suffix)) : end]); found == suffix {
+				m = &locationMatch{division: d, score: level + 1, start: start, end: end}
+			}
+		}
+	}
+	if m == nil {
+		return nil
+	}
+
+	// 上级区划出现在匹配位置之前时加分，用于区分同名简称
+	prefix := string(runes[:m.start])
+	if d.Town != "" && (strings.Contains(prefix, d.County) || strings.Contains(prefix, trimAnySuffix(d.County, countySuffixes))) {
+		m.score += 3
+	}
+	return m
+}
+
+// hasConfusedSuffix 判断 text 是否以后缀的常见错字开头
+func hasConfusedSuffix(text, suffix string) bool {
+	return hasAnyPrefix(text, suffixConfusions[suffix])
+}
+
+func hasAnyPrefix(text string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimParentNames 去掉文本开头依次出现的省、市及（乡镇匹配时的）区县名称，全称或简称均可
+func trimParentNames(text string, d AdminDivision) string {
+	parents := [][]string{
+		{d.Province, trimAnySuffix(d.Province, []string{"省"})},
+		{d.City, trimAnySuffix(d.City, []string{"市"})},
+	}
+	if d.Town != "" {
+		parents = append(parents, []string{d.County, trimAnySuffix(d.County, countySuffixes)})
+	}
+	for _, names := range parents {
+		for _, name := range names {
+			if len([]rune(name)) >= 2 && strings.HasPrefix(text, name) {
+				text = strings.TrimPrefix(text, name)
+				break
+			}
+		}
+	}
+	return strings.Trim(text, ",，、 ")
+}
+
+func trimAnySuffix(name string, suffixes []string) string {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
+
+func runeIndex(haystack, needle []rune) int {
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if string(haystack[i:i+len(needle)]) == string(needle) {
+			return i
+		}
+	}
+	return -1
+}
+
+// fuzzyRuneIndex 查找与 needle 最多相差一个字符（替换）的位置
+func fuzzyRuneIndex(haystack, needle []rune) int {
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		diff := 0
+		for j := range needle {
+			if haystack[i+j] != needle[j] {
+				diff++
+				if diff > 1 {
+					break
+				}
+			}
+		}
+		if diff <= 1 {
+			return i
+		}
+	}
+	return -1
+}
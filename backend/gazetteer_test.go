@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGazetteerNormalize(t *testing.T) {
+	g := NewGazetteer(defaultWuxiDivisions())
+
+	tests := []struct {
+		name     string
+		input    string
+		expected ParsedLocation
+	}{
+		{
+			name:  "full address",
+			input: "江苏省无锡市锡山区羊尖镇严家桥村",
+			expected: ParsedLocation{Province: "江苏省", City: "无锡市", County: "锡山区", Town: "羊尖镇",
+				Detail: "严家桥村", Matched: true, Normalized: "江苏省无锡市锡山区羊尖镇严家桥村"},
+		},
+		{
+			name:  "town only fills parents",
+			input: "洛社镇 张镇桥",
+			expected: ParsedLocation{Province: "江苏省", City: "无锡市", County: "惠山区", Town: "洛社镇",
+				Detail: "张镇桥", Matched: true, Normalized: "江苏省无锡市惠山区洛社镇张镇桥"},
+		},
+		{
+			name:  "short names",
+			input: "江阴华士",
+			expected: ParsedLocation{Province: "江苏省", City: "无锡市", County: "江阴市", Town: "华士镇",
+				Matched: true, Normalized: "江苏省无锡市江阴市华士镇"},
+		},
+		{
+			name:  "ocr confusion",
+			input: "无钖市宜兴币丁蜀镇",
+			expected: ParsedLocation{Province: "江苏省", City: "无锡市", County: "宜兴市", Town: "丁蜀镇",
+				Matched: true, Normalized: "江苏省无锡市宜兴市丁蜀镇"},
+		},
+		{
+			name:  "one wrong character",
+			input: "新吴区鸿山街遭",
+			expected: ParsedLocation{Province: "江苏省", City: "无锡市", County: "新吴区", Town: "鸿山街道",
+				Matched: true, Normalized: "江苏省无锡市新吴区鸿山街道"},
+		},
+		{
+			name:  "county name is not a town short name",
+			input: "惠山区",
+			expected: ParsedLocation{Province: "江苏省", City: "无锡市", County: "惠山区",
+				Matched: true, Normalized: "江苏省无锡市惠山区"},
+		},
+		{
+			name:  "wrong character in the name",
+			input: "新吴区鸿止街道",
+			expected: ParsedLocation{Province: "江苏省", City: "无锡市", County: "新吴区", Town: "鸿山街道",
+				Matched: true, Normalized: "江苏省无锡市新吴区鸿山街道"},
+		},
+		{
+			name:     "road named after a county",
+			input:    "锡山路8号",
+			expected: ParsedLocation{Detail: "锡山路8号", Normalized: "锡山路8号"},
+		},
+		{
+			name:     "avenue named after a town",
+			input:    "太湖大道123号",
+			expected: ParsedLocation{Detail: "太湖大道123号", Normalized: "太湖大道123号"},
+		},
+		{
+			name:  "road inside a county",
+			input: "滨湖区太湖大道123号",
+			expected: ParsedLocation{Province: "江苏省", City: "无锡市", County: "滨湖区",
+				Detail: "太湖大道123号", Matched: true, Normalized: "江苏省无锡市滨湖区太湖大道123号"},
+		},
+		{
+			name:  "text before the town is kept",
+			input: "某小区 梅村街道",
+			expected: ParsedLocation{Province: "江苏省", City: "无锡市", County: "新吴区", Town: "梅村街道",
+				Detail: "某小区", Matched: true, Normalized: "江苏省无锡市新吴区梅村街道某小区"},
+		},
+		{
+			name:     "unknown place",
+			input:    "南京市 玄武区",
+			expected: ParsedLocation{Detail: "南京市玄武区", Normalized: "南京市玄武区"},
+		},
+		{
+			name:     "empty",
+			input:    "  ",
+			expected: ParsedLocation{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.Normalize(tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %+v, want %+v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadGazetteer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "divisions.csv")
+	content := "province,city,county,town\n江苏省,苏州市,吴中区,\n江苏省,苏州市,吴中区,木渎镇\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := loadGazetteer(path)
+	if err != nil {
+		t.Fatalf("loadGazetteer returned error: %v", err)
+	}
+	got := g.Normalize("木渎镇灵岩山")
+	if !got.Matched || got.City != "苏州市" || got.County != "吴中区" || got.Detail != "灵岩山" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("江苏省,苏州市\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadGazetteer(path); err == nil {
+		t.Error("expected error for short record")
+	}
+}
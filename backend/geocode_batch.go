@@ -0,0 +1,503 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// 批量地理编码任务状态
+const (
+	GeocodeBatchRunning   = "running"
+	GeocodeBatchDone      = "done"
+	GeocodeBatchFailed    = "failed"
+	GeocodeBatchCancelled = "cancelled"
+)
+
+const (
+	maxGeocodeBatchImages         = 5000
+	defaultGeocodeBatchIntervalMS = 200
+)
+
+// errGeocodeBatchRunning 表示已有批次在运行，同一时间只运行一个批次以免超出服务商 QPS
+var errGeocodeBatchRunning = errors.New("geocode batch already running")
+
+// GeocodeBatch 为批量地理编码任务及其进度
+type GeocodeBatch struct {
+	ID         int64      `json:"id"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// GeocodeBatchRequest 为批量地理编码的筛选条件，默认处理所有未标注且尚无建议的图片
+type GeocodeBatchRequest struct {
+	ImageIDs  []int `json:"image_ids,omitempty"`
+	Overwrite bool  `json:"overwrite,omitempty"` // 重新生成已有的建议
+	Limit     int   `json:"limit,omitempty"`
+}
+
+// GeocodeSuggestion 为批量地理编码为图片生成的坐标与最近站点建议，坐标失败时 Error 不为空
+type GeocodeSuggestion struct {
+	ImageID           int             `json:"image_id"`
+	BatchID           *int64          `json:"batch_id,omitempty"`
+	Address           string          `json:"address"`
+	Longitude         *float64        `json:"longitude,omitempty"`
+	Latitude          *float64        `json:"latitude,omitempty"`
+	CoordSystem       string          `json:"coord_system,omitempty"`
+	StationID         string          `json:"station_id,omitempty"`
+	StationName       string          `json:"station_name,omitempty"`
+	StationDistanceKM *float64        `json:"station_distance_km,omitempty"`
+	Quality           *GeocodeQuality `json:"quality,omitempty"`
+	Error             string          `json:"error,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+// geocodeBatchImage 为待地理编码的图片
+type geocodeBatchImage struct {
+	ID          int
+	OCRLocation string
+	OCRDateTime *time.Time
+}
+
+// geocodeBatchStore 负责批次进度与建议的持久化
+type geocodeBatchStore interface {
+	ResetRunning() (int64, error)
+	PendingImages(req GeocodeBatchRequest) ([]geocodeBatchImage, error)
+	CreateBatch(total int) (*GeocodeBatch, error)
+	UpdateBatch(batch *GeocodeBatch) error
+	GetBatch(id int64) (*GeocodeBatch, error)
+	SaveSuggestion(suggestion *GeocodeSuggestion) error
+}
+
+// GeocodeBatchRunner 在后台逐张地理编码图片的 ocr_location，同一时间只运行一个批次
+type GeocodeBatchRunner struct {
+	store    geocodeBatchStore
+	geocode  func(ctx context.Context, address string) (*GeocodeResult, error)
+	nearest  func(lon, lat float64, k int, maxKM float64, at time.Time) ([]NearbyStation, error)
+	policy   GeocodePolicy
+	interval time.Duration // 相邻两张图片之间的间隔，避免超出服务商 QPS
+
+	mu        sync.Mutex
+	runningID int64
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// geocodeBatches 为全局批量地理编码任务，main 中初始化
+var geocodeBatches *GeocodeBatchRunner
+
+func getGeocodeBatchInterval() time.Duration {
+	return time.Duration(getEnvInt("GEOCODE_BATCH_INTERVAL_MS", defaultGeocodeBatchIntervalMS)) * time.Millisecond
+}
+
+// NewGeocodeBatchRunner 创建批量地理编码任务，启动前将上次未结束的批次标记为失败
+func NewGeocodeBatchRunner(store geocodeBatchStore, geocode func(ctx context.Context, address string) (*GeocodeResult, error),
+	nearest func(lon, lat float64, k int, maxKM float64, at time.Time) ([]NearbyStation, error), policy GeocodePolicy, interval time.Duration) *GeocodeBatchRunner {
+	if n, err := store.ResetRunning(); err != nil {
+		slog.Error("Failed to reset interrupted geocode batches", "error", err)
+	} else if n > 0 {
+		slog.Info("Marked interrupted geocode batches as failed", "count", n)
+	}
+	return &GeocodeBatchRunner{store: store, geocode: geocode, nearest: nearest, policy: policy, interval: interval}
+}
+
+// Start 选出待处理图片并在后台运行新批次
+func (r *GeocodeBatchRunner) Start(req GeocodeBatchRequest) (*GeocodeBatch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return nil, errGeocodeBatchRunning
+	}
+
+	images, err := r.store.PendingImages(req)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := r.store.CreateBatch(len(images))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.runningID, r.cancel, r.done = batch.ID, cancel, make(chan struct{})
+	started := *batch
+	go r.run(ctx, batch, images)
+	return &started, nil
+}
+
+// Cancel 取消正在运行的批次，id 不是当前批次时返回 false
+func (r *GeocodeBatchRunner) Cancel(id int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel == nil || r.runningID != id {
+		return false
+	}
+	r.cancel()
+	return true
+}
+
+// Wait 等待当前批次结束
+func (r *GeocodeBatchRunner) Wait() {
+	r.mu.Lock()
+	done := r.done
+	r.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func (r *GeocodeBatchRunner) run(ctx context.Context, batch *GeocodeBatch, images []geocodeBatchImage) {
+	defer func() {
+		r.mu.Lock()
+		r.cancel()
+		r.cancel, r.runningID = nil, 0
+		close(r.done)
+		r.mu.Unlock()
+	}()
+
+	for i, img := range images {
+		if i > 0 && r.interval > 0 {
+			select {
+			case <-time.After(r.interval):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			batch.Status = GeocodeBatchCancelled
+			break
+		}
+
+		suggestion, err := r.suggest(ctx, batch.ID, img)
+		if ctx.Err() != nil {
+			// 取消时正在处理的图片不记录结果
+			batch.Status = GeocodeBatchCancelled
+			break
+		}
+		if saveErr := r.store.SaveSuggestion(suggestion); saveErr != nil {
+			slog.Error("Failed to save geocode suggestion", "batch_id", batch.ID, "image_id", img.ID, "error", saveErr)
+			err = saveErr
+		}
+		batch.Processed++
+		if err != nil {
+			batch.Failed++
+			batch.LastError = err.Error()
+		} else {
+			batch.Succeeded++
+		}
+		// 所有服务商当天配额都已用完时停止，剩余图片留待下次
+		if geocodeQuotaExhausted(err) {
+			batch.Status = GeocodeBatchFailed
+		}
+		if batch.Status == GeocodeBatchRunning {
+			if err := r.store.UpdateBatch(batch); err != nil {
+				slog.Error("Failed to update geocode batch", "batch_id", batch.ID, "error", err)
+			}
+			continue
+		}
+		break
+	}
+
+	if batch.Status == GeocodeBatchRunning {
+		batch.Status = GeocodeBatchDone
+	}
+	finished := time.Now()
+	batch.FinishedAt = &finished
+	if err := r.store.UpdateBatch(batch); err != nil {
+		slog.Error("Failed to update geocode batch", "batch_id", batch.ID, "error", err)
+	}
+	slog.Info("Geocode batch finished", "batch_id", batch.ID, "status", batch.Status, "total", batch.Total,
+		"processed", batch.Processed, "failed", batch.Failed, "latency", finished.Sub(batch.CreatedAt))
+}
+
+// suggest 地理编码一张图片的 ocr_location 并查找观测时间（无则当前时间）运行中的最近站点
+func (r *GeocodeBatchRunner) suggest(ctx context.Context, batchID int64, img geocodeBatchImage) (*GeocodeSuggestion, error) {
+	// 失败时也写入 coord_system，该列为 NOT NULL
+	suggestion := &GeocodeSuggestion{ImageID: img.ID, BatchID: &batchID, Address: prepareGeocodeAddress(img.OCRLocation),
+		CoordSystem: storageCoordSystem}
+
+	result, err := r.geocode(ctx, suggestion.Address)
+	if err == nil {
+		var quality GeocodeQuality
+		quality, err = assessGeocodeQuality(suggestion.Address, result, r.policy)
+		suggestion.Quality = &quality
+	}
+	if err != nil {
+		suggestion.Error = err.Error()
+		return suggestion, err
+	}
+
+	lon, lat := convertCoordinate(result.Longitude, result.Latitude, result.CoordSystem, storageCoordSystem)
+	suggestion.Longitude, suggestion.Latitude = &lon, &lat
+
+	var at time.Time
+	if img.OCRDateTime != nil {
+		at = *img.OCRDateTime
+	}
+	nearby, err := r.nearest(lon, lat, 1, 0, at)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to find nearest station", "batch_id", batchID, "image_id", img.ID, "error", err)
+	} else if len(nearby) > 0 {
+		station := nearby[0]
+		distance := roundCoordinate(station.DistanceKM, 3)
+		suggestion.StationID, suggestion.StationName, suggestion.StationDistanceKM = station.ID, station.Name, &distance
+	}
+	return suggestion, nil
+}
+
+// geocodeQuotaExhausted 判断是否所有服务商都因当天配额用完而失败
+func geocodeQuotaExhausted(err error) bool {
+	if err == nil {
+		return false
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !errors.Is(e, errGeocodeQuotaExceeded) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, errGeocodeQuotaExceeded)
+}
+
+// startGeocodeBatch 为未标注图片批量生成坐标与站点建议，立即返回批次，进度通过 GET /geocode/batch/{id} 查询
+func startGeocodeBatch(w http.ResponseWriter, r *http.Request) {
+	var req GeocodeBatchRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if geocoder == nil || geocodeBatches == nil {
+		http.Error(w, "Geocoder not configured", http.StatusInternalServerError)
+		return
+	}
+
+	batch, err := geocodeBatches.Start(req)
+	if errors.Is(err, errGeocodeBatchRunning) {
+		http.Error(w, "A geocode batch is already running", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(batch)
+}
+
+// getGeocodeBatch 返回批次进度
+func getGeocodeBatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+	if geocodeBatches == nil {
+		http.Error(w, "Geocoder not configured", http.StatusInternalServerError)
+		return
+	}
+
+	batch, err := geocodeBatches.store.GetBatch(id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Batch not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// cancelGeocodeBatch 取消正在运行的批次，已处理的图片保留建议
+func cancelGeocodeBatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+	if geocodeBatches == nil || !geocodeBatches.Cancel(id) {
+		http.Error(w, "Batch is not running", http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadGeocodeSuggestion 读取图片的地理编码建议，不存在时返回 nil
+func loadGeocodeSuggestion(imageID int) (*GeocodeSuggestion, error) {
+	suggestion := GeocodeSuggestion{ImageID: imageID}
+	var batchID sql.NullInt64
+	var stationID, stationName, errMsg sql.NullString
+	var quality geocodeQualityColumns
+	err := db.QueryRow(`
+		SELECT s.batch_id, s.address, s.longitude, s.latitude, s.coord_system, s.station_id, st.name,
+		       s.station_distance_km, s.error, s.created_at,
+		       s.geocode_provider, s.geocode_level, s.geocode_precise, s.geocode_confidence,
+		       s.geocode_comprehension, s.geocode_warnings
+		FROM geocode_suggestions s
+		LEFT JOIN stations st ON st.id = s.station_id
+		WHERE s.image_id = ?
+	`, imageID).Scan(append([]interface{}{
+		&batchID, &suggestion.Address, &suggestion.Longitude, &suggestion.Latitude, &suggestion.CoordSystem,
+		&stationID, &stationName, &suggestion.StationDistanceKM, &errMsg, &suggestion.CreatedAt,
+	}, quality.dest()...)...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if batchID.Valid {
+		suggestion.BatchID = &batchID.Int64
+	}
+	suggestion.StationID, suggestion.StationName, suggestion.Error = stationID.String, stationName.String, errMsg.String
+	suggestion.Quality = quality.quality()
+	return &suggestion, nil
+}
+
+// inCoordSystem 返回以 system 坐标系表示的建议
+func (s GeocodeSuggestion) inCoordSystem(system string) GeocodeSuggestion {
+	if s.Longitude != nil && s.Latitude != nil {
+		lon, lat := convertCoordinate(*s.Longitude, *s.Latitude, s.CoordSystem, system)
+		s.Longitude, s.Latitude, s.CoordSystem = &lon, &lat, system
+	}
+	return s
+}
+
+// mysqlGeocodeBatchStore 使用 geocode_batches 与 geocode_suggestions 表
+type mysqlGeocodeBatchStore struct {
+	db *sql.DB
+}
+
+func newMySQLGeocodeBatchStore(db *sql.DB) *mysqlGeocodeBatchStore {
+	return &mysqlGeocodeBatchStore{db: db}
+}
+
+func (s *mysqlGeocodeBatchStore) ResetRunning() (int64, error) {
+	result, err := s.db.Exec(`
+		UPDATE geocode_batches SET status = ?, last_error = ?, finished_at = NOW() WHERE status = ?
+	`, GeocodeBatchFailed, "interrupted by server restart", GeocodeBatchRunning)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *mysqlGeocodeBatchStore) PendingImages(req GeocodeBatchRequest) ([]geocodeBatchImage, error) {
+	query := `
+		SELECT i.id, i.ocr_location, i.ocr_datetime
+		FROM images i
+		WHERE i.annotated = FALSE AND i.ocr_location IS NOT NULL AND i.ocr_location <> ''`
+	args := []interface{}{}
+	if !req.Overwrite {
+		// 失败的建议（配额用尽、超时等）留待下次重试
+		query += " AND NOT EXISTS (SELECT 1 FROM geocode_suggestions s WHERE s.image_id = i.id AND s.error IS NULL)"
+	}
+	if len(req.ImageIDs) > 0 {
+		query += " AND i.id IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(req.ImageIDs)), ", ") + ")"
+		for _, id := range req.ImageIDs {
+			args = append(args, id)
+		}
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > maxGeocodeBatchImages {
+		limit = maxGeocodeBatchImages
+	}
+	query += " ORDER BY i.id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []geocodeBatchImage{}
+	for rows.Next() {
+		var img geocodeBatchImage
+		if err := rows.Scan(&img.ID, &img.OCRLocation, &img.OCRDateTime); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+func (s *mysqlGeocodeBatchStore) CreateBatch(total int) (*GeocodeBatch, error) {
+	result, err := s.db.Exec("INSERT INTO geocode_batches (status, total) VALUES (?, ?)", GeocodeBatchRunning, total)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &GeocodeBatch{ID: id, Status: GeocodeBatchRunning, Total: total, CreatedAt: time.Now()}, nil
+}
+
+func (s *mysqlGeocodeBatchStore) UpdateBatch(batch *GeocodeBatch) error {
+	_, err := s.db.Exec(`
+		UPDATE geocode_batches
+		SET status = ?, processed = ?, succeeded = ?, failed = ?, last_error = ?, finished_at = ?
+		WHERE id = ?
+	`, batch.Status, batch.Processed, batch.Succeeded, batch.Failed, nullString(batch.LastError), batch.FinishedAt, batch.ID)
+	return err
+}
+
+func (s *mysqlGeocodeBatchStore) GetBatch(id int64) (*GeocodeBatch, error) {
+	var batch GeocodeBatch
+	var lastError sql.NullString
+	err := s.db.QueryRow(`
+		SELECT id, status, total, processed, succeeded, failed, last_error, created_at, finished_at
+		FROM geocode_batches
+		WHERE id = ?
+	`, id).Scan(&batch.ID, &batch.Status, &batch.Total, &batch.Processed, &batch.Succeeded, &batch.Failed,
+		&lastError, &batch.CreatedAt, &batch.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	batch.LastError = lastError.String
+	return &batch, nil
+}
+
+func (s *mysqlGeocodeBatchStore) SaveSuggestion(suggestion *GeocodeSuggestion) error {
+	args := append([]interface{}{suggestion.ImageID, suggestion.BatchID, suggestion.Address,
+		suggestion.Longitude, suggestion.Latitude, suggestion.CoordSystem, nullString(suggestion.StationID),
+		suggestion.StationDistanceKM, nullString(suggestion.Error)}, geocodeQualityValues(suggestion.Quality)...)
+	_, err := s.db.Exec(`
+		INSERT INTO geocode_suggestions (image_id, batch_id, address, longitude, latitude, coord_system,
+		                                 station_id, station_distance_km, error,
+		                                 geocode_provider, geocode_level, geocode_precise, geocode_confidence,
+		                                 geocode_comprehension, geocode_warnings)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			batch_id = VALUES(batch_id), address = VALUES(address),
+			longitude = VALUES(longitude), latitude = VALUES(latitude), coord_system = VALUES(coord_system),
+			station_id = VALUES(station_id), station_distance_km = VALUES(station_distance_km), error = VALUES(error),
+			geocode_provider = VALUES(geocode_provider), geocode_level = VALUES(geocode_level),
+			geocode_precise = VALUES(geocode_precise), geocode_confidence = VALUES(geocode_confidence),
+			geocode_comprehension = VALUES(geocode_comprehension), geocode_warnings = VALUES(geocode_warnings),
+			created_at = CURRENT_TIMESTAMP
+	`, args...)
+	return err
+}
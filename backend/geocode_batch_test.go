@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryGeocodeBatchStore 为测试用的内存批次存储
+type memoryGeocodeBatchStore struct {
+	mu          sync.Mutex
+	images      []geocodeBatchImage
+	batches     map[int64]GeocodeBatch
+	suggestions map[int]GeocodeSuggestion
+}
+
+func newMemoryGeocodeBatchStore(images ...geocodeBatchImage) *memoryGeocodeBatchStore {
+	return &memoryGeocodeBatchStore{images: images, batches: map[int64]GeocodeBatch{}, suggestions: map[int]GeocodeSuggestion{}}
+}
+
+func (s *memoryGeocodeBatchStore) ResetRunning() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for id, batch := range s.batches {
+		if batch.Status == GeocodeBatchRunning {
+			batch.Status = GeocodeBatchFailed
+			s.batches[id] = batch
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryGeocodeBatchStore) PendingImages(req GeocodeBatchRequest) ([]geocodeBatchImage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	images := []geocodeBatchImage{}
+	for _, img := range s.images {
+		if suggestion, ok := s.suggestions[img.ID]; ok && suggestion.Error == "" && !req.Overwrite {
+			continue
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+func (s *memoryGeocodeBatchStore) CreateBatch(total int) (*GeocodeBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := GeocodeBatch{ID: int64(len(s.batches) + 1), Status: GeocodeBatchRunning, Total: total}
+	s.batches[batch.ID] = batch
+	return &batch, nil
+}
+
+func (s *memoryGeocodeBatchStore) UpdateBatch(batch *GeocodeBatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches[batch.ID] = *batch
+	return nil
+}
+
+func (s *memoryGeocodeBatchStore) GetBatch(id int64) (*GeocodeBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch := s.batches[id]
+	return &batch, nil
+}
+
+func (s *memoryGeocodeBatchStore) SaveSuggestion(suggestion *GeocodeSuggestion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 与 geocode_suggestions.coord_system 的 NOT NULL ENUM 一致
+	switch suggestion.CoordSystem {
+	case CoordWGS84, CoordGCJ02, CoordBD09:
+	default:
+		return fmt.Errorf("invalid coord_system %q", suggestion.CoordSystem)
+	}
+	s.suggestions[suggestion.ImageID] = *suggestion
+	return nil
+}
+
+func TestGeocodeBatchRunner(t *testing.T) {
+	observed := time.Date(2024, 7, 1, 9, 0, 0, 0, time.Local)
+	store := newMemoryGeocodeBatchStore(
+		geocodeBatchImage{ID: 1, OCRLocation: "锡山区羊尖镇", OCRDateTime: &observed},
+		geocodeBatchImage{ID: 2, OCRLocation: "不存在的地方"},
+	)
+	store.batches[9] = GeocodeBatch{ID: 9, Status: GeocodeBatchRunning}
+
+	gcjLon, gcjLat := convertCoordinate(120.3544, 31.6128, CoordWGS84, CoordGCJ02)
+	geocode := func(ctx context.Context, address string) (*GeocodeResult, error) {
+		if address == "江苏省无锡市锡山区羊尖镇" {
+			return &GeocodeResult{Provider: "amap", Longitude: gcjLon, Latitude: gcjLat, CoordSystem: CoordGCJ02, Level: "乡镇"}, nil
+		}
+		return nil, errGeocodeNotFound
+	}
+	var nearestAt time.Time
+	nearest := func(lon, lat float64, k int, maxKM float64, at time.Time) ([]NearbyStation, error) {
+		nearestAt = at
+		return []NearbyStation{{Station: Station{ID: "58354", Name: "无锡本站"}, DistanceKM: 0.01234}}, nil
+	}
+
+	runner := NewGeocodeBatchRunner(store, geocode, nearest, GeocodePolicy{CoarseMatch: GeocodePolicyWarn}, 0)
+	if store.batches[9].Status != GeocodeBatchFailed {
+		t.Errorf("interrupted batch should be marked failed, got %q", store.batches[9].Status)
+	}
+
+	batch, err := runner.Start(GeocodeBatchRequest{})
+	if err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	runner.Wait()
+
+	got, _ := store.GetBatch(batch.ID)
+	if got.Status != GeocodeBatchDone || got.Total != 2 || got.Processed != 2 || got.Succeeded != 1 || got.Failed != 1 ||
+		got.FinishedAt == nil || got.LastError == "" {
+		t.Errorf("unexpected batch: %+v", got)
+	}
+
+	s := store.suggestions[1]
+	if s.Address != "江苏省无锡市锡山区羊尖镇" || s.CoordSystem != CoordWGS84 || s.Longitude == nil ||
+		math.Abs(*s.Longitude-120.3544) > 0.00002 || math.Abs(*s.Latitude-31.6128) > 0.00002 {
+		t.Errorf("unexpected suggestion coordinates: %+v", s)
+	}
+	if s.StationID != "58354" || s.StationName != "无锡本站" || s.StationDistanceKM == nil || *s.StationDistanceKM != 0.012 {
+		t.Errorf("unexpected suggested station: %+v", s)
+	}
+	if s.Quality == nil || s.Quality.Provider != "amap" || *s.BatchID != batch.ID {
+		t.Errorf("unexpected suggestion: %+v", s)
+	}
+	if !nearestAt.Equal(observed) {
+		t.Errorf("nearest station looked up at %v, want observation time %v", nearestAt, observed)
+	}
+	if failed := store.suggestions[2]; failed.Error == "" || failed.Longitude != nil {
+		t.Errorf("failed image should keep the error without coordinates: %+v", failed)
+	}
+
+	// 已有建议的图片默认不再处理，失败的图片重新尝试
+	batch, _ = runner.Start(GeocodeBatchRequest{})
+	runner.Wait()
+	if got, _ := store.GetBatch(batch.ID); got.Total != 1 || got.Status != GeocodeBatchDone {
+		t.Errorf("expected only the failed image to be retried, got %+v", got)
+	}
+}
+
+func TestGeocodeBatchRunnerQuotaExhausted(t *testing.T) {
+	store := newMemoryGeocodeBatchStore(
+		geocodeBatchImage{ID: 1, OCRLocation: "锡山区羊尖镇"},
+		geocodeBatchImage{ID: 2, OCRLocation: "锡山区东港镇"},
+	)
+	calls := 0
+	geocode := func(ctx context.Context, address string) (*GeocodeResult, error) {
+		calls++
+		return nil, errors.Join(
+			fmt.Errorf("%w: baidu", errGeocodeQuotaExceeded),
+			fmt.Errorf("%w: amap", errGeocodeQuotaExceeded),
+		)
+	}
+	runner := NewGeocodeBatchRunner(store, geocode, findNearestStations, geocodePolicy, 0)
+	batch, _ := runner.Start(GeocodeBatchRequest{})
+	runner.Wait()
+
+	got, _ := store.GetBatch(batch.ID)
+	if got.Status != GeocodeBatchFailed || got.Processed != 1 || calls != 1 {
+		t.Errorf("batch should stop once all quotas are used up: %+v, %d calls", got, calls)
+	}
+}
+
+func TestGeocodeBatchRunnerCancel(t *testing.T) {
+	store := newMemoryGeocodeBatchStore(
+		geocodeBatchImage{ID: 1, OCRLocation: "锡山区羊尖镇"},
+		geocodeBatchImage{ID: 2, OCRLocation: "锡山区东港镇"},
+		geocodeBatchImage{ID: 3, OCRLocation: "锡山区鹅湖镇"},
+	)
+	blocked := make(chan struct{})
+	geocode := func(ctx context.Context, address string) (*GeocodeResult, error) {
+		if address == "江苏省无锡市锡山区东港镇" {
+			close(blocked)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &GeocodeResult{Provider: "amap", Longitude: 120.4, Latitude: 31.6, CoordSystem: CoordWGS84}, nil
+	}
+	nearest := func(lon, lat float64, k int, maxKM float64, at time.Time) ([]NearbyStation, error) { return nil, nil }
+	runner := NewGeocodeBatchRunner(store, geocode, nearest, geocodePolicy, 0)
+
+	batch, _ := runner.Start(GeocodeBatchRequest{})
+	<-blocked
+	if _, err := runner.Start(GeocodeBatchRequest{}); !errors.Is(err, errGeocodeBatchRunning) {
+		t.Errorf("expected errGeocodeBatchRunning, got %v", err)
+	}
+	if runner.Cancel(batch.ID + 1) {
+		t.Error("Cancel should ignore other batches")
+	}
+	if !runner.Cancel(batch.ID) {
+		t.Fatal("Cancel returned false for the running batch")
+	}
+	runner.Wait()
+
+	got, _ := store.GetBatch(batch.ID)
+	if got.Status != GeocodeBatchCancelled || got.Processed != 1 || got.FinishedAt == nil {
+		t.Errorf("unexpected cancelled batch: %+v", got)
+	}
+	if _, ok := store.suggestions[2]; ok {
+		t.Error("the image being geocoded when cancelled should not get a suggestion")
+	}
+	if runner.Cancel(batch.ID) {
+		t.Error("Cancel should return false after the batch finished")
+	}
+}
+
+func TestGeocodeQuotaExhausted(t *testing.T) {
+	quota := fmt.Errorf("%w: baidu", errGeocodeQuotaExceeded)
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{quota, true},
+		{errors.Join(quota, quota), true},
+		{errors.Join(quota, errGeocodeNotFound), false},
+		{errGeocodeNotFound, false},
+	}
+	for _, tt := range tests {
+		if got := geocodeQuotaExhausted(tt.err); got != tt.want {
+			t.Errorf("geocodeQuotaExhausted(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// geocodeCacheProvider 为缓存命中在 geocode_usage 中记录的名称
+	geocodeCacheProvider        = "cache"
+	geocodeUsageDayLayout       = "2006-01-02"
+	defaultGeocodeCacheTTLHours = 720
+	defaultGeocodeUsageDays     = 7
+	maxGeocodeUsageDays         = 90
+)
+
+// errGeocodeQuotaExceeded 表示服务商当天的请求数已达到 *_DAILY_QUOTA，跳过该服务商
+var errGeocodeQuotaExceeded = errors.New("daily geocode quota exceeded")
+
+// GeocodeUsage 为某天某个服务商的请求计数
+type GeocodeUsage struct {
+	Date     string `json:"date"`
+	Provider string `json:"provider"`
+	Requests int    `json:"requests"`
+	Failures int    `json:"failures"`
+}
+
+// GeocodeQuota 为服务商当天的用量与剩余配额
+type GeocodeQuota struct {
+	Provider   string `json:"provider"`
+	Requests   int    `json:"requests"`
+	DailyQuota int    `json:"daily_quota"` // 0 表示不限
+	Remaining  *int   `json:"remaining,omitempty"`
+}
+
+// GeocodeUsageResponse 为 /api/geocode/usage 的返回值
+type GeocodeUsageResponse struct {
+	Date         string         `json:"date"`
+	Providers    []GeocodeQuota `json:"providers"`
+	CacheHits    int            `json:"cache_hits"`
+	CacheEntries int            `json:"cache_entries"`
+	History      []GeocodeUsage `json:"history"`
+}
+
+// geocodeStore 负责地理编码缓存与每日请求计数的持久化
+type geocodeStore interface {
+	// LookupCache 返回未过期的缓存结果，不存在时返回 nil, nil
+	LookupCache(key string, now time.Time) (*GeocodeResult, error)
+	SaveCache(key, address string, result *GeocodeResult, expiresAt time.Time) error
+	CountCache(now time.Time) (int, error)
+	// AddUsage 累加请求数与失败数
+	AddUsage(day, provider string, requests, failures int) error
+	// ReserveUsage 在请求数小于 quota 时将其加一并返回 true，判断与计数为一次原子操作
+	ReserveUsage(day, provider string, quota int) (bool, error)
+	UsageOn(day, provider string) (int, error)
+	UsageSince(day string) ([]GeocodeUsage, error)
+}
+
+var (
+	// geocodeStats 为缓存与用量存储，initGeocoder 中初始化
+	geocodeStats geocodeStore
+	// geocodeMeters 为按回退顺序排列的各服务商计数器，用于报告当天配额
+	geocodeMeters []*meteredGeocoder
+)
+
+func getGeocodeCacheTTL() time.Duration {
+	return time.Duration(getEnvInt("GEOCODE_CACHE_TTL_HOURS", defaultGeocodeCacheTTLHours)) * time.Hour
+}
+
+// normalizeGeocodeAddress 去掉空白与标点、统一全半角和大小写，使写法不同的同一地址共用缓存
+func normalizeGeocodeAddress(address string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, toHalfWidth(address))
+}
+
+// geocodeCacheKey 为规范化地址的 SHA-256，避免长地址超出索引长度
+func geocodeCacheKey(address string) string {
+	sum := sha256.Sum256([]byte(normalizeGeocodeAddress(address)))
+	return hex.EncodeToString(sum[:])
+}
+
+// meteredGeocoder 记录服务商每天的请求数，达到 dailyQuota 后不再调用，由下一个服务商接替
+type meteredGeocoder struct {
+	Geocoder
+	store      geocodeStore
+	dailyQuota int // 0 表示不限
+	now        func() time.Time
+}
+
+// Geocode 占用当天配额后调用服务商，成功与失败都计入请求数
+func (g *meteredGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	day := g.now().Format(geocodeUsageDayLayout)
+	reserved, err := g.reserve(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+	result, err := g.Geocoder.Geocode(ctx, address)
+	g.record(ctx, day, reserved, err)
+	return result, err
+}
+
+// reserve 在调用服务商前占用一次请求数，当天请求数达到 dailyQuota 时返回 errGeocodeQuotaExceeded。
+// 并发请求不会超出配额；不限配额或写入计数失败时不占用，由 record 在调用后计数
+func (g *meteredGeocoder) reserve(ctx context.Context, day string) (bool, error) {
+	if g.dailyQuota <= 0 {
+		return false, nil
+	}
+	ok, err := g.store.ReserveUsage(day, g.Name(), g.dailyQuota)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to reserve geocode quota", "provider", g.Name(), "error", err)
+		return false, nil
+	}
+	if !ok {
+		return false, fmt.Errorf("%s: %w (%d requests)", g.Name(), errGeocodeQuotaExceeded, g.dailyQuota)
+	}
+	return true, nil
+}
+
+// record 记录一次调用，reserved 为 true 时请求数已在 reserve 中计入
+func (g *meteredGeocoder) record(ctx context.Context, day string, reserved bool, err error) {
+	requests, failures := 1, 0
+	if reserved {
+		requests = 0
+	}
+	if err != nil {
+		failures = 1
+	}
+	g.addUsage(ctx, day, requests, failures)
+}
+
+// release 归还 reserve 占用但没有实际请求服务商的次数
+func (g *meteredGeocoder) release(ctx context.Context, day string, reserved bool) {
+	if reserved {
+		g.addUsage(ctx, day, -1, 0)
+	}
+}
+
+func (g *meteredGeocoder) addUsage(ctx context.Context, day string, requests, failures int) {
+	if requests == 0 && failures == 0 {
+		return
+	}
+	if err := g.store.AddUsage(day, g.Name(), requests, failures); err != nil {
+		slog.WarnContext(ctx, "Failed to record geocode usage", "provider", g.Name(), "error", err)
+	}
+}
+
+// quota 返回当天的用量与剩余配额
+func (g *meteredGeocoder) quota(day string) (GeocodeQuota, error) {
+	used, err := g.store.UsageOn(day, g.Name())
+	if err != nil {
+		return GeocodeQuota{}, err
+	}
+	quota := GeocodeQuota{Provider: g.Name(), Requests: used, DailyQuota: g.dailyQuota}
+	if g.dailyQuota > 0 {
+		remaining := g.dailyQuota - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		quota.Remaining = &remaining
+	}
+	return quota, nil
+}
+
+// cachedGeocoder 按规范化地址缓存成功的结果，有效期内重复的地址不再请求服务商；
+// 缓存读写失败只记录日志，不影响地理编码。逆地理编码不缓存
+type cachedGeocoder struct {
+	Geocoder
+	store geocodeStore
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// Geocode 优先返回缓存结果（Cached 为 true），未命中时调用服务商并写入缓存
+func (g *cachedGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	now := g.now()
+	key := geocodeCacheKey(address)
+	cached, err := g.store.LookupCache(key, now)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read geocode cache", "error", err)
+	}
+	if cached != nil {
+		if err := g.store.AddUsage(now.Format(geocodeUsageDayLayout), geocodeCacheProvider, 1, 0); err != nil {
+			slog.WarnContext(ctx, "Failed to record geocode cache hit", "error", err)
+		}
+		cached.Cached = true
+		return cached, nil
+	}
+
+	result, err := g.Geocoder.Geocode(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	// 离线结果来自本地数据且可能随地名表更新，不缓存
+	if result.Provider != "offline" {
+		if err := g.store.SaveCache(key, normalizeGeocodeAddress(address), result, now.Add(g.ttl)); err != nil {
+			slog.WarnContext(ctx, "Failed to write geocode cache", "provider", result.Provider, "error", err)
+		}
+	}
+	return result, nil
+}
+
+// geocodeUsageReport 汇总当天各服务商配额、缓存命中与最近 days 天的请求计数
+func geocodeUsageReport(store geocodeStore, meters []*meteredGeocoder, now time.Time, days int) (*GeocodeUsageResponse, error) {
+	today := now.Format(geocodeUsageDayLayout)
+	report := &GeocodeUsageResponse{Date: today, Providers: []GeocodeQuota{}}
+	for _, meter := range meters {
+		quota, err := meter.quota(today)
+		if err != nil {
+			return nil, err
+		}
+		report.Providers = append(report.Providers, quota)
+	}
+
+	var err error
+	if report.CacheHits, err = store.UsageOn(today, geocodeCacheProvider); err != nil {
+		return nil, err
+	}
+	if report.CacheEntries, err = store.CountCache(now); err != nil {
+		return nil, err
+	}
+	since := now.AddDate(0, 0, 1-days).Format(geocodeUsageDayLayout)
+	if report.History, err = store.UsageSince(since); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// getGeocodeUsage 返回地理编码用量，days 为历史天数（含当天，默认 7）
+func getGeocodeUsage(w http.ResponseWriter, r *http.Request) {
+	days := defaultGeocodeUsageDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 || parsed > maxGeocodeUsageDays {
+			http.Error(w, fmt.Sprintf("Invalid days (1-%d)", maxGeocodeUsageDays), http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+	if geocodeStats == nil {
+		http.Error(w, "Geocoder not configured", http.StatusInternalServerError)
+		return
+	}
+
+	report, err := geocodeUsageReport(geocodeStats, geocodeMeters, time.Now(), days)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load geocode usage", "error", err)
+		http.Error(w, "Failed to load geocode usage", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// mysqlGeocodeStore 使用 geocode_cache 与 geocode_usage 表
+type mysqlGeocodeStore struct {
+	db *sql.DB
+}
+
+func newMySQLGeocodeStore(db *sql.DB) *mysqlGeocodeStore {
+	return &mysqlGeocodeStore{db: db}
+}
+
+func (s *mysqlGeocodeStore) LookupCache(key string, now time.Time) (*GeocodeResult, error) {
+	var result GeocodeResult
+	err := s.db.QueryRow(`
+		SELECT provider, longitude, latitude, coord_system, level, precise, confidence, comprehension
+		FROM geocode_cache
+		WHERE address_hash = ? AND expires_at > ?
+	`, key, now).Scan(&result.Provider, &result.Longitude, &result.Latitude, &result.CoordSystem, &result.Level,
+		&result.Precise, &result.Confidence, &result.Comprehension)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *mysqlGeocodeStore) SaveCache(key, address string, result *GeocodeResult, expiresAt time.Time) error {
+	_, err := s.db.Exec(`
+		INSERT INTO geocode_cache (address_hash, address, provider, longitude, latitude, coord_system, level,
+		                           precise, confidence, comprehension, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			address = VALUES(address), provider = VALUES(provider),
+			longitude = VALUES(longitude), latitude = VALUES(latitude),
+			coord_system = VALUES(coord_system), level = VALUES(level),
+			precise = VALUES(precise), confidence = VALUES(confidence), comprehension = VALUES(comprehension),
+			created_at = CURRENT_TIMESTAMP, expires_at = VALUES(expires_at)
+	`, key, address, result.Provider, result.Longitude, result.Latitude, result.CoordSystem, result.Level,
+		result.Precise, result.Confidence, result.Comprehension, expiresAt)
+	return err
+}
+
+func (s *mysqlGeocodeStore) CountCache(now time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM geocode_cache WHERE expires_at > ?", now).Scan(&count)
+	return count, err
+}
+
+func (s *mysqlGeocodeStore) AddUsage(day, provider string, requests, failures int) error {
+	_, err := s.db.Exec(`
+		INSERT INTO geocode_usage (day, provider, requests, failures)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE requests = requests + VALUES(requests), failures = failures + VALUES(failures)
+	`, day, provider, requests, failures)
+	return err
+}
+
+// ReserveUsage 先确保当天的计数行存在，再用带条件的 UPDATE 加一，受影响行数为 0 表示配额已用尽
+func (s *mysqlGeocodeStore) ReserveUsage(day, provider string, quota int) (bool, error) {
+	if _, err := s.db.Exec("INSERT IGNORE INTO geocode_usage (day, provider) VALUES (?, ?)", day, provider); err != nil {
+		return false, err
+	}
+	res, err := s.db.Exec(`
+		UPDATE geocode_usage SET requests = requests + 1
+		WHERE day = ? AND provider = ? AND requests < ?
+	`, day, provider, quota)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *mysqlGeocodeStore) UsageOn(day, provider string) (int, error) {
+	var requests int
+	err := s.db.QueryRow("SELECT requests FROM geocode_usage WHERE day = ? AND provider = ?", day, provider).Scan(&requests)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return requests, err
+}
+
+func (s *mysqlGeocodeStore) UsageSince(day string) ([]GeocodeUsage, error) {
+	rows, err := s.db.Query(`
+		SELECT day, provider, requests, failures
+		FROM geocode_usage
+		WHERE day >= ?
+		ORDER BY day DESC, provider
+	`, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []GeocodeUsage{}
+	for rows.Next() {
+		var entry GeocodeUsage
+		var date time.Time
+		if err := rows.Scan(&date, &entry.Provider, &entry.Requests, &entry.Failures); err != nil {
+			return nil, err
+		}
+		entry.Date = date.Format(geocodeUsageDayLayout)
+		usage = append(usage, entry)
+	}
+	return usage, rows.Err()
+}
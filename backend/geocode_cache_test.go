@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryGeocodeStore 是 geocodeStore 的内存实现，仅用于测试
+type memoryGeocodeStore struct {
+	mu    sync.Mutex
+	cache map[string]memoryGeocodeCacheEntry
+	usage map[[2]string]*GeocodeUsage
+}
+
+type memoryGeocodeCacheEntry struct {
+	result    GeocodeResult
+	expiresAt time.Time
+}
+
+func newMemoryGeocodeStore() *memoryGeocodeStore {
+	return &memoryGeocodeStore{
+		cache: map[string]memoryGeocodeCacheEntry{},
+		usage: map[[2]string]*GeocodeUsage{},
+	}
+}
+
+func (s *memoryGeocodeStore) LookupCache(key string, now time.Time) (*GeocodeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.cache[key]
+	if !ok || !entry.expiresAt.After(now) {
+		return nil, nil
+	}
+	result := entry.result
+	return &result, nil
+}
+
+func (s *memoryGeocodeStore) SaveCache(key, address string, result *GeocodeResult, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[key] = memoryGeocodeCacheEntry{result: *result, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *memoryGeocodeStore) CountCache(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, entry := range s.cache {
+		if entry.expiresAt.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryGeocodeStore) AddUsage(day, provider string, requests, failures int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entry(day, provider)
+	entry.Requests += requests
+	entry.Failures += failures
+	return nil
+}
+
+func (s *memoryGeocodeStore) ReserveUsage(day, provider string, quota int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.entry(day, provider)
+	if entry.Requests >= quota {
+		return false, nil
+	}
+	entry.Requests++
+	return true, nil
+}
+
+func (s *memoryGeocodeStore) entry(day, provider string) *GeocodeUsage {
+	entry, ok := s.usage[[2]string{day, provider}]
+	if !ok {
+		entry = &GeocodeUsage{Date: day, Provider: provider}
+		s.usage[[2]string{day, provider}] = entry
+	}
+	return entry
+}
+
+func (s *memoryGeocodeStore) UsageOn(day, provider string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.usage[[2]string{day, provider}]; ok {
+		return entry.Requests, nil
+	}
+	return 0, nil
+}
+
+func (s *memoryGeocodeStore) UsageSince(day string) ([]GeocodeUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage := []GeocodeUsage{}
+	for _, entry := range s.usage {
+		if entry.Date >= day {
+			usage = append(usage, *entry)
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Date != usage[j].Date {
+			return usage[i].Date > usage[j].Date
+		}
+		return usage[i].Provider < usage[j].Provider
+	})
+	return usage, nil
+}
+
+func TestNormalizeGeocodeAddress(t *testing.T) {
+	want := normalizeGeocodeAddress("江苏省无锡市锡山区羊尖镇8号")
+	for _, address := range []string{
+		"江苏省无锡市 锡山区 羊尖镇８号",
+		"江苏省无锡市锡山区，羊尖镇8号。",
+		" 江苏省无锡市锡山区羊尖镇8号\t",
+	} {
+		if got := normalizeGeocodeAddress(address); got != want {
+			t.Errorf("normalizeGeocodeAddress(%q) = %q, want %q", address, got, want)
+		}
+		if geocodeCacheKey(address) != geocodeCacheKey("江苏省无锡市锡山区羊尖镇8号") {
+			t.Errorf("geocodeCacheKey(%q) differs", address)
+		}
+	}
+	if geocodeCacheKey("羊尖镇") == geocodeCacheKey("鹅湖镇") {
+		t.Error("different addresses should not share a cache key")
+	}
+}
+
+func TestCachedGeocoder(t *testing.T) {
+	store := newMemoryGeocodeStore()
+	now := time.Date(2024, 7, 1, 9, 0, 0, 0, time.Local)
+	baidu := &stubGeocoder{name: "baidu", result: &GeocodeResult{Provider: "baidu", Longitude: 120.36549, Latitude: 31.61736, CoordSystem: CoordBD09}}
+	g := &cachedGeocoder{Geocoder: baidu, store: store, ttl: 24 * time.Hour, now: func() time.Time { return now }}
+
+	first, err := g.Geocode(context.Background(), "江苏省无锡市锡山区羊尖镇")
+	if err != nil || first.Cached {
+		t.Fatalf("first Geocode = %+v, %v; want uncached result", first, err)
+	}
+	second, err := g.Geocode(context.Background(), "江苏省无锡市 锡山区羊尖镇。")
+	if err != nil || !second.Cached || second.Longitude != 120.36549 || second.CoordSystem != CoordBD09 {
+		t.Fatalf("second Geocode = %+v, %v; want cached result", second, err)
+	}
+	if baidu.calls != 1 {
+		t.Errorf("provider called %d times, want 1", baidu.calls)
+	}
+	if hits, _ := store.UsageOn("2024-07-01", geocodeCacheProvider); hits != 1 {
+		t.Errorf("cache hits = %d, want 1", hits)
+	}
+
+	// 过期后重新请求服务商
+	now = now.Add(25 * time.Hour)
+	if result, err := g.Geocode(context.Background(), "江苏省无锡市锡山区羊尖镇"); err != nil || result.Cached || baidu.calls != 2 {
+		t.Errorf("expected expired entry to be refreshed, got %+v, %v (calls %d)", result, err, baidu.calls)
+	}
+
+	// 失败与离线结果不缓存
+	failing := &stubGeocoder{name: "baidu", err: errGeocodeNotFound}
+	g.Geocoder = failing
+	for i := 0; i < 2; i++ {
+		if _, err := g.Geocode(context.Background(), "不存在的地方"); !errors.Is(err, errGeocodeNotFound) {
+			t.Errorf("expected errGeocodeNotFound, got %v", err)
+		}
+	}
+	offline := &stubGeocoder{name: "offline", result: &GeocodeResult{Provider: "offline", Longitude: 120.3544, Latitude: 31.6128}}
+	g.Geocoder = offline
+	g.Geocode(context.Background(), "西石桥")
+	g.Geocode(context.Background(), "西石桥")
+	if failing.calls != 2 || offline.calls != 2 {
+		t.Errorf("unexpected calls: failing %d, offline %d", failing.calls, offline.calls)
+	}
+}
+
+func TestMeteredGeocoderQuota(t *testing.T) {
+	store := newMemoryGeocodeStore()
+	now := time.Date(2024, 7, 1, 23, 0, 0, 0, time.Local)
+	baidu := &stubGeocoder{name: "baidu", result: &GeocodeResult{Provider: "baidu"}}
+	meter := &meteredGeocoder{Geocoder: baidu, store: store, dailyQuota: 2, now: func() time.Time { return now }}
+	offline := &stubGeocoder{name: "offline", result: &GeocodeResult{Provider: "offline"}}
+	f := &fallbackGeocoder{geocoders: []Geocoder{meter, offline}}
+
+	var providers []string
+	for i := 0; i < 3; i++ {
+		result, err := f.Geocode(context.Background(), "羊尖镇")
+		if err != nil {
+			t.Fatalf("Geocode returned error: %v", err)
+		}
+		providers = append(providers, result.Provider)
+	}
+	if providers[0] != "baidu" || providers[1] != "baidu" || providers[2] != "offline" {
+		t.Errorf("providers = %v, want baidu, baidu, offline", providers)
+	}
+	if baidu.calls != 2 {
+		t.Errorf("baidu called %d times, want 2", baidu.calls)
+	}
+	if _, err := meter.Geocode(context.Background(), "羊尖镇"); !errors.Is(err, errGeocodeQuotaExceeded) {
+		t.Errorf("expected errGeocodeQuotaExceeded, got %v", err)
+	}
+
+	// 次日配额重置
+	now = now.Add(2 * time.Hour)
+	if result, err := meter.Geocode(context.Background(), "羊尖镇"); err != nil || result.Provider != "baidu" {
+		t.Errorf("expected quota reset on the next day, got %+v, %v", result, err)
+	}
+
+	// 服务商失败同样计入请求数
+	baidu.err = &GeocodeError{Provider: "baidu", Status: "302"}
+	meter.Geocode(context.Background(), "羊尖镇")
+	usage, _ := store.UsageSince("2024-07-02")
+	if len(usage) != 1 || usage[0].Requests != 2 || usage[0].Failures != 1 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+// slowGeocoder 可被并发调用，请求间隔足以让所有请求在首个返回前检查配额
+type slowGeocoder struct{ *stubGeocoder }
+
+func (g slowGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	time.Sleep(10 * time.Millisecond)
+	return &GeocodeResult{Provider: g.name}, nil
+}
+
+func TestMeteredGeocoderQuotaUnderConcurrency(t *testing.T) {
+	store := newMemoryGeocodeStore()
+	now := time.Date(2024, 7, 1, 9, 0, 0, 0, time.Local)
+	meter := &meteredGeocoder{Geocoder: slowGeocoder{&stubGeocoder{name: "baidu"}}, store: store, dailyQuota: 3,
+		now: func() time.Time { return now }}
+
+	var wg sync.WaitGroup
+	var succeeded, exceeded int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := meter.Geocode(context.Background(), "羊尖镇")
+			switch {
+			case err == nil:
+				atomic.AddInt32(&succeeded, 1)
+			case errors.Is(err, errGeocodeQuotaExceeded):
+				atomic.AddInt32(&exceeded, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 3 || exceeded != 7 {
+		t.Errorf("succeeded %d, exceeded %d; want 3 and 7", succeeded, exceeded)
+	}
+	if used, _ := store.UsageOn("2024-07-01", "baidu"); used != 3 {
+		t.Errorf("usage = %d, want 3", used)
+	}
+}
+
+func TestGeocodeUsageReport(t *testing.T) {
+	store := newMemoryGeocodeStore()
+	store.AddUsage("2024-06-20", "baidu", 1, 0)
+	store.AddUsage("2024-06-30", "baidu", 1, 1)
+	store.AddUsage("2024-07-01", "baidu", 2, 0)
+	store.AddUsage("2024-07-01", geocodeCacheProvider, 1, 0)
+	store.SaveCache("a", "羊尖镇", &GeocodeResult{Provider: "baidu"}, time.Date(2024, 8, 1, 0, 0, 0, 0, time.Local))
+	store.SaveCache("b", "鹅湖镇", &GeocodeResult{Provider: "baidu"}, time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local))
+
+	meters := []*meteredGeocoder{
+		{Geocoder: &stubGeocoder{name: "baidu"}, store: store, dailyQuota: 5000},
+		{Geocoder: &stubGeocoder{name: "offline"}, store: store},
+	}
+	report, err := geocodeUsageReport(store, meters, time.Date(2024, 7, 1, 12, 0, 0, 0, time.Local), 7)
+	if err != nil {
+		t.Fatalf("geocodeUsageReport returned error: %v", err)
+	}
+	if report.Date != "2024-07-01" || report.CacheHits != 1 || report.CacheEntries != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(report.Providers) != 2 {
+		t.Fatalf("providers = %+v", report.Providers)
+	}
+	if baidu := report.Providers[0]; baidu.Requests != 2 || baidu.Remaining == nil || *baidu.Remaining != 4998 {
+		t.Errorf("unexpected baidu quota: %+v", baidu)
+	}
+	if offline := report.Providers[1]; offline.Requests != 0 || offline.Remaining != nil {
+		t.Errorf("unexpected offline quota: %+v", offline)
+	}
+	// 2024-06-20 不在最近 7 天内
+	if len(report.History) != 3 || report.History[0].Date != "2024-07-01" || report.History[2].Date != "2024-06-30" {
+		t.Errorf("unexpected history: %+v", report.History)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 地理编码匹配精度，数值越大越精确
+const (
+	geocodeLevelUnknown = iota
+	geocodeLevelProvince
+	geocodeLevelCity
+	geocodeLevelCounty
+	geocodeLevelTown
+	geocodeLevelStreet  // 村庄、道路
+	geocodeLevelAddress // 门址、兴趣点
+)
+
+// 地理编码质量警告
+const (
+	GeocodeWarningCoarse        = "coarse_match"   // 地址含街道、门牌，结果只精确到区县或更粗
+	GeocodeWarningLowConfidence = "low_confidence" // 服务商给出的可信度低于 GEOCODE_MIN_CONFIDENCE
+)
+
+// 粗粒度匹配的处理方式
+const (
+	GeocodePolicyWarn   = "warn"
+	GeocodePolicyReject = "reject"
+	GeocodePolicyOff    = "off"
+)
+
+const defaultGeocodeMinConfidence = 50
+
+// annotations 表 geocode_provider、geocode_level 的列宽
+const (
+	maxGeocodeProviderLength = 32
+	maxGeocodeLevelLength    = 50
+)
+
+// geocodeCoordinateTolerance 为提交坐标与服务端记录比对的容差（度），
+// 覆盖返回值保留 5 位小数与坐标系往返转换的误差
+const geocodeCoordinateTolerance = 1e-4
+
+// errGeocodeTooCoarse 表示按 GEOCODE_COARSE_MATCH_POLICY=reject 拒绝了粗粒度的匹配结果
+var errGeocodeTooCoarse = errors.New("geocode result too coarse for address")
+
+// geocodeLevelRanks 将百度、高德与离线地理编码的 level 映射为精度；未列出的级别不参与判断
+var geocodeLevelRanks = map[string]int{
+	"国家": geocodeLevelProvince, "省": geocodeLevelProvince,
+	"城市": geocodeLevelCity, "市": geocodeLevelCity,
+	"区县": geocodeLevelCounty, "开发区": geocodeLevelCounty,
+	"乡镇": geocodeLevelTown, "商圈": geocodeLevelTown, "热点商圈": geocodeLevelTown,
+	"村庄": geocodeLevelStreet, "道路": geocodeLevelStreet, "道路交叉路口": geocodeLevelStreet,
+	"地名": geocodeLevelStreet, "站点": geocodeLevelStreet,
+	"门址": geocodeLevelAddress, "门牌号": geocodeLevelAddress, "单元号": geocodeLevelAddress,
+	"兴趣点": geocodeLevelAddress, "地产小区": geocodeLevelAddress, "教育": geocodeLevelAddress,
+	"医疗": geocodeLevelAddress, "政府机构": geocodeLevelAddress, "公司企业": geocodeLevelAddress,
+	"交通设施": geocodeLevelAddress, "旅游景点": geocodeLevelAddress, "购物": geocodeLevelAddress,
+}
+
+// streetMarkers 出现在行政区划之后时，地址至少应精确到村庄、道路
+var streetMarkers = []string{"路", "街", "巷", "弄", "号", "村", "组", "小区", "大道", "桥", "公园"}
+
+// GeocodeQuality 为地理编码结果的精度与可信度，随标注保存以便审计
+type GeocodeQuality struct {
+	Provider      string   `json:"provider"`
+	Level         string   `json:"level,omitempty"`
+	Precise       *bool    `json:"precise,omitempty"`       // 百度：是否精确打点
+	Confidence    *int     `json:"confidence,omitempty"`    // 百度：打点绝对精度（0-100）
+	Comprehension *int     `json:"comprehension,omitempty"` // 百度：地址理解程度（0-100）
+	Warnings      []string `json:"warnings,omitempty"`
+}
+
+// GeocodePolicy 为地理编码质量检查的配置
+type GeocodePolicy struct {
+	CoarseMatch   string // 街道级地址只匹配到区县时：warn、reject 或 off
+	MinConfidence int    // 低于该可信度时警告，0 表示不检查
+}
+
+var geocodePolicy = GeocodePolicy{CoarseMatch: GeocodePolicyWarn, MinConfidence: defaultGeocodeMinConfidence}
+
+// loadGeocodePolicy 从环境变量读取质量检查配置，无效的策略按 warn 处理
+func loadGeocodePolicy() GeocodePolicy {
+	policy := GeocodePolicy{
+		CoarseMatch:   strings.ToLower(getEnv("GEOCODE_COARSE_MATCH_POLICY", GeocodePolicyWarn)),
+		MinConfidence: getEnvInt("GEOCODE_MIN_CONFIDENCE", defaultGeocodeMinConfidence),
+	}
+	switch policy.CoarseMatch {
+	case GeocodePolicyWarn, GeocodePolicyReject, GeocodePolicyOff:
+	default:
+		policy.CoarseMatch = GeocodePolicyWarn
+	}
+	return policy
+}
+
+// expectedGeocodeLevel 估计地址本身的精度：行政区划之后仍有道路、门牌、村等内容时为街道级
+func expectedGeocodeLevel(address string) int {
+	parts := gazetteer.Normalize(address)
+	rest := address
+	if parts.Matched {
+		rest = parts.Detail
+	}
+	rest = strings.ReplaceAll(rest, "街道", "")
+	for _, marker := range streetMarkers {
+		if strings.Contains(rest, marker) {
+			return geocodeLevelStreet
+		}
+	}
+	switch {
+	case parts.Town != "":
+		return geocodeLevelTown
+	case parts.County != "":
+		return geocodeLevelCounty
+	}
+	return geocodeLevelUnknown
+}
+
+// assessGeocodeQuality 检查结果精度是否与地址相符；策略为 reject 时粗粒度匹配返回 errGeocodeTooCoarse
+func assessGeocodeQuality(address string, result *GeocodeResult, policy GeocodePolicy) (GeocodeQuality, error) {
+	quality := GeocodeQuality{
+		Provider:      result.Provider,
+		Level:         result.Level,
+		Precise:       result.Precise,
+		Confidence:    result.Confidence,
+		Comprehension: result.Comprehension,
+	}
+
+	rank := geocodeLevelRanks[result.Level]
+	if policy.CoarseMatch != GeocodePolicyOff && rank != geocodeLevelUnknown && rank <= geocodeLevelCounty &&
+		expectedGeocodeLevel(address) >= geocodeLevelStreet {
+		if policy.CoarseMatch == GeocodePolicyReject {
+			return quality, fmt.Errorf("%w: %s matched at level %s", errGeocodeTooCoarse, result.Provider, result.Level)
+		}
+		quality.Warnings = append(quality.Warnings, GeocodeWarningCoarse)
+	}
+	if policy.MinConfidence > 0 && result.Confidence != nil && *result.Confidence < policy.MinConfidence {
+		quality.Warnings = append(quality.Warnings, GeocodeWarningLowConfidence)
+	}
+	return quality, nil
+}
+
+// geocodeQualityColumns 对应 annotations 表的 geocode_* 列，手动输入坐标的标注均为 NULL
+type geocodeQualityColumns struct {
+	Provider      sql.NullString
+	Level         sql.NullString
+	Precise       *bool
+	Confidence    *int
+	Comprehension *int
+	Warnings      sql.NullString
+}
+
+// dest 返回 Scan 使用的目标
+func (c *geocodeQualityColumns) dest() []interface{} {
+	return []interface{}{&c.Provider, &c.Level, &c.Precise, &c.Confidence, &c.Comprehension, &c.Warnings}
+}
+
+// quality 将列值转换为 GeocodeQuality，未保存时返回 nil
+func (c *geocodeQualityColumns) quality() *GeocodeQuality {
+	if !c.Provider.Valid {
+		return nil
+	}
+	q := &GeocodeQuality{
+		Provider:      c.Provider.String,
+		Level:         c.Level.String,
+		Precise:       c.Precise,
+		Confidence:    c.Confidence,
+		Comprehension: c.Comprehension,
+	}
+	if c.Warnings.String != "" {
+		q.Warnings = strings.Split(c.Warnings.String, ",")
+	}
+	return q
+}
+
+// geocodeQualityValues 返回写入 geocode_* 列的值，q 为 nil 时全部为 NULL
+func geocodeQualityValues(q *GeocodeQuality) []interface{} {
+	if q == nil || q.Provider == "" {
+		return []interface{}{nil, nil, nil, nil, nil, nil}
+	}
+	return []interface{}{q.Provider, nullString(q.Level), q.Precise, q.Confidence, q.Comprehension,
+		nullString(strings.Join(q.Warnings, ","))}
+}
+
+// validateGeocodeQuality 检查标注附带的质量信息：服务商须已注册、警告须为已知取值，长度不超过列宽
+func validateGeocodeQuality(q *GeocodeQuality) error {
+	if q == nil {
+		return nil
+	}
+	if _, ok := geocoderRegistry[q.Provider]; !ok || len(q.Provider) > maxGeocodeProviderLength {
+		return fmt.Errorf("unknown geocode provider %q", q.Provider)
+	}
+	if utf8.RuneCountInString(q.Level) > maxGeocodeLevelLength {
+		return fmt.Errorf("geocode level is too long")
+	}
+	for _, score := range []*int{q.Confidence, q.Comprehension} {
+		if score != nil && (*score < 0 || *score > 100) {
+			return fmt.Errorf("geocode confidence %d out of range [0, 100]", *score)
+		}
+	}
+	for _, warning := range q.Warnings {
+		if warning != GeocodeWarningCoarse && warning != GeocodeWarningLowConfidence {
+			return fmt.Errorf("unknown geocode warning %q", warning)
+		}
+	}
+	return nil
+}
+
+// geocodeQualitySources 为核对标注质量信息所用的服务端记录
+type geocodeQualitySources struct {
+	// suggestion 返回图片的批量地理编码建议，不存在时为 nil
+	suggestion func(imageID int) (*GeocodeSuggestion, error)
+	// cached 返回地址未过期的缓存结果，未命中时为 nil
+	cached func(address string) (*GeocodeResult, error)
+	// offline 重新执行离线地理编码，离线结果不缓存；未配置时为 nil
+	offline func(ctx context.Context, address string) (*GeocodeResult, error)
+}
+
+// serverGeocodeQualitySources 使用 geocode_suggestions、geocode_cache 与已配置的离线服务商
+func serverGeocodeQualitySources() geocodeQualitySources {
+	sources := geocodeQualitySources{suggestion: loadGeocodeSuggestion}
+	if geocodeStats != nil {
+		sources.cached = func(address string) (*GeocodeResult, error) {
+			return geocodeStats.LookupCache(geocodeCacheKey(address), time.Now())
+		}
+	}
+	for _, meter := range geocodeMeters {
+		// 直接调用服务商，核对不计入用量
+		if offline, ok := meter.Geocoder.(*OfflineGeocoder); ok {
+			sources.offline = offline.Geocode
+		}
+	}
+	return sources
+}
+
+// resolveGeocodeQuality 用服务端记录替换标注附带的质量信息，客户端提交的值只用于选择记录：
+// 依次查找图片的批量地理编码建议、标注地点的地理编码缓存与离线地理编码结果，
+// 服务商与坐标（storageCoordSystem）一致时采用服务端的质量信息；均不一致时返回 nil，按手动输入坐标处理
+func resolveGeocodeQuality(ctx context.Context, annotation Annotation, sources geocodeQualitySources) (*GeocodeQuality, error) {
+	submitted := annotation.GeocodeQuality
+	if submitted == nil {
+		return nil, nil
+	}
+	matches := func(provider string, lon, lat float64, system string) bool {
+		lon, lat = convertCoordinate(lon, lat, system, storageCoordSystem)
+		return provider == submitted.Provider &&
+			math.Abs(lon-annotation.Longitude) <= geocodeCoordinateTolerance &&
+			math.Abs(lat-annotation.Latitude) <= geocodeCoordinateTolerance
+	}
+
+	if sources.suggestion != nil {
+		suggestion, err := sources.suggestion(annotation.ImageID)
+		if err != nil {
+			return nil, err
+		}
+		if suggestion != nil && suggestion.Quality != nil && suggestion.Longitude != nil && suggestion.Latitude != nil &&
+			matches(suggestion.Quality.Provider, *suggestion.Longitude, *suggestion.Latitude, suggestion.CoordSystem) {
+			return suggestion.Quality, nil
+		}
+	}
+
+	address := prepareGeocodeAddress(annotation.Location)
+	var result *GeocodeResult
+	if sources.cached != nil {
+		cached, err := sources.cached(address)
+		if err != nil {
+			return nil, err
+		}
+		result = cached
+	}
+	if (result == nil || !matches(result.Provider, result.Longitude, result.Latitude, result.CoordSystem)) &&
+		sources.offline != nil && submitted.Provider == "offline" {
+		offline, err := sources.offline(ctx, address)
+		if err != nil && !errors.Is(err, errGeocodeNotFound) {
+			return nil, err
+		}
+		result = offline
+	}
+	if result == nil || !matches(result.Provider, result.Longitude, result.Latitude, result.CoordSystem) {
+		return nil, nil
+	}
+
+	// 结果已返回给标注人员，粗粒度匹配只记录警告
+	policy := geocodePolicy
+	if policy.CoarseMatch == GeocodePolicyReject {
+		policy.CoarseMatch = GeocodePolicyWarn
+	}
+	quality, err := assessGeocodeQuality(address, result, policy)
+	if err != nil {
+		return nil, err
+	}
+	return &quality, nil
+}
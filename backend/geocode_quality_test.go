@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestExpectedGeocodeLevel(t *testing.T) {
+	tests := []struct {
+		address string
+		want    int
+	}{
+		{"江苏省无锡市锡山区羊尖镇人民路8号", geocodeLevelStreet},
+		{"江苏省无锡市锡山区羊尖镇严家桥村", geocodeLevelStreet},
+		{"江苏省无锡市锡山区羊尖镇", geocodeLevelTown},
+		{"江苏省无锡市梁溪区崇安寺街道", geocodeLevelTown}, // “街道”为乡镇级
+		{"江苏省无锡市锡山区", geocodeLevelCounty},
+		{"南京市鼓楼区中山路1号", geocodeLevelStreet},
+		{"某地", geocodeLevelUnknown},
+	}
+	for _, tt := range tests {
+		if got := expectedGeocodeLevel(tt.address); got != tt.want {
+			t.Errorf("expectedGeocodeLevel(%q) = %d, want %d", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestAssessGeocodeQuality(t *testing.T) {
+	street := "江苏省无锡市锡山区羊尖镇人民路8号"
+	district := &GeocodeResult{Provider: "baidu", Level: "区县", Confidence: intPtr(20), Comprehension: intPtr(60)}
+	road := &GeocodeResult{Provider: "baidu", Level: "道路", Confidence: intPtr(80)}
+
+	tests := []struct {
+		name    string
+		address string
+		result  *GeocodeResult
+		policy  GeocodePolicy
+		want    []string
+		wantErr bool
+	}{
+		{"coarse warn", street, district, GeocodePolicy{CoarseMatch: GeocodePolicyWarn, MinConfidence: 50},
+			[]string{GeocodeWarningCoarse, GeocodeWarningLowConfidence}, false},
+		{"coarse reject", street, district, GeocodePolicy{CoarseMatch: GeocodePolicyReject}, nil, true},
+		{"coarse off", street, district, GeocodePolicy{CoarseMatch: GeocodePolicyOff}, nil, false},
+		// 地址本身只到区县时不算粗粒度
+		{"district address", "江苏省无锡市锡山区", district, GeocodePolicy{CoarseMatch: GeocodePolicyReject}, nil, false},
+		{"road match", street, road, GeocodePolicy{CoarseMatch: GeocodePolicyReject, MinConfidence: 50}, nil, false},
+		// 未知级别不参与判断
+		{"unknown level", street, &GeocodeResult{Provider: "tianditu", Level: "地名地址"}, GeocodePolicy{CoarseMatch: GeocodePolicyReject}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quality, err := assessGeocodeQuality(tt.address, tt.result, tt.policy)
+			if tt.wantErr {
+				if !errors.Is(err, errGeocodeTooCoarse) {
+					t.Errorf("expected errGeocodeTooCoarse, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("assessGeocodeQuality returned error: %v", err)
+			}
+			if !reflect.DeepEqual(quality.Warnings, tt.want) {
+				t.Errorf("warnings = %v, want %v", quality.Warnings, tt.want)
+			}
+			if quality.Provider != tt.result.Provider || quality.Level != tt.result.Level || quality.Confidence != tt.result.Confidence {
+				t.Errorf("unexpected quality: %+v", quality)
+			}
+		})
+	}
+}
+
+func TestLoadGeocodePolicy(t *testing.T) {
+	t.Setenv("GEOCODE_COARSE_MATCH_POLICY", "Reject")
+	t.Setenv("GEOCODE_MIN_CONFIDENCE", "70")
+	if got := loadGeocodePolicy(); got != (GeocodePolicy{CoarseMatch: GeocodePolicyReject, MinConfidence: 70}) {
+		t.Errorf("loadGeocodePolicy() = %+v", got)
+	}
+	t.Setenv("GEOCODE_COARSE_MATCH_POLICY", "ignore")
+	if got := loadGeocodePolicy(); got.CoarseMatch != GeocodePolicyWarn {
+		t.Errorf("invalid policy should fall back to warn, got %q", got.CoarseMatch)
+	}
+}
+
+func TestGeocodeQualityColumns(t *testing.T) {
+	precise := true
+	quality := &GeocodeQuality{Provider: "baidu", Level: "门址", Precise: &precise, Confidence: intPtr(80),
+		Comprehension: intPtr(100), Warnings: []string{GeocodeWarningCoarse, GeocodeWarningLowConfidence}}
+
+	values := geocodeQualityValues(quality)
+	columns := geocodeQualityColumns{
+		Provider:      sql.NullString{String: values[0].(string), Valid: true},
+		Level:         sql.NullString{String: values[1].(string), Valid: true},
+		Precise:       values[2].(*bool),
+		Confidence:    values[3].(*int),
+		Comprehension: values[4].(*int),
+		Warnings:      sql.NullString{String: values[5].(string), Valid: true},
+	}
+	if got := columns.quality(); !reflect.DeepEqual(got, quality) {
+		t.Errorf("round trip = %+v, want %+v", got, quality)
+	}
+
+	for _, v := range geocodeQualityValues(nil) {
+		if v != nil {
+			t.Errorf("expected NULL values for manual coordinates, got %v", v)
+		}
+	}
+	if (&geocodeQualityColumns{}).quality() != nil {
+		t.Error("expected nil quality for NULL columns")
+	}
+}
+
+func TestValidateGeocodeQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		quality *GeocodeQuality
+		wantErr bool
+	}{
+		{"manual", nil, false},
+		{"valid", &GeocodeQuality{Provider: "baidu", Level: "门址", Confidence: intPtr(80), Warnings: []string{GeocodeWarningCoarse}}, false},
+		{"unknown provider", &GeocodeQuality{Provider: "google"}, true},
+		{"long level", &GeocodeQuality{Provider: "amap", Level: strings.Repeat("级", 51)}, true},
+		{"confidence out of range", &GeocodeQuality{Provider: "baidu", Confidence: intPtr(1000)}, true},
+		{"unknown warning", &GeocodeQuality{Provider: "baidu", Warnings: []string{"trusted"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateGeocodeQuality(tt.quality); (err != nil) != tt.wantErr {
+				t.Errorf("validateGeocodeQuality() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveGeocodeQuality(t *testing.T) {
+	lon, lat := 120.35440, 31.61280
+	annotation := Annotation{ImageID: 1, Location: "无锡市梁溪区人民路8号", Longitude: lon, Latitude: lat,
+		CoordSystem: storageCoordSystem}
+	// 客户端伪造的高精度质量信息
+	forged := &GeocodeQuality{Provider: "baidu", Level: "门址", Confidence: intPtr(100)}
+	cached := &GeocodeResult{Provider: "baidu", Longitude: lon, Latitude: lat, CoordSystem: storageCoordSystem,
+		Level: "道路", Confidence: intPtr(30)}
+	noSuggestion := func(int) (*GeocodeSuggestion, error) { return nil, nil }
+
+	t.Run("cache record wins", func(t *testing.T) {
+		a := annotation
+		a.GeocodeQuality = forged
+		got, err := resolveGeocodeQuality(context.Background(), a, geocodeQualitySources{
+			suggestion: noSuggestion,
+			cached:     func(string) (*GeocodeResult, error) { return cached, nil },
+		})
+		if err != nil {
+			t.Fatalf("resolveGeocodeQuality returned error: %v", err)
+		}
+		if got == nil || got.Level != "道路" || *got.Confidence != 30 ||
+			!reflect.DeepEqual(got.Warnings, []string{GeocodeWarningLowConfidence}) {
+			t.Errorf("expected the cached quality, got %+v", got)
+		}
+	})
+
+	t.Run("suggestion record", func(t *testing.T) {
+		a := annotation
+		a.GeocodeQuality = forged
+		stored := &GeocodeQuality{Provider: "baidu", Level: "乡镇"}
+		got, err := resolveGeocodeQuality(context.Background(), a, geocodeQualitySources{
+			suggestion: func(int) (*GeocodeSuggestion, error) {
+				return &GeocodeSuggestion{Longitude: &lon, Latitude: &lat, CoordSystem: storageCoordSystem, Quality: stored}, nil
+			},
+		})
+		if err != nil || got != stored {
+			t.Errorf("expected the suggestion quality, got %+v (%v)", got, err)
+		}
+	})
+
+	t.Run("coordinates differ", func(t *testing.T) {
+		a := annotation
+		a.GeocodeQuality = forged
+		a.Longitude += 0.01
+		got, err := resolveGeocodeQuality(context.Background(), a, geocodeQualitySources{
+			suggestion: noSuggestion,
+			cached:     func(string) (*GeocodeResult, error) { return cached, nil },
+		})
+		if err != nil || got != nil {
+			t.Errorf("expected unverified quality to be dropped, got %+v (%v)", got, err)
+		}
+	})
+
+	t.Run("offline recomputed", func(t *testing.T) {
+		a := annotation
+		a.GeocodeQuality = &GeocodeQuality{Provider: "offline", Level: "门址"}
+		got, err := resolveGeocodeQuality(context.Background(), a, geocodeQualitySources{
+			suggestion: noSuggestion,
+			cached:     func(string) (*GeocodeResult, error) { return nil, nil },
+			offline: func(context.Context, string) (*GeocodeResult, error) {
+				return &GeocodeResult{Provider: "offline", Longitude: lon, Latitude: lat, CoordSystem: storageCoordSystem, Level: "站点"}, nil
+			},
+		})
+		if err != nil || got == nil || got.Level != "站点" {
+			t.Errorf("expected the offline quality, got %+v (%v)", got, err)
+		}
+	})
+
+	t.Run("lookup error", func(t *testing.T) {
+		a := annotation
+		a.GeocodeQuality = forged
+		_, err := resolveGeocodeQuality(context.Background(), a, geocodeQualitySources{
+			suggestion: func(int) (*GeocodeSuggestion, error) { return nil, errors.New("db down") },
+		})
+		if err == nil {
+			t.Error("expected lookup error")
+		}
+	})
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	defaultBaiduReverseURL = "https://api.map.baidu.com/reverse_geocoding/v3/"
+	defaultAmapReverseURL  = "https://restapi.amap.com/v3/geocode/regeo"
+)
+
+// errReverseGeocodeUnsupported 表示服务商不提供逆地理编码，回退时直接跳过
+var errReverseGeocodeUnsupported = errors.New("reverse geocoding not supported")
+
+// AddressComponents 为逆地理编码得到的行政区划与街道门牌
+type AddressComponents struct {
+	Province     string `json:"province,omitempty"`
+	City         string `json:"city,omitempty"`
+	County       string `json:"county,omitempty"`
+	Town         string `json:"town,omitempty"`
+	Street       string `json:"street,omitempty"`
+	StreetNumber string `json:"street_number,omitempty"`
+}
+
+// ReverseGeocodeResult 为逆地理编码结果
+type ReverseGeocodeResult struct {
+	Provider   string
+	Address    string // 服务商给出的格式化地址
+	Components AddressComponents
+}
+
+// ReverseGeocodeRequest 为 /api/geocode/reverse 的请求体，未提供经纬度时使用 image_id 图片的 EXIF GPS 坐标
+type ReverseGeocodeRequest struct {
+	Longitude *float64 `json:"longitude"`
+	Latitude  *float64 `json:"latitude"`
+	ImageID   *int     `json:"image_id"`
+}
+
+// ReverseGeocodeResponse 为逆地理编码返回值，坐标为请求的 coord_system 坐标系
+type ReverseGeocodeResponse struct {
+	Longitude   float64           `json:"longitude"`
+	Latitude    float64           `json:"latitude"`
+	CoordSystem string            `json:"coord_system"`
+	Provider    string            `json:"provider"`
+	Address     string            `json:"address"`
+	Components  AddressComponents `json:"components"`
+}
+
+// ReverseGeocode 依次调用支持逆地理编码的服务商，不支持的服务商直接跳过
+func (f *fallbackGeocoder) ReverseGeocode(ctx context.Context, lon, lat float64) (*ReverseGeocodeResult, error) {
+	errs := []error{}
+	for _, g := range f.geocoders {
+		result, err := f.reverseGeocodeWith(ctx, g, lon, lat)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !errors.Is(err, errReverseGeocodeUnsupported) {
+			slog.WarnContext(ctx, "Reverse geocode provider failed", "provider", g.Name(), "longitude", lon, "latitude", lat, "error", err)
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (f *fallbackGeocoder) reverseGeocodeWith(ctx context.Context, g Geocoder, lon, lat float64) (*ReverseGeocodeResult, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+	return g.ReverseGeocode(ctx, lon, lat)
+}
+
+// ReverseGeocode 与正向地理编码共用当天配额与计数，不支持的服务商不计数
+func (g *meteredGeocoder) ReverseGeocode(ctx context.Context, lon, lat float64) (*ReverseGeocodeResult, error) {
+	day := g.now().Format(geocodeUsageDayLayout)
+	reserved, err := g.reserve(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+	result, err := g.Geocoder.ReverseGeocode(ctx, lon, lat)
+	if errors.Is(err, errReverseGeocodeUnsupported) {
+		g.release(ctx, day, reserved)
+	} else {
+		g.record(ctx, day, reserved, err)
+	}
+	return result, err
+}
+
+// baiduReverseGeocodingResponse 为百度逆地理编码 v3 回包
+type baiduReverseGeocodingResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Result  struct {
+		FormattedAddress string `json:"formatted_address"`
+		AddressComponent struct {
+			Province     string `json:"province"`
+			City         string `json:"city"`
+			District     string `json:"district"`
+			Town         string `json:"town"`
+			Street       string `json:"street"`
+			StreetNumber string `json:"street_number"`
+		} `json:"addressComponent"`
+	} `json:"result"`
+}
+
+// ReverseGeocode 调用百度逆地理编码，坐标先转换为 BD-09
+func (g *BaiduGeocoder) ReverseGeocode(ctx context.Context, lon, lat float64) (*ReverseGeocodeResult, error) {
+	bdLon, bdLat := convertCoordinate(lon, lat, storageCoordSystem, CoordBD09)
+	apiURL := fmt.Sprintf("%s?location=%s&coordtype=bd09ll&extensions_town=true&output=json&ak=%s",
+		g.ReverseURL, url.QueryEscape(fmt.Sprintf("%.6f,%.6f", bdLat, bdLon)), g.AK)
+
+	var resp baiduReverseGeocodingResponse
+	if err := getGeocodeJSON(ctx, g.Name(), apiURL, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != 0 {
+		return nil, &GeocodeError{Provider: g.Name(), Status: strconv.Itoa(resp.Status), Message: resp.Message}
+	}
+	if resp.Result.FormattedAddress == "" {
+		return nil, errGeocodeNotFound
+	}
+	c := resp.Result.AddressComponent
+	return &ReverseGeocodeResult{
+		Provider: g.Name(),
+		Address:  resp.Result.FormattedAddress,
+		Components: AddressComponents{Province: c.Province, City: c.City, County: c.District,
+			Town: c.Town, Street: c.Street, StreetNumber: c.StreetNumber},
+	}, nil
+}
+
+// amapString 兼容高德回包中为空时返回 [] 的字符串字段（如直辖市的 city）
+type amapString string
+
+func (s *amapString) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		*s = ""
+		return nil
+	}
+	*s = amapString(value)
+	return nil
+}
+
+// amapReverseGeocodingResponse 为高德逆地理编码回包
+type amapReverseGeocodingResponse struct {
+	Status    string `json:"status"`
+	Info      string `json:"info"`
+	InfoCode  string `json:"infocode"`
+	Regeocode *struct {
+		FormattedAddress amapString `json:"formatted_address"`
+		AddressComponent struct {
+			Province     amapString `json:"province"`
+			City         amapString `json:"city"`
+			District     amapString `json:"district"`
+			Township     amapString `json:"township"`
+			StreetNumber struct {
+				Street amapString `json:"street"`
+				Number amapString `json:"number"`
+			} `json:"streetNumber"`
+		} `json:"addressComponent"`
+	} `json:"regeocode"`
+}
+
+// ReverseGeocode 调用高德逆地理编码，坐标先转换为 GCJ-02
+func (g *AmapGeocoder) ReverseGeocode(ctx context.Context, lon, lat float64) (*ReverseGeocodeResult, error) {
+	gcjLon, gcjLat := convertCoordinate(lon, lat, storageCoordSystem, CoordGCJ02)
+	apiURL := fmt.Sprintf("%s?location=%s&output=JSON&key=%s",
+		g.ReverseURL, url.QueryEscape(fmt.Sprintf("%.6f,%.6f", gcjLon, gcjLat)), g.Key)
+
+	var resp amapReverseGeocodingResponse
+	if err := getGeocodeJSON(ctx, g.Name(), apiURL, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "1" {
+		return nil, &GeocodeError{Provider: g.Name(), Status: resp.InfoCode, Message: resp.Info}
+	}
+	if resp.Regeocode == nil || resp.Regeocode.FormattedAddress == "" {
+		return nil, errGeocodeNotFound
+	}
+	c := resp.Regeocode.AddressComponent
+	return &ReverseGeocodeResult{
+		Provider: g.Name(),
+		Address:  string(resp.Regeocode.FormattedAddress),
+		Components: AddressComponents{Province: string(c.Province), City: string(c.City), County: string(c.District),
+			Town: string(c.Township), Street: string(c.StreetNumber.Street), StreetNumber: string(c.StreetNumber.Number)},
+	}, nil
+}
+
+// tiandituReverseGeocodingResponse 为天地图逆地理编码回包
+type tiandituReverseGeocodingResponse struct {
+	Status string `json:"status"`
+	Msg    string `json:"msg"`
+	Result *struct {
+		FormattedAddress string `json:"formatted_address"`
+		AddressComponent struct {
+			Province string `json:"province"`
+			City     string `json:"city"`
+			County   string `json:"county"`
+			Town     string `json:"town"`
+			Road     string `json:"road"`
+		} `json:"addressComponent"`
+	} `json:"result"`
+}
+
+// ReverseGeocode 调用天地图逆地理编码（postStr + type=geocode）
+func (g *TiandituGeocoder) ReverseGeocode(ctx context.Context, lon, lat float64) (*ReverseGeocodeResult, error) {
+	postStr, err := json.Marshal(map[string]float64{"lon": lon, "lat": lat, "ver": 1})
+	if err != nil {
+		return nil, err
+	}
+	apiURL := fmt.Sprintf("%s?postStr=%s&type=geocode&tk=%s", g.ReverseURL, url.QueryEscape(string(postStr)), g.TK)
+
+	var resp tiandituReverseGeocodingResponse
+	if err := getGeocodeJSON(ctx, g.Name(), apiURL, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "0" {
+		return nil, &GeocodeError{Provider: g.Name(), Status: resp.Status, Message: resp.Msg}
+	}
+	if resp.Result == nil || resp.Result.FormattedAddress == "" {
+		return nil, errGeocodeNotFound
+	}
+	c := resp.Result.AddressComponent
+	return &ReverseGeocodeResult{
+		Provider: g.Name(),
+		Address:  resp.Result.FormattedAddress,
+		Components: AddressComponents{Province: c.Province, City: c.City, County: c.County,
+			Town: c.Town, Street: c.Road},
+	}, nil
+}
+
+// ReverseGeocode 离线地名表没有行政区划信息，不支持逆地理编码
+func (g *OfflineGeocoder) ReverseGeocode(ctx context.Context, lon, lat float64) (*ReverseGeocodeResult, error) {
+	return nil, errReverseGeocodeUnsupported
+}
+
+// reverseGeocode 将经纬度（地图点选或图片 EXIF GPS）转换为地址与行政区划，用于水印没有地点文字时填写标注地点
+func reverseGeocode(w http.ResponseWriter, r *http.Request) {
+	coordSystem, err := coordSystemParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req ReverseGeocodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// lon/lat 为 WGS-84，与数据库及服务商调用一致
+	var lon, lat float64
+	switch {
+	case req.Longitude != nil && req.Latitude != nil:
+		if *req.Longitude < -180 || *req.Longitude > 180 || *req.Latitude < -90 || *req.Latitude > 90 {
+			http.Error(w, "Coordinates out of range", http.StatusBadRequest)
+			return
+		}
+		lon, lat = convertCoordinate(*req.Longitude, *req.Latitude, coordSystem, storageCoordSystem)
+	case req.ImageID != nil:
+		var exifLon, exifLat sql.NullFloat64
+		err := db.QueryRow("SELECT exif_longitude, exif_latitude FROM images WHERE id = ?", *req.ImageID).Scan(&exifLon, &exifLat)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Image not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !exifLon.Valid || !exifLat.Valid {
+			http.Error(w, "图片没有 EXIF GPS 坐标", http.StatusBadRequest)
+			return
+		}
+		lon, lat = exifLon.Float64, exifLat.Float64
+	default:
+		http.Error(w, "longitude and latitude (or image_id) are required", http.StatusBadRequest)
+		return
+	}
+
+	if geocoder == nil {
+		http.Error(w, "Geocoder not configured", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := geocoder.ReverseGeocode(r.Context(), lon, lat)
+	if err != nil {
+		writeGeocodeError(w, r, err, "请检查经纬度是否正确")
+		return
+	}
+
+	response := ReverseGeocodeResponse{
+		CoordSystem: coordSystem,
+		Provider:    result.Provider,
+		Address:     result.Address,
+		Components:  result.Components,
+	}
+	response.Longitude, response.Latitude = convertCoordinate(lon, lat, storageCoordSystem, coordSystem)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProviderReverseGeocoders(t *testing.T) {
+	// 无锡本站，WGS-84
+	lon, lat := 120.3544, 31.6128
+	bdLon, bdLat := convertCoordinate(lon, lat, CoordWGS84, CoordBD09)
+	gcjLon, gcjLat := convertCoordinate(lon, lat, CoordWGS84, CoordGCJ02)
+
+	tests := []struct {
+		provider     string
+		response     string
+		wantLocation string
+		want         AddressComponents
+	}{
+		{"baidu", `{"status":0,"result":{"formatted_address":"江苏省无锡市锡山区人民路8号","addressComponent":{"province":"江苏省","city":"无锡市","district":"锡山区","town":"羊尖镇","street":"人民路","street_number":"8号"}}}`,
+			fmt.Sprintf("%.6f,%.6f", bdLat, bdLon),
+			AddressComponents{Province: "江苏省", City: "无锡市", County: "锡山区", Town: "羊尖镇", Street: "人民路", StreetNumber: "8号"}},
+		// 高德字段为空时返回 []
+		{"amap", `{"status":"1","info":"OK","infocode":"10000","regeocode":{"formatted_address":"江苏省无锡市锡山区羊尖镇人民路8号","addressComponent":{"province":"江苏省","city":"无锡市","district":"锡山区","township":"羊尖镇","streetNumber":{"street":"人民路","number":[]}}}}`,
+			fmt.Sprintf("%.6f,%.6f", gcjLon, gcjLat),
+			AddressComponents{Province: "江苏省", City: "无锡市", County: "锡山区", Town: "羊尖镇", Street: "人民路"}},
+		{"tianditu", `{"result":{"formatted_address":"江苏省无锡市锡山区人民路","addressComponent":{"province":"江苏省","city":"无锡市","county":"锡山区","town":"羊尖镇","road":"人民路"}},"msg":"ok","status":"0"}`,
+			"",
+			AddressComponents{Province: "江苏省", City: "无锡市", County: "锡山区", Town: "羊尖镇", Street: "人民路"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.provider == "tianditu" {
+					var post map[string]float64
+					if err := json.Unmarshal([]byte(r.URL.Query().Get("postStr")), &post); err != nil || post["lon"] != lon || post["lat"] != lat {
+						t.Errorf("unexpected postStr %q", r.URL.Query().Get("postStr"))
+					}
+				} else if got := r.URL.Query().Get("location"); got != tt.wantLocation {
+					t.Errorf("location = %q, want %q", got, tt.wantLocation)
+				}
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			g, err := NewGeocoder(GeocoderConfig{Provider: tt.provider, APIKey: "secret", ReverseURL: server.URL})
+			if err != nil {
+				t.Fatalf("NewGeocoder returned error: %v", err)
+			}
+			result, err := g.ReverseGeocode(context.Background(), lon, lat)
+			if err != nil {
+				t.Fatalf("ReverseGeocode returned error: %v", err)
+			}
+			if result.Provider != tt.provider || result.Address == "" || result.Components != tt.want {
+				t.Errorf("unexpected result: %+v", result)
+			}
+		})
+	}
+}
+
+func TestReverseGeocodeErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"0","info":"INVALID_USER_KEY","infocode":"10001"}`))
+	}))
+	defer server.Close()
+	g, _ := NewGeocoder(GeocoderConfig{Provider: "amap", APIKey: "secret", ReverseURL: server.URL})
+	var geocodeErr *GeocodeError
+	if _, err := g.ReverseGeocode(context.Background(), 120.3544, 31.6128); !errors.As(err, &geocodeErr) || geocodeErr.Status != "10001" {
+		t.Errorf("expected GeocodeError 10001, got %v", err)
+	}
+
+	offline, _ := NewGeocoder(GeocoderConfig{Provider: "offline"})
+	if _, err := offline.ReverseGeocode(context.Background(), 120.3544, 31.6128); !errors.Is(err, errReverseGeocodeUnsupported) {
+		t.Errorf("expected errReverseGeocodeUnsupported, got %v", err)
+	}
+}
+
+func TestFallbackReverseGeocode(t *testing.T) {
+	store := newMemoryGeocodeStore()
+	now := func() time.Time { return time.Date(2024, 7, 1, 9, 0, 0, 0, time.Local) }
+	offline := &meteredGeocoder{Geocoder: &stubGeocoder{name: "offline"}, store: store, now: now}
+	baidu := &meteredGeocoder{Geocoder: &stubGeocoder{name: "baidu", err: &GeocodeError{Provider: "baidu", Status: "302"}}, store: store, now: now}
+	amap := &meteredGeocoder{Geocoder: &stubGeocoder{name: "amap", reverse: &ReverseGeocodeResult{Provider: "amap", Address: "江苏省无锡市锡山区羊尖镇"}}, store: store, now: now}
+
+	f := &fallbackGeocoder{geocoders: []Geocoder{offline, baidu, amap}}
+	result, err := f.ReverseGeocode(context.Background(), 120.3544, 31.6128)
+	if err != nil || result.Provider != "amap" {
+		t.Fatalf("ReverseGeocode = %+v, %v; want amap result", result, err)
+	}
+	// 不支持逆地理编码的服务商不计入用量
+	for provider, want := range map[string]int{"offline": 0, "baidu": 1, "amap": 1} {
+		if got, _ := store.UsageOn("2024-07-01", provider); got != want {
+			t.Errorf("usage of %s = %d, want %d", provider, got, want)
+		}
+	}
+
+	f = &fallbackGeocoder{geocoders: []Geocoder{offline}}
+	if _, err := f.ReverseGeocode(context.Background(), 120.3544, 31.6128); !errors.Is(err, errReverseGeocodeUnsupported) {
+		t.Errorf("expected errReverseGeocodeUnsupported, got %v", err)
+	}
+}
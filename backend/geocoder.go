@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGeocodeProviders = "baidu,offline"
+	defaultBaiduGeocodeURL  = "https://api.map.baidu.com/geocoding/v3/"
+	defaultAmapGeocodeURL   = "https://restapi.amap.com/v3/geocode/geo"
+	defaultTiandituURL      = "http://api.tianditu.gov.cn/geocoder"
+
+	// 离线地理编码只采用完整包含站名（或别名）的匹配
+	offlineStationMinScore = 0.8
+)
+
+var (
+	// errGeocoderNotConfigured 表示服务商缺少必需配置（如 AK），初始化时跳过该服务商
+	errGeocoderNotConfigured = errors.New("geocoder not configured")
+	// errGeocodeNotFound 表示服务商没有找到该地址
+	errGeocodeNotFound = errors.New("address not found")
+)
+
+// GeocodeError 为服务商返回的错误状态（配额用尽、AK 无效、地址无法解析等）
+type GeocodeError struct {
+	Provider string
+	Status   string
+	Message  string
+}
+
+func (e *GeocodeError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s geocoding failed with status %s", e.Provider, e.Status)
+	}
+	return fmt.Sprintf("%s geocoding failed with status %s: %s", e.Provider, e.Status, e.Message)
+}
+
+// GeocodeResult 为地理编码结果，坐标属于 CoordSystem 坐标系
+type GeocodeResult struct {
+	Provider    string
+	Longitude   float64
+	Latitude    float64
+	CoordSystem string
+	Level       string // 服务商给出的匹配级别，如“乡镇”“道路”
+	Cached      bool   // 来自 geocode_cache 而非本次请求
+	// 百度返回的精度信息，其他服务商为 nil
+	Precise       *bool
+	Confidence    *int
+	Comprehension *int
+}
+
+// Geocoder 将地址转换为经纬度，或将经纬度（WGS-84）转换为地址；
+// 不支持逆地理编码的服务商返回 errReverseGeocodeUnsupported
+type Geocoder interface {
+	Name() string
+	Geocode(ctx context.Context, address string) (*GeocodeResult, error)
+	ReverseGeocode(ctx context.Context, lon, lat float64) (*ReverseGeocodeResult, error)
+}
+
+// GeocoderConfig 描述一个地理编码服务商的配置
+type GeocoderConfig struct {
+	Provider   string
+	APIKey     string
+	BaseURL    string
+	ReverseURL string // 逆地理编码接口地址，为空时使用服务商默认地址
+	DailyQuota int    // 每天最多请求次数，0 表示不限
+}
+
+// GeocoderFactory 根据配置创建 Geocoder
+type GeocoderFactory func(cfg GeocoderConfig) (Geocoder, error)
+
+var geocoderRegistry = map[string]GeocoderFactory{}
+
+// geocoderEnv 为各服务商读取密钥、接口地址与每日配额的环境变量
+var geocoderEnv = map[string]struct{ key, url, reverseURL, quota string }{
+	"baidu":    {"BAIDU_MAP_AK", "BAIDU_GEOCODE_URL", "BAIDU_REVERSE_GEOCODE_URL", "BAIDU_DAILY_QUOTA"},
+	"amap":     {"AMAP_KEY", "AMAP_GEOCODE_URL", "AMAP_REVERSE_GEOCODE_URL", "AMAP_DAILY_QUOTA"},
+	"tianditu": {"TIANDITU_TK", "TIANDITU_GEOCODE_URL", "TIANDITU_REVERSE_GEOCODE_URL", "TIANDITU_DAILY_QUOTA"},
+}
+
+// geocoder 为当前生效的地理编码器（按 GEOCODE_PROVIDERS 顺序回退），nil 表示未配置
+var geocoder Geocoder
+
+func init() {
+	RegisterGeocoder("baidu", newBaiduGeocoder)
+	RegisterGeocoder("amap", newAmapGeocoder)
+	RegisterGeocoder("tianditu", newTiandituGeocoder)
+	RegisterGeocoder("offline", newOfflineGeocoder)
+}
+
+// RegisterGeocoder 注册一个地理编码服务商，同名注册会覆盖旧值
+func RegisterGeocoder(name string, factory GeocoderFactory) {
+	geocoderRegistry[strings.ToLower(name)] = factory
+}
+
+func registeredGeocoders() []string {
+	names := make([]string, 0, len(geocoderRegistry))
+	for name := range geocoderRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewGeocoder 按 cfg.Provider 创建地理编码器
+func NewGeocoder(cfg GeocoderConfig) (Geocoder, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	factory, ok := geocoderRegistry[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown geocode provider %q (available: %s)",
+			cfg.Provider, strings.Join(registeredGeocoders(), ", "))
+	}
+	cfg.Provider = provider
+	return factory(cfg)
+}
+
+// loadGeocoderConfigs 按 GEOCODE_PROVIDERS（逗号分隔，按顺序回退）读取各服务商配置
+func loadGeocoderConfigs() []GeocoderConfig {
+	configs := []GeocoderConfig{}
+	for _, provider := range strings.Split(getEnv("GEOCODE_PROVIDERS", defaultGeocodeProviders), ",") {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if provider == "" {
+			continue
+		}
+		env := geocoderEnv[provider]
+		cfg := GeocoderConfig{Provider: provider}
+		if env.key != "" {
+			cfg.APIKey = getEnv(env.key, "")
+			cfg.BaseURL = getEnv(env.url, "")
+			cfg.ReverseURL = getEnv(env.reverseURL, "")
+			cfg.DailyQuota = getEnvInt(env.quota, 0)
+		}
+		configs = append(configs, cfg)
+	}
+	return configs
+}
+
+// initGeocoder 在启动时创建地理编码器，缺少配置的服务商被跳过，全部缺失时 /api/geocode 不可用。
+// 各服务商按天计数并受 *_DAILY_QUOTA 限制，结果按 GEOCODE_CACHE_TTL_HOURS 缓存
+func initGeocoder() error {
+	store := newMySQLGeocodeStore(db)
+	geocodeStats = store
+	geocodeMeters = nil
+	geocoders := []Geocoder{}
+	for _, cfg := range loadGeocoderConfigs() {
+		g, err := NewGeocoder(cfg)
+		if errors.Is(err, errGeocoderNotConfigured) {
+			slog.Warn("Geocode provider not configured, skipping", "provider", cfg.Provider)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		meter := &meteredGeocoder{Geocoder: g, store: store, dailyQuota: cfg.DailyQuota, now: time.Now}
+		geocodeMeters = append(geocodeMeters, meter)
+		geocoders = append(geocoders, meter)
+	}
+	if len(geocoders) == 0 {
+		slog.Warn("No geocode provider configured, geocoding disabled")
+		geocoder = nil
+		return nil
+	}
+	geocoder = &fallbackGeocoder{geocoders: geocoders, timeout: getGeocodeTimeout()}
+	if ttl := getGeocodeCacheTTL(); ttl > 0 {
+		geocoder = &cachedGeocoder{Geocoder: geocoder, store: store, ttl: ttl, now: time.Now}
+	}
+	slog.Info("Geocode providers initialized", "providers", geocoder.Name())
+	return nil
+}
+
+// fallbackGeocoder 按顺序调用各服务商，前一个失败（配额用尽、超时、无结果）时尝试下一个
+type fallbackGeocoder struct {
+	geocoders []Geocoder
+	timeout   time.Duration // 单个服务商的调用时长上限
+}
+
+// Name 返回按回退顺序排列的服务商名称
+func (f *fallbackGeocoder) Name() string {
+	names := make([]string, len(f.geocoders))
+	for i, g := range f.geocoders {
+		names[i] = g.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Geocode 返回第一个成功的结果；全部失败时返回各服务商错误的合并
+func (f *fallbackGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	errs := []error{}
+	for _, g := range f.geocoders {
+		result, err := f.geocodeWith(ctx, g, address)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		slog.WarnContext(ctx, "Geocode provider failed", "provider", g.Name(), "address", address, "error", err)
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (f *fallbackGeocoder) geocodeWith(ctx context.Context, g Geocoder, address string) (*GeocodeResult, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+	return g.Geocode(ctx, address)
+}
+
+// getGeocodeJSON 请求服务商接口并解析 JSON 回包；URL 含密钥，日志与错误中只出现脱敏后的地址
+func getGeocodeJSON(ctx context.Context, provider, apiURL string, out interface{}) error {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return redactError(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return redactError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	slog.DebugContext(ctx, "Geocode provider responded", "provider", provider, "url", logRedactor.Redact(apiURL),
+		"status", resp.StatusCode, "bytes", len(body), "latency", time.Since(start))
+
+	if resp.StatusCode != http.StatusOK {
+		return &GeocodeError{Provider: provider, Status: strconv.Itoa(resp.StatusCode), Message: "unexpected HTTP status"}
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parse %s geocoding response: %w", provider, err)
+	}
+	return nil
+}
+
+// Baidu Geocoding API structures
+type BaiduGeocodingResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Result  struct {
+		Location struct {
+			Lng float64 `json:"lng"`
+			Lat float64 `json:"lat"`
+		} `json:"location"`
+		Precise       int    `json:"precise"`
+		Confidence    int    `json:"confidence"`
+		Comprehension int    `json:"comprehension"`
+		Level         string `json:"level"`
+	} `json:"result"`
+}
+
+// BaiduGeocoder 调用百度地理编码 v3，返回 BD-09 坐标
+type BaiduGeocoder struct {
+	AK         string
+	BaseURL    string
+	ReverseURL string
+}
+
+func newBaiduGeocoder(cfg GeocoderConfig) (Geocoder, error) {
+	if cfg.APIKey == "" {
+		return nil, errGeocoderNotConfigured
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaiduGeocodeURL
+	}
+	if cfg.ReverseURL == "" {
+		cfg.ReverseURL = defaultBaiduReverseURL
+	}
+	return &BaiduGeocoder{AK: cfg.APIKey, BaseURL: cfg.BaseURL, ReverseURL: cfg.ReverseURL}, nil
+}
+
+// Name 返回服务商名称
+func (g *BaiduGeocoder) Name() string {
+	return "baidu"
+}
+
+// Geocode 调用百度地理编码接口
+func (g *BaiduGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	apiURL := fmt.Sprintf("%s?address=%s&output=json&ak=%s", g.BaseURL, url.QueryEscape(address), g.AK)
+
+	var resp BaiduGeocodingResponse
+	if err := getGeocodeJSON(ctx, g.Name(), apiURL, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != 0 {
+		return nil, &GeocodeError{Provider: g.Name(), Status: strconv.Itoa(resp.Status), Message: resp.Message}
+	}
+	precise := resp.Result.Precise == 1
+	return &GeocodeResult{
+		Provider:      g.Name(),
+		Longitude:     resp.Result.Location.Lng,
+		Latitude:      resp.Result.Location.Lat,
+		CoordSystem:   CoordBD09,
+		Level:         resp.Result.Level,
+		Precise:       &precise,
+		Confidence:    &resp.Result.Confidence,
+		Comprehension: &resp.Result.Comprehension,
+	}, nil
+}
+
+// amapGeocodingResponse 为高德地理编码回包，location 为 "经度,纬度"
+type amapGeocodingResponse struct {
+	Status   string `json:"status"`
+	Info     string `json:"info"`
+	InfoCode string `json:"infocode"`
+	Geocodes []struct {
+		FormattedAddress string `json:"formatted_address"`
+		Location         string `json:"location"`
+		Level            string `json:"level"`
+	} `json:"geocodes"`
+}
+
+// AmapGeocoder 调用高德地理编码，返回 GCJ-02 坐标
+type AmapGeocoder struct {
+	Key        string
+	BaseURL    string
+	ReverseURL string
+}
+
+func newAmapGeocoder(cfg GeocoderConfig) (Geocoder, error) {
+	if cfg.APIKey == "" {
+		return nil, errGeocoderNotConfigured
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultAmapGeocodeURL
+	}
+	if cfg.ReverseURL == "" {
+		cfg.ReverseURL = defaultAmapReverseURL
+	}
+	return &AmapGeocoder{Key: cfg.APIKey, BaseURL: cfg.BaseURL, ReverseURL: cfg.ReverseURL}, nil
+}
+
+// Name 返回服务商名称
+func (g *AmapGeocoder) Name() string {
+	return "amap"
+}
+
+// Geocode 调用高德地理编码接口，取第一条结果
+func (g *AmapGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	apiURL := fmt.Sprintf("%s?address=%s&output=JSON&key=%s", g.BaseURL, url.QueryEscape(address), g.Key)
+
+	var resp amapGeocodingResponse
+	if err := getGeocodeJSON(ctx, g.Name(), apiURL, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "1" {
+		return nil, &GeocodeError{Provider: g.Name(), Status: resp.InfoCode, Message: resp.Info}
+	}
+	if len(resp.Geocodes) == 0 {
+		return nil, errGeocodeNotFound
+	}
+	lon, lat, err := parseLonLat(resp.Geocodes[0].Location)
+	if err != nil {
+		return nil, fmt.Errorf("amap: %w", err)
+	}
+	return &GeocodeResult{
+		Provider:    g.Name(),
+		Longitude:   lon,
+		Latitude:    lat,
+		CoordSystem: CoordGCJ02,
+		Level:       resp.Geocodes[0].Level,
+	}, nil
+}
+
+// parseLonLat 解析 "经度,纬度" 格式的坐标
+func parseLonLat(value string) (float64, float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid location %q", value)
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid location %q", value)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid location %q", value)
+	}
+	return lon, lat, nil
+}
+
+// tiandituGeocodingResponse 为天地图地理编码回包
+type tiandituGeocodingResponse struct {
+	Status   string `json:"status"`
+	Msg      string `json:"msg"`
+	Location *struct {
+		Lon   float64 `json:"lon"`
+		Lat   float64 `json:"lat"`
+		Level string  `json:"level"`
+	} `json:"location"`
+}
+
+// TiandituGeocoder 调用天地图地理编码，返回 CGCS2000 坐标（与 WGS-84 相差在厘米级，按 WGS-84 处理）
+type TiandituGeocoder struct {
+	TK         string
+	BaseURL    string
+	ReverseURL string
+}
+
+func newTiandituGeocoder(cfg GeocoderConfig) (Geocoder, error) {
+	if cfg.APIKey == "" {
+		return nil, errGeocoderNotConfigured
+	}
+	// 天地图正向与逆地理编码为同一接口
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultTiandituURL
+	}
+	if cfg.ReverseURL == "" {
+		cfg.ReverseURL = cfg.BaseURL
+	}
+	return &TiandituGeocoder{TK: cfg.APIKey, BaseURL: cfg.BaseURL, ReverseURL: cfg.ReverseURL}, nil
+}
+
+// Name 返回服务商名称
+func (g *TiandituGeocoder) Name() string {
+	return "tianditu"
+}
+
+// Geocode 调用天地图地理编码接口
+func (g *TiandituGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	ds, err := json.Marshal(map[string]string{"keyWord": address})
+	if err != nil {
+		return nil, err
+	}
+	apiURL := fmt.Sprintf("%s?ds=%s&tk=%s", g.BaseURL, url.QueryEscape(string(ds)), g.TK)
+
+	var resp tiandituGeocodingResponse
+	if err := getGeocodeJSON(ctx, g.Name(), apiURL, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "0" {
+		return nil, &GeocodeError{Provider: g.Name(), Status: resp.Status, Message: resp.Msg}
+	}
+	if resp.Location == nil {
+		return nil, errGeocodeNotFound
+	}
+	return &GeocodeResult{
+		Provider:    g.Name(),
+		Longitude:   resp.Location.Lon,
+		Latitude:    resp.Location.Lat,
+		CoordSystem: CoordWGS84,
+		Level:       resp.Location.Level,
+	}, nil
+}
+
+// KnownPlace 为离线地理编码使用的已知地名，坐标已转换为 storageCoordSystem
+type KnownPlace struct {
+	Name      string
+	Longitude float64
+	Latitude  float64
+}
+
+// OfflineGeocoder 不访问网络，从 geocode_places 地名表与站名、别名中查找地址，
+// 用于 AK 配额用尽或部署环境无法联网时
+type OfflineGeocoder struct {
+	load func() ([]KnownPlace, []StationWithAliases, error)
+}
+
+func newOfflineGeocoder(cfg GeocoderConfig) (Geocoder, error) {
+	return &OfflineGeocoder{load: loadOfflineGeocodeData}, nil
+}
+
+// Name 返回服务商名称
+func (g *OfflineGeocoder) Name() string {
+	return "offline"
+}
+
+// Geocode 优先返回地址中包含的最长地名，其次为完整包含站名或别名的站点
+func (g *OfflineGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	places, stations, err := g.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var best *KnownPlace
+	for i, place := range places {
+		if place.Name != "" && strings.Contains(address, place.Name) &&
+			(best == nil || len(place.Name) > len(best.Name)) {
+			best = &places[i]
+		}
+	}
+	if best != nil {
+		return &GeocodeResult{Provider: g.Name(), Longitude: best.Longitude, Latitude: best.Latitude,
+			CoordSystem: storageCoordSystem, Level: "地名"}, nil
+	}
+
+	if matches := matchStations(address, stations, 1); len(matches) > 0 && matches[0].Score >= offlineStationMinScore {
+		station := matches[0].Station
+		return &GeocodeResult{Provider: g.Name(), Longitude: station.Longitude, Latitude: station.Latitude,
+			CoordSystem: storageCoordSystem, Level: "站点"}, nil
+	}
+	return nil, errGeocodeNotFound
+}
+
+// loadOfflineGeocodeData 读取地名表与站点
+func loadOfflineGeocodeData() ([]KnownPlace, []StationWithAliases, error) {
+	rows, err := db.Query("SELECT name, longitude, latitude, coord_system FROM geocode_places")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	places := []KnownPlace{}
+	for rows.Next() {
+		var place KnownPlace
+		var coordSystem string
+		if err := rows.Scan(&place.Name, &place.Longitude, &place.Latitude, &coordSystem); err != nil {
+			return nil, nil, err
+		}
+		place.Longitude, place.Latitude = convertCoordinate(place.Longitude, place.Latitude, coordSystem, storageCoordSystem)
+		places = append(places, place)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	stations, err := loadStationsWithAliases()
+	if err != nil {
+		return nil, nil, err
+	}
+	return places, stations, nil
+}
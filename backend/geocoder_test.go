@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewGeocoder(t *testing.T) {
+	if _, err := NewGeocoder(GeocoderConfig{Provider: "google"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+	for _, name := range []string{"baidu", "amap", "tianditu"} {
+		if _, err := NewGeocoder(GeocoderConfig{Provider: name}); !errors.Is(err, errGeocoderNotConfigured) {
+			t.Errorf("NewGeocoder(%s) without key: got %v, want errGeocoderNotConfigured", name, err)
+		}
+		g, err := NewGeocoder(GeocoderConfig{Provider: name, APIKey: "key"})
+		if err != nil || g.Name() != name {
+			t.Errorf("NewGeocoder(%s) = %v, %v", name, g, err)
+		}
+	}
+	if g, err := NewGeocoder(GeocoderConfig{Provider: "Offline"}); err != nil || g.Name() != "offline" {
+		t.Errorf("NewGeocoder(offline) = %v, %v", g, err)
+	}
+}
+
+func TestLoadGeocoderConfigs(t *testing.T) {
+	t.Setenv("GEOCODE_PROVIDERS", " amap, baidu ,,offline")
+	t.Setenv("AMAP_KEY", "amap-key")
+	t.Setenv("BAIDU_MAP_AK", "baidu-ak")
+	t.Setenv("BAIDU_GEOCODE_URL", "http://127.0.0.1/baidu")
+	t.Setenv("BAIDU_DAILY_QUOTA", "5000")
+
+	configs := loadGeocoderConfigs()
+	want := []GeocoderConfig{
+		{Provider: "amap", APIKey: "amap-key"},
+		{Provider: "baidu", APIKey: "baidu-ak", BaseURL: "http://127.0.0.1/baidu", DailyQuota: 5000},
+		{Provider: "offline"},
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("loadGeocoderConfigs() = %+v, want %+v", configs, want)
+	}
+	for i := range want {
+		if configs[i] != want[i] {
+			t.Errorf("config %d = %+v, want %+v", i, configs[i], want[i])
+		}
+	}
+}
+
+func TestProviderGeocoders(t *testing.T) {
+	tests := []struct {
+		provider  string
+		response  string
+		wantParam string
+		wantLon   float64
+		wantCRS   string
+	}{
+		{"baidu", `{"status":0,"result":{"location":{"lng":120.36549,"lat":31.61736},"precise":0,"confidence":50,"comprehension":100,"level":"乡镇"}}`,
+			"ak", 120.36549, CoordBD09},
+		{"amap", `{"status":"1","info":"OK","infocode":"10000","count":"1","geocodes":[{"formatted_address":"江苏省无锡市锡山区羊尖镇","location":"120.35908,31.61100","level":"乡镇"}]}`,
+			"key", 120.35908, CoordGCJ02},
+		{"tianditu", `{"msg":"ok","location":{"score":100,"level":"乡镇","lon":120.3544,"lat":31.6128,"keyWord":"羊尖镇"},"status":"0"}`,
+			"tk", 120.3544, CoordWGS84},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get(tt.wantParam); got != "secret" {
+					t.Errorf("%s = %q, want secret", tt.wantParam, got)
+				}
+				if tt.provider == "tianditu" {
+					var ds map[string]string
+					if err := json.Unmarshal([]byte(r.URL.Query().Get("ds")), &ds); err != nil || ds["keyWord"] != "江苏省无锡市锡山区羊尖镇" {
+						t.Errorf("unexpected ds %q", r.URL.Query().Get("ds"))
+					}
+				} else if got := r.URL.Query().Get("address"); got != "江苏省无锡市锡山区羊尖镇" {
+					t.Errorf("address = %q", got)
+				}
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			g, err := NewGeocoder(GeocoderConfig{Provider: tt.provider, APIKey: "secret", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("NewGeocoder returned error: %v", err)
+			}
+			result, err := g.Geocode(context.Background(), "江苏省无锡市锡山区羊尖镇")
+			if err != nil {
+				t.Fatalf("Geocode returned error: %v", err)
+			}
+			if result.Provider != tt.provider || result.Longitude != tt.wantLon || result.CoordSystem != tt.wantCRS || result.Level != "乡镇" {
+				t.Errorf("unexpected result: %+v", result)
+			}
+			// 只有百度返回精度信息
+			if (result.Confidence != nil) != (tt.provider == "baidu") {
+				t.Errorf("unexpected confidence: %v", result.Confidence)
+			}
+			if tt.provider == "baidu" && (*result.Confidence != 50 || *result.Comprehension != 100 || *result.Precise) {
+				t.Errorf("unexpected baidu quality: %v %v %v", *result.Precise, *result.Confidence, *result.Comprehension)
+			}
+		})
+	}
+}
+
+func TestProviderGeocoderErrors(t *testing.T) {
+	tests := []struct {
+		provider string
+		response string
+		status   string
+	}{
+		{"baidu", `{"status":302,"message":"天配额超限，限制访问"}`, "302"},
+		{"amap", `{"status":"0","info":"DAILY_QUERY_OVER_LIMIT","infocode":"10003"}`, "10003"},
+		{"tianditu", `{"msg":"无结果","status":"101"}`, "101"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			g, _ := NewGeocoder(GeocoderConfig{Provider: tt.provider, APIKey: "secret", BaseURL: server.URL})
+			_, err := g.Geocode(context.Background(), "羊尖镇")
+			var geocodeErr *GeocodeError
+			if !errors.As(err, &geocodeErr) || geocodeErr.Status != tt.status || geocodeErr.Provider != tt.provider {
+				t.Errorf("Geocode error = %v, want GeocodeError with status %s", err, tt.status)
+			}
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"1","info":"OK","infocode":"10000","count":"0","geocodes":[]}`))
+	}))
+	defer server.Close()
+	g, _ := NewGeocoder(GeocoderConfig{Provider: "amap", APIKey: "secret", BaseURL: server.URL})
+	if _, err := g.Geocode(context.Background(), "不存在的地方"); !errors.Is(err, errGeocodeNotFound) {
+		t.Errorf("expected errGeocodeNotFound, got %v", err)
+	}
+}
+
+// stubGeocoder 返回预设结果并记录调用次数
+type stubGeocoder struct {
+	name    string
+	result  *GeocodeResult
+	reverse *ReverseGeocodeResult
+	err     error
+	delay   time.Duration
+	calls   int
+}
+
+func (g *stubGeocoder) Name() string { return g.name }
+
+func (g *stubGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	g.calls++
+	if g.delay > 0 {
+		select {
+		case <-time.After(g.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return g.result, g.err
+}
+
+func (g *stubGeocoder) ReverseGeocode(ctx context.Context, lon, lat float64) (*ReverseGeocodeResult, error) {
+	g.calls++
+	if g.reverse == nil && g.err == nil {
+		return nil, errReverseGeocodeUnsupported
+	}
+	return g.reverse, g.err
+}
+
+func TestFallbackGeocoder(t *testing.T) {
+	quota := &stubGeocoder{name: "baidu", err: &GeocodeError{Provider: "baidu", Status: "302"}}
+	slow := &stubGeocoder{name: "amap", delay: time.Second}
+	offline := &stubGeocoder{name: "offline", result: &GeocodeResult{Provider: "offline", Longitude: 120.3544, Latitude: 31.6128}}
+
+	f := &fallbackGeocoder{geocoders: []Geocoder{quota, slow, offline}, timeout: 20 * time.Millisecond}
+	if f.Name() != "baidu,amap,offline" {
+		t.Errorf("Name() = %q", f.Name())
+	}
+	result, err := f.Geocode(context.Background(), "羊尖镇")
+	if err != nil || result.Provider != "offline" {
+		t.Fatalf("Geocode = %+v, %v; want offline result", result, err)
+	}
+	if quota.calls != 1 || slow.calls != 1 || offline.calls != 1 {
+		t.Errorf("unexpected calls: %d %d %d", quota.calls, slow.calls, offline.calls)
+	}
+
+	// 全部失败时保留各服务商的错误
+	f = &fallbackGeocoder{geocoders: []Geocoder{quota, &stubGeocoder{name: "offline", err: errGeocodeNotFound}}}
+	_, err = f.Geocode(context.Background(), "羊尖镇")
+	var geocodeErr *GeocodeError
+	if !errors.As(err, &geocodeErr) || !errors.Is(err, errGeocodeNotFound) {
+		t.Errorf("expected joined errors, got %v", err)
+	}
+
+	// 客户端取消时不再尝试后续服务商
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	next := &stubGeocoder{name: "offline", result: offline.result}
+	f = &fallbackGeocoder{geocoders: []Geocoder{&stubGeocoder{name: "amap", delay: time.Second}, next}}
+	if _, err := f.Geocode(ctx, "羊尖镇"); !errors.Is(err, context.Canceled) || next.calls != 0 {
+		t.Errorf("expected cancellation without fallback, got %v (calls %d)", err, next.calls)
+	}
+}
+
+func TestOfflineGeocoder(t *testing.T) {
+	g := &OfflineGeocoder{load: func() ([]KnownPlace, []StationWithAliases, error) {
+		return []KnownPlace{
+			{Name: "羊尖", Longitude: 120.40, Latitude: 31.60},
+			{Name: "羊尖镇中心小学", Longitude: 120.41, Latitude: 31.61},
+		}, []StationWithAliases{
+			{Station: Station{ID: "M2877", Name: "西石桥", Longitude: 120.0817, Latitude: 31.8866}},
+			{Station: Station{ID: "58354", Name: "无锡本站", Longitude: 120.3544, Latitude: 31.6128}},
+		}, nil
+	}}
+
+	tests := []struct {
+		address string
+		wantLon float64
+		level   string
+	}{
+		{"江苏省无锡市锡山区羊尖镇中心小学门口", 120.41, "地名"},
+		{"江苏省无锡市锡山区羊尖镇", 120.40, "地名"},
+		{"江阴市西石桥", 120.0817, "站点"},
+	}
+	for _, tt := range tests {
+		result, err := g.Geocode(context.Background(), tt.address)
+		if err != nil {
+			t.Errorf("Geocode(%q) returned error: %v", tt.address, err)
+			continue
+		}
+		if result.Longitude != tt.wantLon || result.Level != tt.level || result.CoordSystem != CoordWGS84 {
+			t.Errorf("Geocode(%q) = %+v", tt.address, result)
+		}
+	}
+
+	if _, err := g.Geocode(context.Background(), "南京市鼓楼区"); !errors.Is(err, errGeocodeNotFound) {
+		t.Errorf("expected errGeocodeNotFound, got %v", err)
+	}
+}
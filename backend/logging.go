@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 日志输出格式，由 LOG_FORMAT 选择
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// requestIDHeader 为请求 ID 的请求头与响应头
+const requestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 64
+
+type requestIDKey struct{}
+
+// withRequestID 返回带请求 ID 的 context
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestIDFrom 取出 context 中的请求 ID，没有时返回空字符串
+func requestIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler 为每条日志加上 context 中的 request_id
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestIDFrom(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// newLogHandler 按 format 创建 JSON 或文本日志处理器。字符串与错误在编码前脱敏（JSON 会转义 URL 中的 &），
+// latency 等时长统一输出为 "1.5s" 形式
+func newLogHandler(w io.Writer, format string, level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Value.Kind() {
+			case slog.KindDuration:
+				return slog.String(a.Key, a.Value.Duration().String())
+			case slog.KindString:
+				return slog.String(a.Key, logRedactor.Redact(a.Value.String()))
+			case slog.KindAny:
+				if err, ok := a.Value.Any().(error); ok {
+					return slog.String(a.Key, logRedactor.Redact(err.Error()))
+				}
+			}
+			return a
+		},
+	}
+	if format == logFormatJSON {
+		return contextHandler{slog.NewJSONHandler(w, opts)}
+	}
+	return contextHandler{slog.NewTextHandler(w, opts)}
+}
+
+// parseLogLevel 解析 LOG_LEVEL（debug、info、warn、error），无效时为 info
+func parseLogLevel(value string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// initLogger 按 LOG_FORMAT、LOG_LEVEL 设置默认 slog 日志，输出经过密钥脱敏；标准库 log 的输出同样转到 slog
+func initLogger() {
+	logRedactor.RegisterEnv(os.Environ())
+	format := strings.ToLower(getEnv("LOG_FORMAT", logFormatText))
+	level := parseLogLevel(getEnv("LOG_LEVEL", "info"))
+	slog.SetDefault(slog.New(newLogHandler(newRedactingWriter(os.Stderr, logRedactor), format, level)))
+}
+
+// fatal 记录错误并退出
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// validRequestID 只接受客户端传入的短小、可打印的请求 ID，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder 记录处理器写出的状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// requestIDMiddleware 为每个请求分配请求 ID（沿用客户端传入的 X-Request-ID），写入响应头与 context，
+// 请求结束时记录方法、路径、状态码与耗时；/api 以外的静态文件与图片请求只在 debug 级别记录
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := withRequestID(r.Context(), id)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case rec.status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case !strings.HasPrefix(r.URL.Path, "/api/"):
+			level = slog.LevelDebug
+		}
+		slog.Log(ctx, level, "request completed", "method", r.Method, "path", r.URL.Path,
+			"status", rec.status, "latency", time.Since(start))
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogHandlerFormats(t *testing.T) {
+	previous := logRedactor
+	logRedactor = &secretRedactor{}
+	logRedactor.Register("amap-secret-key")
+	defer func() { logRedactor = previous }()
+
+	ctx := withRequestID(context.Background(), "req-1")
+	apiURL := "https://restapi.amap.com/v3/geocode/geo?address=无锡&key=amap-secret-key&output=json"
+
+	var buf bytes.Buffer
+	logger := slog.New(newLogHandler(&buf, logFormatJSON, slog.LevelInfo))
+	logger.InfoContext(ctx, "Address geocoded", "provider", "amap", "image_id", 7, "url", apiURL,
+		"latency", 1500*time.Millisecond, "error", errors.New("Get \""+apiURL+"\": connection refused"))
+	logger.Debug("hidden below info")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry["request_id"] != "req-1" || entry["provider"] != "amap" || entry["image_id"] != float64(7) || entry["latency"] != "1.5s" {
+		t.Errorf("unexpected JSON entry: %v", entry)
+	}
+	if strings.Contains(buf.String(), "amap-secret-key") || !strings.Contains(entry["url"].(string), "key=***") {
+		t.Errorf("JSON log leaks the key: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger = slog.New(newLogHandler(&buf, logFormatText, slog.LevelInfo))
+	logger.InfoContext(ctx, "Annotation saved", "annotation_id", 3, "image_id", 7)
+	if line := buf.String(); !strings.Contains(line, "msg=\"Annotation saved\"") || !strings.Contains(line, "annotation_id=3") ||
+		!strings.Contains(line, "request_id=req-1") {
+		t.Errorf("unexpected text entry: %q", line)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]slog.Level{"debug": slog.LevelDebug, "WARN": slog.LevelWarn, "error": slog.LevelError, "verbose": slog.LevelInfo, "": slog.LevelInfo}
+	for input, want := range tests {
+		if got := parseLogLevel(input); got != want {
+			t.Errorf("parseLogLevel(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFrom(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"propagated", "upstream-42", true},
+		{"rejected", "bad id\nwith newline", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/annotations/3", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(requestIDHeader)
+			if !validRequestID(id) || id != seen {
+				t.Errorf("response header %q does not match context request ID %q", id, seen)
+			}
+			if (id == tt.incoming) != tt.keep {
+				t.Errorf("request ID = %q for incoming %q", id, tt.incoming)
+			}
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d", rec.Code)
+			}
+		})
+	}
+}
@@ -1,18 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"math"
 	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -22,6 +25,9 @@ import (
 
 var db *sql.DB
 
+// shutdownTimeout 为收到退出信号后等待进行中请求完成的时长
+const shutdownTimeout = 10 * time.Second
+
 func init() {
 	_ = godotenv.Load()
 }
@@ -38,7 +44,17 @@ func getEnvInt(key string, fallback int) int {
 		if n, err := strconv.Atoi(val); err == nil {
 			return n
 		}
-		log.Printf("Invalid value for %s, using default %d", key, fallback)
+		slog.Warn("Invalid integer setting, using default", "key", key, "default", fallback)
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if val := strings.TrimSpace(os.Getenv(key)); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+		slog.Warn("Invalid number setting, using default", "key", key, "default", fallback)
 	}
 	return fallback
 }
@@ -75,12 +91,22 @@ func getStaticDir() string {
 	return getEnv("STATIC_DIR", "../frontend/dist")
 }
 
+func getGeocodeTimeout() time.Duration {
+	return time.Duration(getEnvInt("GEOCODE_TIMEOUT_SECONDS", 10)) * time.Second
+}
+
 // Models
 type Station struct {
 	ID        string  `json:"id"`
 	Name      string  `json:"name"`
 	Longitude float64 `json:"longitude"`
 	Latitude  float64 `json:"latitude"`
+	Type      string  `json:"type,omitempty"`
+	// 启用与撤销日期（YYYY-MM-DD，含当天），为空表示不限
+	ActiveFrom string `json:"active_from,omitempty"`
+	ActiveTo   string `json:"active_to,omitempty"`
+	// 经纬度所属坐标系，读取后统一为 storageCoordSystem；写入时为请求体中坐标的坐标系，默认 WGS-84
+	CoordSystem string `json:"coord_system,omitempty"`
 }
 
 type Image struct {
@@ -90,8 +116,87 @@ type Image struct {
 	UploadedAt  time.Time `json:"uploaded_at"`
 	Annotated   bool      `json:"annotated"`
 	IsStandard  *bool     `json:"is_standard,omitempty"`
+	OCRStatus   string    `json:"ocr_status,omitempty"`
 	OCRTime     string    `json:"ocr_time,omitempty"`
 	OCRLocation string    `json:"ocr_location,omitempty"`
+	// OCR 最近一次识别的模型置信度
+	OCRConfidence *float64 `json:"ocr_confidence,omitempty"`
+	// ocr_time 解析后的时间，无法解析时为空
+	OCRDateTime *time.Time `json:"ocr_datetime,omitempty"`
+	// 上传时从 EXIF 读取的拍摄信息
+	EXIFTime      *time.Time `json:"exif_time,omitempty"`
+	EXIFLatitude  *float64   `json:"exif_latitude,omitempty"`
+	EXIFLongitude *float64   `json:"exif_longitude,omitempty"`
+	CameraMake    string     `json:"camera_make,omitempty"`
+	CameraModel   string     `json:"camera_model,omitempty"`
+	Orientation   int        `json:"orientation,omitempty"`
+	// ocr_location 按行政区划拆分的结果，未匹配时为空
+	OCRLocationParts *ParsedLocation `json:"ocr_location_parts,omitempty"`
+	// ocr_time 与 EXIF、上传时间的一致性检查结果，见 checkImageTime
+	TimeFlags []string `json:"time_flags,omitempty"`
+}
+
+// imageColumns 为查询 images 表时的公共列，顺序与 scanImage 一致
+const imageColumns = `i.id, i.filename, i.filepath, i.uploaded_at, i.annotated,
+		i.is_standard, i.ocr_status, i.ocr_time, i.ocr_datetime, i.ocr_location, i.ocr_confidence,
+		i.exif_time, i.exif_latitude, i.exif_longitude, i.camera_make, i.camera_model, i.orientation,
+		i.location_province, i.location_city, i.location_county, i.location_town, i.location_detail`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanImage 读取一行 imageColumns
+func scanImage(row rowScanner) (Image, error) {
+	var img Image
+	var isStandard sql.NullBool
+	var ocrStatus, ocrTime, ocrLocation, cameraMake, cameraModel sql.NullString
+	var ocrConfidence, exifLat, exifLon sql.NullFloat64
+	var ocrDateTime, exifTime sql.NullTime
+	var orientation sql.NullInt64
+	var province, city, county, town, detail sql.NullString
+	if err := row.Scan(&img.ID, &img.Filename, &img.Filepath, &img.UploadedAt, &img.Annotated,
+		&isStandard, &ocrStatus, &ocrTime, &ocrDateTime, &ocrLocation, &ocrConfidence,
+		&exifTime, &exifLat, &exifLon, &cameraMake, &cameraModel, &orientation,
+		&province, &city, &county, &town, &detail); err != nil {
+		return img, err
+	}
+
+	if isStandard.Valid {
+		img.IsStandard = &isStandard.Bool
+	}
+	img.OCRStatus = ocrStatus.String
+	img.OCRTime = ocrTime.String
+	if ocrDateTime.Valid {
+		img.OCRDateTime = &ocrDateTime.Time
+	}
+	img.OCRLocation = ocrLocation.String
+	if ocrConfidence.Valid {
+		img.OCRConfidence = &ocrConfidence.Float64
+	}
+	if exifTime.Valid {
+		img.EXIFTime = &exifTime.Time
+	}
+	if exifLat.Valid && exifLon.Valid {
+		img.EXIFLatitude = &exifLat.Float64
+		img.EXIFLongitude = &exifLon.Float64
+	}
+	img.CameraMake = cameraMake.String
+	img.CameraModel = cameraModel.String
+	img.Orientation = int(orientation.Int64)
+	if province.Valid || county.Valid || town.Valid {
+		img.OCRLocationParts = &ParsedLocation{
+			Province: province.String,
+			City:     city.String,
+			County:   county.String,
+			Town:     town.String,
+			Detail:   detail.String,
+			Matched:  true,
+		}
+		img.OCRLocationParts.Normalized = province.String + city.String + county.String + town.String + detail.String
+	}
+	img.TimeFlags = checkImageTime(img.OCRTime, img.OCRDateTime, img.EXIFTime, img.UploadedAt, timeCheckConfig)
+	return img, nil
 }
 
 type Annotation struct {
@@ -106,11 +211,18 @@ type Annotation struct {
 	StationID       string    `json:"station_id"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
+	// 经纬度所属坐标系，提交时默认 WGS-84，保存前转换为 storageCoordSystem
+	CoordSystem string `json:"coord_system,omitempty"`
+	// 坐标来自地理编码时的结果质量，手动输入坐标时为空
+	GeocodeQuality *GeocodeQuality `json:"geocode_quality,omitempty"`
 }
 
 type ImageWithAnnotation struct {
-	Image      Image       `json:"image"`
-	Annotation *Annotation `json:"annotation,omitempty"`
+	Image      Image        `json:"image"`
+	Annotation *Annotation  `json:"annotation,omitempty"`
+	OCRResults []OCRAttempt `json:"ocr_results,omitempty"`
+	// 批量地理编码生成的坐标与站点建议，见 GeocodeBatchRunner
+	GeocodeSuggestion *GeocodeSuggestion `json:"geocode_suggestion,omitempty"`
 }
 
 // Initialize database connection
@@ -130,7 +242,7 @@ func initDB() error {
 	db.SetMaxOpenConns(getEnvInt("DB_MAX_OPEN_CONNS", 10))
 	db.SetMaxIdleConns(getEnvInt("DB_MAX_IDLE_CONNS", 5))
 
-	log.Println("Database connected successfully")
+	slog.Info("Database connected")
 	return nil
 }
 
@@ -142,6 +254,14 @@ func nullString(s string) interface{} {
 	return s
 }
 
+// nullInt 将 0 转换为NULL值
+func nullInt(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
 // Calculate distance between two points using Haversine formula
 func haversineDistance(lon1, lat1, lon2, lat2 float64) float64 {
 	const earthRadius = 6371 // km
@@ -159,31 +279,13 @@ func haversineDistance(lon1, lat1, lon2, lat2 float64) float64 {
 	return earthRadius * c
 }
 
-// Find nearest station to given coordinates
-func findNearestStation(lon, lat float64) (*Station, error) {
-	rows, err := db.Query("SELECT id, name, longitude, latitude FROM stations")
-	if err != nil {
+// Find nearest station to given coordinates that was active at the given time
+func findNearestStation(lon, lat float64, at time.Time) (*Station, error) {
+	nearby, err := findNearestStations(lon, lat, 1, 0, at)
+	if err != nil || len(nearby) == 0 {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var nearestStation *Station
-	minDistance := math.MaxFloat64
-
-	for rows.Next() {
-		var station Station
-		if err := rows.Scan(&station.ID, &station.Name, &station.Longitude, &station.Latitude); err != nil {
-			continue
-		}
-
-		distance := haversineDistance(lon, lat, station.Longitude, station.Latitude)
-		if distance < minDistance {
-			minDistance = distance
-			nearestStation = &station
-		}
-	}
-
-	return nearestStation, nil
+	return &nearby[0].Station, nil
 }
 
 // API Handlers
@@ -191,7 +293,8 @@ func enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+requestIDHeader)
+		w.Header().Set("Access-Control-Expose-Headers", requestIDHeader)
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -203,7 +306,13 @@ func enableCORS(next http.Handler) http.Handler {
 }
 
 func getStations(w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query("SELECT id, name, longitude, latitude FROM stations ORDER BY name")
+	coordSystem, err := coordSystemParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query("SELECT " + stationColumns + " FROM stations ORDER BY name")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -212,11 +321,11 @@ func getStations(w http.ResponseWriter, r *http.Request) {
 
 	stations := []Station{}
 	for rows.Next() {
-		var station Station
-		if err := rows.Scan(&station.ID, &station.Name, &station.Longitude, &station.Latitude); err != nil {
+		station, err := scanStation(rows)
+		if err != nil {
 			continue
 		}
-		stations = append(stations, station)
+		stations = append(stations, station.inCoordSystem(coordSystem))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -238,47 +347,116 @@ func getNearestStation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid latitude", http.StatusBadRequest)
 		return
 	}
+	if !validCoordinate(lon, lat) {
+		http.Error(w, "Coordinates out of range", http.StatusBadRequest)
+		return
+	}
+
+	// coord_system 同时指定查询坐标与返回站点坐标的坐标系，默认 WGS-84
+	coordSystem, err := coordSystemParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lon, lat = convertCoordinate(lon, lat, coordSystem, storageCoordSystem)
+
+	// at 为观测时间，只推荐当时在运行的站点及其当时的位置，默认当前时间
+	var at time.Time
+	if atStr := r.URL.Query().Get("at"); atStr != "" {
+		if at, err = parseFilterTime(atStr); err != nil {
+			http.Error(w, "Invalid at", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// 未指定 k 与 max_km 时保持原有行为，只返回最近的一个站点
+	kStr := r.URL.Query().Get("k")
+	maxKMStr := r.URL.Query().Get("max_km")
+	if kStr == "" && maxKMStr == "" {
+		station, err := findNearestStation(lon, lat, at)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if station != nil {
+			*station = station.inCoordSystem(coordSystem)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(station)
+		return
+	}
+
+	k := 5
+	if kStr != "" {
+		k, err = strconv.Atoi(kStr)
+		if err != nil || k <= 0 || k > 100 {
+			http.Error(w, "Invalid k (1-100)", http.StatusBadRequest)
+			return
+		}
+	}
+	maxKM := 0.0
+	if maxKMStr != "" {
+		maxKM, err = strconv.ParseFloat(maxKMStr, 64)
+		if err != nil || maxKM <= 0 || !isFinite(maxKM) {
+			http.Error(w, "Invalid max_km", http.StatusBadRequest)
+			return
+		}
+	}
 
-	station, err := findNearestStation(lon, lat)
+	nearby, err := findNearestStations(lon, lat, k, maxKM, at)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	for i := range nearby {
+		nearby[i].Station = nearby[i].Station.inCoordSystem(coordSystem)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(station)
+	json.NewEncoder(w).Encode(nearby)
 }
 
 func getImages(w http.ResponseWriter, r *http.Request) {
+	where := ""
+	args := []interface{}{}
+	if status := r.URL.Query().Get("ocr_status"); status != "" {
+		if !isValidOCRStatus(status) {
+			http.Error(w, "Invalid ocr_status", http.StatusBadRequest)
+			return
+		}
+		where = "WHERE i.ocr_status = ?"
+		args = append(args, status)
+	}
+
+	orderBy := "i.annotated ASC, i.uploaded_at DESC"
+	if r.URL.Query().Get("sort") == "confidence" {
+		// 低置信度优先，便于审核
+		orderBy = "i.annotated ASC, i.ocr_confidence IS NULL, i.ocr_confidence ASC, i.uploaded_at DESC"
+	}
+
 	rows, err := db.Query(`
-		SELECT i.id, i.filename, i.filepath, i.uploaded_at, i.annotated,
-		       i.is_standard, i.ocr_time, i.ocr_location
+		SELECT `+imageColumns+`
 		FROM images i
-		ORDER BY i.annotated ASC, i.uploaded_at DESC
-	`)
+		`+where+`
+		ORDER BY `+orderBy, args...)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
+	// 时间标记在读取时计算，因此在内存中筛选
+	suspiciousOnly := r.URL.Query().Get("suspicious_time") == "true"
+
 	images := []Image{}
 	for rows.Next() {
-		var img Image
-		var isStandard sql.NullBool
-		var ocrTime, ocrLocation sql.NullString
-		if err := rows.Scan(&img.ID, &img.Filename, &img.Filepath, &img.UploadedAt, &img.Annotated,
-			&isStandard, &ocrTime, &ocrLocation); err != nil {
+		img, err := scanImage(rows)
+		if err != nil {
 			continue
 		}
-		if isStandard.Valid {
-			img.IsStandard = &isStandard.Bool
-		}
-		if ocrTime.Valid {
-			img.OCRTime = ocrTime.String
-		}
-		if ocrLocation.Valid {
-			img.OCRLocation = ocrLocation.String
+		if suspiciousOnly && len(img.TimeFlags) == 0 {
+			continue
 		}
 		images = append(images, img)
 	}
@@ -290,54 +468,66 @@ func getImages(w http.ResponseWriter, r *http.Request) {
 func getImage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
+	coordSystem, err := coordSystemParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	var img Image
-	var isStandard sql.NullBool
-	var ocrTime, ocrLocation sql.NullString
-	err := db.QueryRow(`
-		SELECT id, filename, filepath, uploaded_at, annotated,
-		       is_standard, ocr_time, ocr_location
-		FROM images
-		WHERE id = ?
-	`, id).Scan(&img.ID, &img.Filename, &img.Filepath, &img.UploadedAt, &img.Annotated,
-		&isStandard, &ocrTime, &ocrLocation)
+	img, err := scanImage(db.QueryRow(`
+		SELECT `+imageColumns+`
+		FROM images i
+		WHERE i.id = ?
+	`, id))
 
 	if err != nil {
 		http.Error(w, "Image not found", http.StatusNotFound)
 		return
 	}
 
-	if isStandard.Valid {
-		img.IsStandard = &isStandard.Bool
-	}
-	if ocrTime.Valid {
-		img.OCRTime = ocrTime.String
-	}
-	if ocrLocation.Valid {
-		img.OCRLocation = ocrLocation.String
-	}
-
 	// Get annotation if exists
 	var annotation Annotation
+	var quality geocodeQualityColumns
 	err = db.QueryRow(`
 		SELECT id, image_id, category, severity, observation_time, location, 
-		       longitude, latitude, station_id, created_at, updated_at
+		       longitude, latitude, station_id, created_at, updated_at, coord_system,
+		       geocode_provider, geocode_level, geocode_precise, geocode_confidence,
+		       geocode_comprehension, geocode_warnings
 		FROM annotations
 		WHERE image_id = ?
-	`, img.ID).Scan(
+	`, img.ID).Scan(append([]interface{}{
 		&annotation.ID, &annotation.ImageID, &annotation.Category, &annotation.Severity,
 		&annotation.ObservationTime, &annotation.Location, &annotation.Longitude,
 		&annotation.Latitude, &annotation.StationID, &annotation.CreatedAt, &annotation.UpdatedAt,
-	)
+		&annotation.CoordSystem,
+	}, quality.dest()...)...)
 
 	response := ImageWithAnnotation{
 		Image: img,
 	}
 
 	if err == nil {
+		annotation.Longitude, annotation.Latitude = convertCoordinate(annotation.Longitude, annotation.Latitude,
+			annotation.CoordSystem, coordSystem)
+		annotation.CoordSystem = coordSystem
+		annotation.GeocodeQuality = quality.quality()
 		response.Annotation = &annotation
 	}
 
+	// Get OCR attempts (confidence, notes, model, raw response)
+	if attempts, err := loadOCRAttempts(img.ID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load OCR results", "image_id", img.ID, "error", err)
+	} else {
+		response.OCRResults = attempts
+	}
+
+	if suggestion, err := loadGeocodeSuggestion(img.ID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load geocode suggestion", "image_id", img.ID, "error", err)
+	} else if suggestion != nil {
+		converted := suggestion.inCoordSystem(coordSystem)
+		response.GeocodeSuggestion = &converted
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -349,18 +539,62 @@ func createAnnotation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	coordSystem, err := parseCoordSystem(annotation.CoordSystem)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	annotation.Longitude, annotation.Latitude = convertCoordinate(annotation.Longitude, annotation.Latitude,
+		coordSystem, storageCoordSystem)
+	annotation.CoordSystem = storageCoordSystem
+
+	// 质量信息以服务端的地理编码记录为准，找不到一致的记录时不保存
+	if err := validateGeocodeQuality(annotation.GeocodeQuality); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	quality, err := resolveGeocodeQuality(r.Context(), annotation, serverGeocodeQualitySources())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if annotation.GeocodeQuality != nil && quality == nil {
+		slog.WarnContext(r.Context(), "Discarding unverified geocode quality", "image_id", annotation.ImageID,
+			"provider", annotation.GeocodeQuality.Provider)
+	}
+	annotation.GeocodeQuality = quality
+
+	// 所选站点须在观测时间处于运行期内
+	if annotation.StationID != "" {
+		station, err := loadStationByID(annotation.StationID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Station not found", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !annotation.ObservationTime.IsZero() && !stationActiveAt(station.Station, annotation.ObservationTime) {
+			http.Error(w, fmt.Sprintf("Station %s was not active at the observation time", station.ID), http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Check if annotation already exists for this image
 	var existingID int
-	err := db.QueryRow("SELECT id FROM annotations WHERE image_id = ?", annotation.ImageID).Scan(&existingID)
+	err = db.QueryRow("SELECT id FROM annotations WHERE image_id = ?", annotation.ImageID).Scan(&existingID)
 
 	if err == sql.ErrNoRows {
 		// Create new annotation
 		result, err := db.Exec(`
 			INSERT INTO annotations (image_id, category, severity, observation_time, location, 
-			                        longitude, latitude, station_id)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-		`, annotation.ImageID, annotation.Category, annotation.Severity, annotation.ObservationTime,
-			annotation.Location, annotation.Longitude, annotation.Latitude, annotation.StationID)
+			                        longitude, latitude, station_id, coord_system,
+			                        geocode_provider, geocode_level, geocode_precise, geocode_confidence,
+			                        geocode_comprehension, geocode_warnings)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, append([]interface{}{annotation.ImageID, annotation.Category, annotation.Severity, annotation.ObservationTime,
+			annotation.Location, annotation.Longitude, annotation.Latitude, annotation.StationID,
+			annotation.CoordSystem}, geocodeQualityValues(annotation.GeocodeQuality)...)...)
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -371,14 +605,17 @@ func createAnnotation(w http.ResponseWriter, r *http.Request) {
 		annotation.ID = int(id)
 	} else {
 		// Update existing annotation
+		args := append([]interface{}{annotation.Category, annotation.Severity, annotation.ObservationTime,
+			annotation.Location, annotation.Longitude, annotation.Latitude,
+			annotation.StationID, annotation.CoordSystem}, geocodeQualityValues(annotation.GeocodeQuality)...)
 		_, err := db.Exec(`
 			UPDATE annotations 
 			SET category = ?, severity = ?, observation_time = ?, location = ?, 
-			    longitude = ?, latitude = ?, station_id = ?
+			    longitude = ?, latitude = ?, station_id = ?, coord_system = ?,
+			    geocode_provider = ?, geocode_level = ?, geocode_precise = ?, geocode_confidence = ?,
+			    geocode_comprehension = ?, geocode_warnings = ?
 			WHERE image_id = ?
-		`, annotation.Category, annotation.Severity, annotation.ObservationTime,
-			annotation.Location, annotation.Longitude, annotation.Latitude,
-			annotation.StationID, annotation.ImageID)
+		`, append(args, annotation.ImageID)...)
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -390,8 +627,10 @@ func createAnnotation(w http.ResponseWriter, r *http.Request) {
 	// Mark image as annotated
 	_, err = db.Exec("UPDATE images SET annotated = TRUE WHERE id = ?", annotation.ImageID)
 	if err != nil {
-		log.Printf("Error updating image annotated status: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to mark image annotated", "image_id", annotation.ImageID, "error", err)
 	}
+	slog.InfoContext(r.Context(), "Annotation saved", "annotation_id", annotation.ID, "image_id", annotation.ImageID,
+		"station_id", annotation.StationID, "category", annotation.Category)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -425,8 +664,9 @@ func deleteAnnotation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if _, err := db.Exec("UPDATE images SET annotated = FALSE WHERE id = ?", imageID); err != nil {
-		log.Printf("Error resetting image annotated status: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to reset image annotated status", "image_id", imageID, "error", err)
 	}
+	slog.InfoContext(r.Context(), "Annotation deleted", "annotation_id", annotationID, "image_id", imageID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -483,6 +723,7 @@ func deleteImage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	slog.InfoContext(r.Context(), "Image deleted", "image_id", imageID, "filename", filename)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -526,35 +767,57 @@ func uploadImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 执行OCR识别
-	ocrResult, err := ProcessImageOCR(filepath)
-	if err != nil {
-		log.Printf("OCR processing failed for %s: %v", filename, err)
-		ocrResult = &OCRResult{IsStandard: false}
+	img := Image{
+		Filename:   filename,
+		Filepath:   filepath,
+		UploadedAt: time.Now(),
+		Annotated:  false,
 	}
 
-	// Save to database with OCR results
+	// 读取 EXIF 拍摄信息；没有 EXIF 的图片照常入库
+	var exifTime interface{}
+	if exif, err := readEXIFFile(filepath); err == nil {
+		img.EXIFTime = exif.CaptureTime
+		img.EXIFLatitude = exif.Latitude
+		img.EXIFLongitude = exif.Longitude
+		img.CameraMake = exif.Make
+		img.CameraModel = exif.Model
+		img.Orientation = exif.Orientation
+		if exif.CaptureTime != nil {
+			exifTime = exif.CaptureTime.In(time.Local)
+		}
+	} else if !errors.Is(err, errNoEXIF) {
+		slog.WarnContext(r.Context(), "Failed to read EXIF", "filename", filename, "error", err)
+	}
+
+	// Save to database; is_standard stays NULL until the OCR job finishes
 	result, err := db.Exec(`
-		INSERT INTO images (filename, filepath, is_standard, ocr_time, ocr_location)
-		VALUES (?, ?, ?, ?, ?)
-	`, filename, filepath, ocrResult.IsStandard,
-		nullString(ocrResult.Time), nullString(ocrResult.Location))
+		INSERT INTO images (filename, filepath, exif_time, exif_latitude, exif_longitude,
+		                    camera_make, camera_model, orientation)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, filename, filepath, exifTime, nullFloat(img.EXIFLatitude), nullFloat(img.EXIFLongitude),
+		nullString(img.CameraMake), nullString(img.CameraModel), nullInt(img.Orientation))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	id, _ := result.LastInsertId()
-
-	img := Image{
-		ID:          int(id),
-		Filename:    filename,
-		Filepath:    filepath,
-		UploadedAt:  time.Now(),
-		Annotated:   false,
-		IsStandard:  &ocrResult.IsStandard,
-		OCRTime:     ocrResult.Time,
-		OCRLocation: ocrResult.Location,
+	img.ID = int(id)
+
+	slog.InfoContext(r.Context(), "Image uploaded", "image_id", img.ID, "filename", filename, "size", header.Size)
+
+	// 异步执行OCR识别；投递失败时撤销上传，否则图片没有任务也不会再被处理
+	if _, err := ocrQueue.Enqueue(int(id)); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to enqueue OCR job", "image_id", img.ID, "error", err)
+		if _, delErr := db.Exec("DELETE FROM images WHERE id = ?", id); delErr != nil {
+			slog.ErrorContext(r.Context(), "Failed to roll back image", "image_id", img.ID, "error", delErr)
+		} else {
+			dst.Close()
+			os.Remove(filepath)
+		}
+		http.Error(w, "Failed to queue OCR job", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -577,32 +840,29 @@ func serveImage(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, filepath)
 }
 
-// Baidu Geocoding API structures
-type BaiduGeocodingResponse struct {
-	Status int `json:"status"`
-	Result struct {
-		Location struct {
-			Lng float64 `json:"lng"`
-			Lat float64 `json:"lat"`
-		} `json:"location"`
-		Precise       int    `json:"precise"`
-		Confidence    int    `json:"confidence"`
-		Comprehension int    `json:"comprehension"`
-		Level         string `json:"level"`
-	} `json:"result"`
-}
-
 type GeocodeRequest struct {
 	Address string `json:"address"`
 }
 
 type GeocodeResponse struct {
-	Longitude float64 `json:"longitude"`
-	Latitude  float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Latitude    float64 `json:"latitude"`
+	CoordSystem string  `json:"coord_system"`
+	Provider    string  `json:"provider"`
+	Cached      bool    `json:"cached"`
+	// 匹配级别、百度精度信息与质量警告，提交标注时原样附带以便审计
+	Quality GeocodeQuality `json:"quality"`
 }
 
 // Geocode address to coordinates
+// 按 GEOCODE_PROVIDERS 顺序调用服务商，结果转换为 coord_system 参数指定的坐标系（默认 WGS-84）
 func geocodeAddress(w http.ResponseWriter, r *http.Request) {
+	coordSystem, err := coordSystemParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var req GeocodeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -614,99 +874,144 @@ func geocodeAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get Baidu Map AK from environment
-	baiduAK := os.Getenv("BAIDU_MAP_AK")
-	if baiduAK == "" {
-		http.Error(w, "Baidu Map AK not configured", http.StatusInternalServerError)
-		return
-	}
-
-	// Clean address: remove special characters that might affect geocoding
-	cleanedAddress := req.Address
-	// Remove common separators and special characters
-	replacer := strings.NewReplacer(
-		"-", "",
-		"_", "",
-		"|", "",
-		"/", "",
-		"\\", "",
-		"(", "",
-		")", "",
-		"[", "",
-		"]", "",
-	)
-	cleanedAddress = replacer.Replace(cleanedAddress)
-	cleanedAddress = strings.TrimSpace(cleanedAddress)
-
-	// Get location prefix from environment and prepend to address
-	locationPrefix := os.Getenv("LOCATION_PREFIX")
-	fullAddress := cleanedAddress
-	if locationPrefix != "" {
-		// Check if address already starts with the prefix
-		if !strings.HasPrefix(cleanedAddress, locationPrefix) {
-			fullAddress = locationPrefix + cleanedAddress
-		}
+	if geocoder == nil {
+		http.Error(w, "Geocoder not configured", http.StatusInternalServerError)
+		return
 	}
 
-	log.Printf("Geocoding - Original: %s, Cleaned: %s, Full: %s", req.Address, cleanedAddress, fullAddress)
+	fullAddress := prepareGeocodeAddress(req.Address)
 
-	// Call Baidu Geocoding API with proper URL encoding
-	apiURL := fmt.Sprintf("https://api.map.baidu.com/geocoding/v3/?address=%s&output=json&ak=%s",
-		url.QueryEscape(fullAddress),
-		baiduAK)
-
-	log.Printf("Geocoding request - Address: %s, URL: %s", fullAddress, apiURL)
-
-	resp, err := http.Get(apiURL)
+	// 浏览器取消请求时中止服务商调用，单个服务商的调用时长由 GEOCODE_TIMEOUT_SECONDS 限制
+	start := time.Now()
+	result, err := geocoder.Geocode(r.Context(), fullAddress)
 	if err != nil {
-		log.Printf("Failed to call Baidu API: %v", err)
-		http.Error(w, "Failed to geocode address", http.StatusInternalServerError)
+		writeGeocodeError(w, r, err, "请检查地址格式是否正确")
 		return
 	}
-	defer resp.Body.Close()
+	slog.InfoContext(r.Context(), "Address geocoded", "address", req.Address, "full_address", fullAddress,
+		"provider", result.Provider, "cached", result.Cached, "latency", time.Since(start))
 
-	body, err := io.ReadAll(resp.Body)
+	// 街道级地址只匹配到区县时按 GEOCODE_COARSE_MATCH_POLICY 警告或拒绝
+	quality, err := assessGeocodeQuality(fullAddress, result, geocodePolicy)
 	if err != nil {
-		log.Printf("Failed to read response: %v", err)
-		http.Error(w, "Failed to read geocoding response", http.StatusInternalServerError)
+		writeGeocodeError(w, r, err, "")
 		return
 	}
 
-	log.Printf("Baidu API response: %s", string(body))
-
-	var baiduResp BaiduGeocodingResponse
-	if err := json.Unmarshal(body, &baiduResp); err != nil {
-		log.Printf("Failed to parse response: %v", err)
-		http.Error(w, "Failed to parse geocoding response", http.StatusInternalServerError)
-		return
-	}
-
-	if baiduResp.Status != 0 {
-		log.Printf("Baidu API error - Status: %d, Address: %s, Response: %s", baiduResp.Status, req.Address, string(body))
-		http.Error(w, fmt.Sprintf("地理编码失败：请检查地址格式是否正确（状态码: %d）", baiduResp.Status), http.StatusBadRequest)
-		return
-	}
+	lng, lat := convertCoordinate(result.Longitude, result.Latitude, result.CoordSystem, coordSystem)
 
 	// Round to 5 decimal places
-	lng := math.Round(baiduResp.Result.Location.Lng*100000) / 100000
-	lat := math.Round(baiduResp.Result.Location.Lat*100000) / 100000
+	lng = math.Round(lng*100000) / 100000
+	lat = math.Round(lat*100000) / 100000
 
 	response := GeocodeResponse{
-		Longitude: lng,
-		Latitude:  lat,
+		Longitude:   lng,
+		Latitude:    lat,
+		CoordSystem: coordSystem,
+		Provider:    result.Provider,
+		Cached:      result.Cached,
+		Quality:     quality,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// geocodeAddressReplacer 去掉可能影响地理编码的分隔符与括号
+var geocodeAddressReplacer = strings.NewReplacer(
+	"-", "",
+	"_", "",
+	"|", "",
+	"/", "",
+	"\\", "",
+	"(", "",
+	")", "",
+	"[", "",
+	"]", "",
+)
+
+// prepareGeocodeAddress 清理地址；匹配到行政区划时使用补全后的完整地址，否则按 LOCATION_PREFIX 补前缀
+func prepareGeocodeAddress(address string) string {
+	cleanedAddress := strings.TrimSpace(geocodeAddressReplacer.Replace(address))
+	if parts := gazetteer.Normalize(cleanedAddress); parts.Matched {
+		return parts.Normalized
+	}
+	// Check if address already starts with the prefix
+	if locationPrefix := os.Getenv("LOCATION_PREFIX"); locationPrefix != "" && !strings.HasPrefix(cleanedAddress, locationPrefix) {
+		return locationPrefix + cleanedAddress
+	}
+	return cleanedAddress
+}
+
+// writeGeocodeError 将地理编码错误转换为 HTTP 状态码与中文提示，hint 为服务商返回错误状态时的提示
+func writeGeocodeError(w http.ResponseWriter, r *http.Request, err error, hint string) {
+	var geocodeErr *GeocodeError
+	switch {
+	case errors.As(err, &geocodeErr):
+		http.Error(w, fmt.Sprintf("地理编码失败：%s（%s 状态码: %s）", hint, geocodeErr.Provider, geocodeErr.Status), http.StatusBadRequest)
+	case errors.Is(err, errGeocodeNotFound):
+		http.Error(w, "地理编码失败：未找到该地址", http.StatusBadRequest)
+	case errors.Is(err, errGeocodeQuotaExceeded):
+		http.Error(w, "地理编码失败：今日请求次数已达配额上限", http.StatusTooManyRequests)
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "Geocoding request timed out", http.StatusGatewayTimeout)
+	case errors.Is(err, errGeocodeTooCoarse):
+		http.Error(w, "地理编码结果仅精确到区县，请补充更详细的地址或手动输入经纬度", http.StatusUnprocessableEntity)
+	case errors.Is(err, errReverseGeocodeUnsupported):
+		http.Error(w, "当前配置的地理编码服务商不支持逆地理编码", http.StatusNotImplemented)
+	default:
+		slog.ErrorContext(r.Context(), "Geocoding failed", "error", err)
+		http.Error(w, "Failed to geocode", http.StatusInternalServerError)
+	}
+}
+
 func main() {
+	// Structured logging (LOG_FORMAT / LOG_LEVEL) with API keys and passwords redacted
+	initLogger()
+
 	// Initialize database
 	if err := initDB(); err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("Failed to connect to database", err)
 	}
 	defer db.Close()
 
+	// Initialize VLM extractor
+	if err := initExtractor(); err != nil {
+		fatal("Failed to initialize VLM extractor", err)
+	}
+
+	// Load OCR confidence thresholds
+	ocrThresholds = loadOCRThresholds()
+	timeCheckConfig = loadTimeCheckConfig()
+	geocodePolicy = loadGeocodePolicy()
+
+	// Load administrative divisions for location normalization
+	if err := initGazetteer(); err != nil {
+		fatal("Failed to load gazetteer", err)
+	}
+
+	// Initialize geocode providers
+	if err := initGeocoder(); err != nil {
+		fatal("Failed to initialize geocoder", err)
+	}
+
+	// SIGINT/SIGTERM 时停止接收请求，并等待 OCR 工作协程退出；中断的任务保持 running，下次启动时重新排队
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Load station spatial index
+	if err := initStationIndex(ctx); err != nil {
+		fatal("Failed to load station index", err)
+	}
+
+	// Start OCR worker pool
+	initOCRQueue(ctx)
+
+	if geocoder != nil {
+		geocodeBatches = NewGeocodeBatchRunner(newMySQLGeocodeBatchStore(db), geocoder.Geocode, findNearestStations,
+			geocodePolicy, getGeocodeBatchInterval())
+	}
+
 	// Create router
 	r := mux.NewRouter()
 
@@ -714,6 +1019,12 @@ func main() {
 	api := r.PathPrefix("/api").Subrouter()
 	api.HandleFunc("/stations", getStations).Methods("GET")
 	api.HandleFunc("/stations/nearest", getNearestStation).Methods("GET")
+	api.HandleFunc("/stations/match", matchStationHandler).Methods("GET")
+	api.HandleFunc("/stations", createStation).Methods("POST")
+	api.HandleFunc("/stations/import", importStations).Methods("POST")
+	api.HandleFunc("/stations/{id}", updateStation).Methods("PUT")
+	api.HandleFunc("/stations/{id}", deleteStation).Methods("DELETE")
+	api.HandleFunc("/stations/{id}/positions", getStationPositions).Methods("GET")
 	api.HandleFunc("/images", getImages).Methods("GET")
 	api.HandleFunc("/images/{id}", getImage).Methods("GET")
 	api.HandleFunc("/images/{id}", deleteImage).Methods("DELETE")
@@ -721,6 +1032,15 @@ func main() {
 	api.HandleFunc("/annotations/{id}", deleteAnnotation).Methods("DELETE")
 	api.HandleFunc("/upload", uploadImage).Methods("POST")
 	api.HandleFunc("/geocode", geocodeAddress).Methods("POST")
+	api.HandleFunc("/geocode/usage", getGeocodeUsage).Methods("GET")
+	api.HandleFunc("/geocode/reverse", reverseGeocode).Methods("POST")
+	api.HandleFunc("/geocode/batch", startGeocodeBatch).Methods("POST")
+	api.HandleFunc("/geocode/batch/{id}", getGeocodeBatch).Methods("GET")
+	api.HandleFunc("/geocode/batch/{id}", cancelGeocodeBatch).Methods("DELETE")
+	api.HandleFunc("/images/{id}/ocr", rerunImageOCR).Methods("POST")
+	api.HandleFunc("/images/{id}/ocr/history", getOCRHistory).Methods("GET")
+	api.HandleFunc("/ocr/jobs", getOCRJobs).Methods("GET")
+	api.HandleFunc("/ocr/rerun", rerunOCR).Methods("POST")
 
 	// Image serving route
 	r.HandleFunc("/images/{filename}", serveImage).Methods("GET")
@@ -728,8 +1048,8 @@ func main() {
 	// Serve static files from frontend
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir(getStaticDir())))
 
-	// Enable CORS
-	handler := enableCORS(r)
+	// Enable CORS; every request gets an X-Request-ID and a completion log line
+	handler := requestIDMiddleware(enableCORS(r))
 
 	// Start server
 	port := getEnv("PORT", "8080")
@@ -737,6 +1057,20 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s...\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, handler))
+	server := &http.Server{Addr: ":" + port, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Server shutdown failed", "error", err)
+		}
+	}()
+
+	slog.Info("Server starting", "port", port)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fatal("Server stopped", err)
+	}
+	ocrQueue.Wait()
+	slog.Info("Server stopped")
 }
@@ -0,0 +1,235 @@
+-- 将只有 stations / images / annotations 三张表的旧版数据库升级到当前 schema.sql 的结构
+-- 可重复执行：已存在的列、索引与表会跳过，补齐数据的语句只在新增对应列时执行一次
+-- 新建数据库请直接运行 schema.sql
+USE weather_label_db;
+
+DROP PROCEDURE IF EXISTS add_column_if_missing;
+DROP PROCEDURE IF EXISTS add_index_if_missing;
+
+DELIMITER //
+
+-- 列不存在时新增，backfill 不为 NULL 时在新增后执行一次
+CREATE PROCEDURE add_column_if_missing(IN tbl VARCHAR(64), IN col VARCHAR(64), IN definition TEXT, IN backfill TEXT)
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.COLUMNS
+                   WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = tbl AND COLUMN_NAME = col) THEN
+        SET @ddl = CONCAT('ALTER TABLE `', tbl, '` ADD COLUMN `', col, '` ', definition);
+        PREPARE stmt FROM @ddl;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+        IF backfill IS NOT NULL THEN
+            SET @dml = backfill;
+            PREPARE stmt FROM @dml;
+            EXECUTE stmt;
+            DEALLOCATE PREPARE stmt;
+        END IF;
+    END IF;
+END //
+
+CREATE PROCEDURE add_index_if_missing(IN tbl VARCHAR(64), IN idx VARCHAR(64), IN definition TEXT)
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.STATISTICS
+                   WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = tbl AND INDEX_NAME = idx) THEN
+        SET @ddl = CONCAT('ALTER TABLE `', tbl, '` ADD INDEX `', idx, '` ', definition);
+        PREPARE stmt FROM @ddl;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END //
+
+DELIMITER ;
+
+-- stations: 类型按区站号与名称推断，与 schema.sql 中的初始数据一致
+CALL add_column_if_missing('stations', 'station_type', "ENUM('national', 'regional', 'micro') NOT NULL DEFAULT 'regional' AFTER latitude",
+    "UPDATE stations SET station_type = CASE WHEN id REGEXP '^[0-9]{5}$' THEN 'national' WHEN name LIKE '%微智站' THEN 'micro' ELSE 'regional' END");
+CALL add_column_if_missing('stations', 'active_from', 'DATE NULL AFTER station_type', NULL);
+CALL add_column_if_missing('stations', 'active_to', 'DATE NULL AFTER active_from', NULL);
+CALL add_column_if_missing('stations', 'coord_system', "ENUM('wgs84', 'gcj02', 'bd09') NOT NULL DEFAULT 'wgs84' COMMENT '经纬度坐标系，读取时统一转换为 WGS-84' AFTER active_to", NULL);
+
+-- images: 旧数据只有 is_standard，据此补齐 ocr_status
+CALL add_column_if_missing('images', 'ocr_status', "ENUM('standard', 'non_standard', 'uncertain') DEFAULT NULL COMMENT 'NULL=未处理, uncertain=置信度低于阈值需人工复核' AFTER is_standard",
+    "UPDATE images SET ocr_status = IF(is_standard, 'standard', 'non_standard') WHERE is_standard IS NOT NULL");
+CALL add_column_if_missing('images', 'ocr_datetime', "DATETIME DEFAULT NULL COMMENT 'ocr_time解析后的时间，无法解析时为NULL' AFTER ocr_time", NULL);
+CALL add_column_if_missing('images', 'ocr_confidence', "DECIMAL(5, 4) DEFAULT NULL COMMENT 'OCR最近一次识别的模型置信度' AFTER ocr_location", NULL);
+CALL add_column_if_missing('images', 'exif_time', "DATETIME DEFAULT NULL COMMENT 'EXIF拍摄时间(DateTimeOriginal)' AFTER ocr_confidence", NULL);
+CALL add_column_if_missing('images', 'exif_latitude', "DECIMAL(10, 7) DEFAULT NULL COMMENT 'EXIF GPS纬度(WGS-84)' AFTER exif_time", NULL);
+CALL add_column_if_missing('images', 'exif_longitude', "DECIMAL(10, 7) DEFAULT NULL COMMENT 'EXIF GPS经度(WGS-84)' AFTER exif_latitude", NULL);
+CALL add_column_if_missing('images', 'camera_make', 'VARCHAR(100) DEFAULT NULL AFTER exif_longitude', NULL);
+CALL add_column_if_missing('images', 'camera_model', 'VARCHAR(100) DEFAULT NULL AFTER camera_make', NULL);
+CALL add_column_if_missing('images', 'orientation', "TINYINT DEFAULT NULL COMMENT 'EXIF方向(1-8)' AFTER camera_model", NULL);
+CALL add_column_if_missing('images', 'location_province', "VARCHAR(32) DEFAULT NULL COMMENT 'ocr_location匹配到的省' AFTER orientation", NULL);
+CALL add_column_if_missing('images', 'location_city', "VARCHAR(32) DEFAULT NULL COMMENT 'ocr_location匹配到的市' AFTER location_province", NULL);
+CALL add_column_if_missing('images', 'location_county', "VARCHAR(32) DEFAULT NULL COMMENT 'ocr_location匹配到的区县' AFTER location_city", NULL);
+CALL add_column_if_missing('images', 'location_town', "VARCHAR(32) DEFAULT NULL COMMENT 'ocr_location匹配到的乡镇/街道' AFTER location_county", NULL);
+CALL add_column_if_missing('images', 'location_detail', "VARCHAR(255) DEFAULT NULL COMMENT '行政区划之后的剩余地址' AFTER location_town", NULL);
+CALL add_index_if_missing('images', 'idx_ocr_status', '(ocr_status)');
+
+-- annotations: 旧版直接保存百度地理编码结果，已有标注均为 BD-09 坐标
+CALL add_column_if_missing('annotations', 'coord_system', "ENUM('wgs84', 'gcj02', 'bd09') NOT NULL DEFAULT 'wgs84' COMMENT '经纬度坐标系，新标注统一保存为 WGS-84' AFTER station_id",
+    "UPDATE annotations SET coord_system = 'bd09'");
+CALL add_column_if_missing('annotations', 'geocode_provider', "VARCHAR(32) DEFAULT NULL COMMENT '坐标来自地理编码时的服务商，手动输入为 NULL' AFTER coord_system", NULL);
+CALL add_column_if_missing('annotations', 'geocode_level', "VARCHAR(50) DEFAULT NULL COMMENT '地理编码匹配级别' AFTER geocode_provider", NULL);
+CALL add_column_if_missing('annotations', 'geocode_precise', 'TINYINT(1) DEFAULT NULL AFTER geocode_level', NULL);
+CALL add_column_if_missing('annotations', 'geocode_confidence', 'SMALLINT DEFAULT NULL AFTER geocode_precise', NULL);
+CALL add_column_if_missing('annotations', 'geocode_comprehension', 'SMALLINT DEFAULT NULL AFTER geocode_confidence', NULL);
+CALL add_column_if_missing('annotations', 'geocode_warnings', "VARCHAR(255) DEFAULT NULL COMMENT '逗号分隔的质量警告，如 coarse_match' AFTER geocode_comprehension", NULL);
+
+DROP PROCEDURE add_column_if_missing;
+DROP PROCEDURE add_index_if_missing;
+
+-- 新增的表，定义与 schema.sql 相同
+-- Station position history (站点迁移前的位置，valid_to 为迁出时间)
+CREATE TABLE IF NOT EXISTS station_positions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    station_id VARCHAR(255) NOT NULL,
+    longitude DECIMAL(10, 5) NOT NULL,
+    latitude DECIMAL(10, 5) NOT NULL,
+    coord_system ENUM('wgs84', 'gcj02', 'bd09') NOT NULL DEFAULT 'wgs84' COMMENT '经纬度坐标系',
+    valid_to DATETIME NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (station_id) REFERENCES stations(id) ON DELETE CASCADE,
+    INDEX idx_station_valid_to (station_id, valid_to)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Station aliases table (站点别名，可为俗称、旧名或拼音)
+CREATE TABLE IF NOT EXISTS station_aliases (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    station_id VARCHAR(255) NOT NULL,
+    alias VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (station_id) REFERENCES stations(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_station_alias (station_id, alias)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Known places for offline geocoding (离线地理编码使用的地名，如村、小区、学校)
+CREATE TABLE IF NOT EXISTS geocode_places (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    longitude DECIMAL(10, 5) NOT NULL,
+    latitude DECIMAL(10, 5) NOT NULL,
+    coord_system ENUM('wgs84', 'gcj02', 'bd09') NOT NULL DEFAULT 'wgs84' COMMENT '经纬度坐标系',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_place_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Geocoding cache keyed by normalized address (地理编码缓存，按规范化地址的 SHA-256 索引)
+CREATE TABLE IF NOT EXISTS geocode_cache (
+    address_hash CHAR(64) PRIMARY KEY,
+    address VARCHAR(500) NOT NULL COMMENT '规范化后的地址',
+    provider VARCHAR(32) NOT NULL,
+    longitude DECIMAL(12, 8) NOT NULL,
+    latitude DECIMAL(12, 8) NOT NULL,
+    coord_system ENUM('wgs84', 'gcj02', 'bd09') NOT NULL COMMENT '服务商返回坐标的坐标系',
+    level VARCHAR(50) NOT NULL DEFAULT '',
+    precise TINYINT(1) DEFAULT NULL,
+    confidence SMALLINT DEFAULT NULL,
+    comprehension SMALLINT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    INDEX idx_geocode_cache_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Daily geocoding requests per provider (每天各服务商的请求数，provider 为 cache 时表示缓存命中)
+CREATE TABLE IF NOT EXISTS geocode_usage (
+    day DATE NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    requests INT NOT NULL DEFAULT 0,
+    failures INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, provider)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- OCR jobs table (异步 OCR 任务队列，重启后可恢复)
+CREATE TABLE IF NOT EXISTS ocr_jobs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    image_id INT NOT NULL,
+    status ENUM('pending', 'running', 'done', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT DEFAULT NULL,
+    next_run_at DATETIME DEFAULT NULL COMMENT '失败重试的最早执行时间',
+    started_at DATETIME DEFAULT NULL,
+    finished_at DATETIME DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    active_image_id INT GENERATED ALWAYS AS (IF(status IN ('pending', 'running'), image_id, NULL)) STORED COMMENT '每张图片最多一条未完成任务',
+    FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE,
+    UNIQUE KEY uk_active_image (active_image_id),
+    INDEX idx_status_next_run (status, next_run_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- OCR history table (重新识别前的旧结果)
+CREATE TABLE IF NOT EXISTS ocr_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    image_id INT NOT NULL,
+    job_id BIGINT DEFAULT NULL COMMENT '替换该结果的 OCR 任务',
+    is_standard BOOLEAN DEFAULT NULL,
+    ocr_status ENUM('standard', 'non_standard', 'uncertain') DEFAULT NULL,
+    ocr_time VARCHAR(255) DEFAULT NULL,
+    ocr_datetime DATETIME DEFAULT NULL,
+    ocr_location VARCHAR(255) DEFAULT NULL,
+    ocr_confidence DECIMAL(5, 4) DEFAULT NULL,
+    replaced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE,
+    INDEX idx_image_id (image_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- OCR results table (每次 VLM 调用的完整输出，用于审核与模型质量评估)
+CREATE TABLE IF NOT EXISTS ocr_results (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    image_id INT NOT NULL,
+    job_id BIGINT DEFAULT NULL,
+    provider VARCHAR(64) DEFAULT NULL,
+    model VARCHAR(128) DEFAULT NULL,
+    thinking BOOLEAN NOT NULL DEFAULT FALSE,
+    confidence DECIMAL(5, 4) DEFAULT NULL,
+    time_confidence DECIMAL(5, 4) DEFAULT NULL,
+    location_confidence DECIMAL(5, 4) DEFAULT NULL,
+    status ENUM('standard', 'non_standard', 'uncertain') DEFAULT NULL,
+    notes TEXT DEFAULT NULL,
+    ocr_time VARCHAR(255) DEFAULT NULL,
+    ocr_location VARCHAR(255) DEFAULT NULL,
+    latency_ms INT NOT NULL DEFAULT 0,
+    prompt_tokens INT NOT NULL DEFAULT 0,
+    completion_tokens INT NOT NULL DEFAULT 0,
+    total_tokens INT NOT NULL DEFAULT 0,
+    raw_response MEDIUMTEXT DEFAULT NULL,
+    error TEXT DEFAULT NULL COMMENT '调用失败时的错误信息',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE,
+    INDEX idx_image_id (image_id),
+    INDEX idx_confidence (confidence)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Geocode batches table (批量地理编码任务进度，重启时运行中的批次标记为失败)
+CREATE TABLE IF NOT EXISTS geocode_batches (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    status ENUM('running', 'done', 'failed', 'cancelled') NOT NULL DEFAULT 'running',
+    total INT NOT NULL DEFAULT 0,
+    processed INT NOT NULL DEFAULT 0,
+    succeeded INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    last_error TEXT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finished_at DATETIME DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Geocode suggestions table (批量地理编码为未标注图片生成的坐标与最近站点，用于预填标注表单)
+CREATE TABLE IF NOT EXISTS geocode_suggestions (
+    image_id INT PRIMARY KEY,
+    batch_id BIGINT DEFAULT NULL,
+    address VARCHAR(500) NOT NULL COMMENT '补全后用于地理编码的地址',
+    longitude DECIMAL(10, 7) DEFAULT NULL,
+    latitude DECIMAL(10, 7) DEFAULT NULL,
+    coord_system ENUM('wgs84', 'gcj02', 'bd09') NOT NULL DEFAULT 'wgs84',
+    station_id VARCHAR(255) DEFAULT NULL COMMENT '观测时间运行中的最近站点，站点删除后不再显示名称',
+    station_distance_km DECIMAL(10, 3) DEFAULT NULL,
+    geocode_provider VARCHAR(32) DEFAULT NULL,
+    geocode_level VARCHAR(50) DEFAULT NULL,
+    geocode_precise TINYINT(1) DEFAULT NULL,
+    geocode_confidence SMALLINT DEFAULT NULL,
+    geocode_comprehension SMALLINT DEFAULT NULL,
+    geocode_warnings VARCHAR(255) DEFAULT NULL,
+    error TEXT DEFAULT NULL COMMENT '地理编码失败原因，成功时为 NULL',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE,
+    FOREIGN KEY (batch_id) REFERENCES geocode_batches(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
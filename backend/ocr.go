@@ -2,15 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
-	"mime"
+	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -29,6 +28,8 @@ const (
   "time": "24小时制时间戳，格式为 YYYY-MM-DD HH:MM[:SS]，若无法确定则为空字符串",
   "location": "地点中文名称，包含省市县或测站名称，无法确定则为空字符串",
   "confidence": 小数，0-1 之间，表示整体提取置信度,
+  "time_confidence": 小数，0-1 之间，表示时间字段的置信度,
+  "location_confidence": 小数，0-1 之间，表示地点字段的置信度,
   "notes": "可选，说明判断依据，若无可留空"
 }
 仅返回 JSON，不要添加其它文字。`
@@ -41,7 +42,8 @@ type QwenVLMClient struct {
 	Model          string
 	EnableThinking bool
 	ThinkingBudget int
-	httpClient     *http.Client
+	transport      vlmTransport
+	preprocess     ImagePreprocessConfig
 }
 
 type qwenMessage struct {
@@ -72,6 +74,7 @@ type qwenChatResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *vlmUsage `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
@@ -79,19 +82,93 @@ type qwenChatResponse struct {
 	} `json:"error,omitempty"`
 }
 
+// vlmUsage 为一次调用消耗的 token 数
+type vlmUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
 // vlmStructuredResult 定义模型返回的结构化内容
 type vlmStructuredResult struct {
 	Time       string  `json:"time"`
 	Location   string  `json:"location"`
 	Confidence float64 `json:"confidence"`
 	Notes      string  `json:"notes,omitempty"`
+
+	TimeConfidence     *float64 `json:"time_confidence,omitempty"`
+	LocationConfidence *float64 `json:"location_confidence,omitempty"`
+
+	// 以下字段由客户端填充，不来自模型输出
+	Model       string   `json:"-"`
+	Thinking    bool     `json:"-"`
+	Usage       vlmUsage `json:"-"`
+	RawResponse string   `json:"-"`
+}
+
+// chatCompletionResult 为 chat completions 接口的解析结果
+type chatCompletionResult struct {
+	Content string
+	Usage   vlmUsage
+	Raw     string
 }
 
 // OCRResult OCR识别结果
 type OCRResult struct {
-	Time       string // 识别到的时间
-	Location   string // 识别到的地点
-	IsStandard bool   // 是否为标准图片（同时有时间和地点）
+	Time       string     // 识别到的时间，可解析时为 YYYY-MM-DD HH:MM:SS，否则为模型原文
+	DateTime   *time.Time // 解析后的时间，无法解析时为 nil
+	Location   string     // 识别到的地点，匹配到行政区划时为规范化后的地址
+	IsStandard bool       // 是否为标准图片（同时有时间和地点，且置信度达标）
+	Status     string     // 识别状态：standard / non_standard / uncertain
+
+	Confidence         float64       // 模型给出的整体置信度
+	TimeConfidence     *float64      // 时间字段置信度（模型可能不提供）
+	LocationConfidence *float64      // 地点字段置信度（模型可能不提供）
+	Notes              string        // 模型给出的判断依据
+	Provider           string        // VLM 后端名称，为空表示未调用模型
+	Model              string        // 模型名称
+	Thinking           bool          // 是否开启思考模式
+	Latency            time.Duration // 调用耗时
+	Usage              vlmUsage      // token 用量
+	RawResponse        string        // 原始响应
+
+	// 按行政区划拆分的地点，未识别出地点时为 nil
+	LocationParts *ParsedLocation
+}
+
+// OCRAttemptError 携带失败调用的元数据，便于记录到 ocr_results；
+// 收到响应但无法解析时同时保留模型、用量与原始响应
+type OCRAttemptError struct {
+	Provider    string
+	Model       string
+	Latency     time.Duration
+	Usage       vlmUsage
+	RawResponse string
+	Err         error
+}
+
+func (e *OCRAttemptError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OCRAttemptError) Unwrap() error {
+	return e.Err
+}
+
+// vlmResponseError 表示收到了模型响应但无法解析出结构化结果
+type vlmResponseError struct {
+	Model       string
+	Usage       vlmUsage
+	RawResponse string
+	Err         error
+}
+
+func (e *vlmResponseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *vlmResponseError) Unwrap() error {
+	return e.Err
 }
 
 // NewQwenVLMClient 创建 VLM 客户端
@@ -102,8 +179,9 @@ func NewQwenVLMClient(apiKey, baseURL, model string, enableThinking bool, thinki
 		Model:          model,
 		EnableThinking: enableThinking,
 		ThinkingBudget: thinkingBudget,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+		transport: vlmTransport{
+			client: &http.Client{Timeout: 60 * time.Second},
+			retry:  defaultRetryPolicy,
 		},
 	}
 
@@ -117,31 +195,21 @@ func NewQwenVLMClient(apiKey, baseURL, model string, enableThinking bool, thinki
 	return client
 }
 
+// Name 返回后端名称
+func (c *QwenVLMClient) Name() string {
+	return "qwen"
+}
+
 // ExtractMetadata 调用 VLM 模型识别时间、地点
-func (c *QwenVLMClient) ExtractMetadata(imagePath string) (*vlmStructuredResult, error) {
-	dataURL, err := encodeImageToDataURL(imagePath)
+func (c *QwenVLMClient) ExtractMetadata(ctx context.Context, imagePath string) (*vlmStructuredResult, error) {
+	dataURL, err := encodeImageToDataURL(imagePath, c.preprocess)
 	if err != nil {
 		return nil, err
 	}
 
 	request := qwenChatRequest{
-		Model: c.Model,
-		Messages: []qwenMessage{
-			{
-				Role: "system",
-				Content: []qwenContent{{
-					Type: "text",
-					Text: vlmSystemPrompt,
-				}},
-			},
-			{
-				Role: "user",
-				Content: []qwenContent{
-					{Type: "text", Text: vlmUserInstruction},
-					{Type: "image_url", ImageURL: &qwenImageURL{URL: dataURL}},
-				},
-			},
-		},
+		Model:    c.Model,
+		Messages: buildVisionMessages(dataURL),
 	}
 
 	if c.EnableThinking {
@@ -152,31 +220,66 @@ func (c *QwenVLMClient) ExtractMetadata(imagePath string) (*vlmStructuredResult,
 		}
 	}
 
-	payload, err := json.Marshal(request)
+	completion, err := postChatCompletion(ctx, c.transport, c.BaseURL, c.APIKey, request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal VLM request: %w", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", c.BaseURL, bytes.NewReader(payload))
+	result, err := parseVLMJSON(completion.Content)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create VLM request: %w", err)
+		return nil, &vlmResponseError{Model: c.Model, Usage: completion.Usage, RawResponse: completion.Raw, Err: err}
+	}
+	result.Model = c.Model
+	result.Thinking = c.EnableThinking
+	result.Usage = completion.Usage
+	result.RawResponse = completion.Raw
+	return result, nil
+}
+
+// buildVisionMessages 构造 OpenAI 兼容格式的图文消息
+func buildVisionMessages(dataURL string) []qwenMessage {
+	return []qwenMessage{
+		{
+			Role: "system",
+			Content: []qwenContent{{
+				Type: "text",
+				Text: vlmSystemPrompt,
+			}},
+		},
+		{
+			Role: "user",
+			Content: []qwenContent{
+				{Type: "text", Text: vlmUserInstruction},
+				{Type: "image_url", ImageURL: &qwenImageURL{URL: dataURL}},
+			},
+		},
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
-	req.Header.Set("Content-Type", "application/json")
+}
 
-	resp, err := c.httpClient.Do(req)
+// postChatCompletion 发送 OpenAI 兼容的 chat completions 请求，返回首个回答的文本与用量
+func postChatCompletion(ctx context.Context, transport vlmTransport, endpoint, apiKey string, request qwenChatRequest) (*chatCompletionResult, error) {
+	payload, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call VLM API: %w", err)
+		return nil, fmt.Errorf("failed to marshal VLM request: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	status, body, err := transport.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create VLM request: %w", err)
+		}
+		if apiKey != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read VLM response: %w", err)
+		return nil, fmt.Errorf("failed to call VLM API: %w", err)
 	}
 
-	if resp.StatusCode >= http.StatusBadRequest {
-		return nil, fmt.Errorf("VLM API error: status %d, body %s", resp.StatusCode, string(body))
+	if status >= http.StatusBadRequest {
+		return nil, fmt.Errorf("VLM API error: status %d, body %s", status, logRedactor.Redact(string(body)))
 	}
 
 	var chatResp qwenChatResponse
@@ -197,23 +300,27 @@ func (c *QwenVLMClient) ExtractMetadata(imagePath string) (*vlmStructuredResult,
 		return nil, fmt.Errorf("VLM API returned empty content")
 	}
 
-	return parseVLMJSON(rawContent)
+	completion := &chatCompletionResult{Content: rawContent, Raw: string(body)}
+	if chatResp.Usage != nil {
+		completion.Usage = *chatResp.Usage
+	}
+	return completion, nil
 }
 
-func encodeImageToDataURL(imagePath string) (string, error) {
-	imageData, err := os.ReadFile(imagePath)
+func encodeImageToDataURL(imagePath string, preprocess ImagePreprocessConfig) (string, error) {
+	imageData, mimeType, err := preprocessImage(imagePath, preprocess)
 	if err != nil {
-		return "", fmt.Errorf("failed to read image: %w", err)
+		return "", err
 	}
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(imageData)), nil
+}
 
-	ext := strings.ToLower(filepath.Ext(imagePath))
-	mimeType := mime.TypeByExtension(ext)
-	if mimeType == "" {
-		mimeType = "application/octet-stream"
+func encodeImageToBase64(imagePath string, preprocess ImagePreprocessConfig) (string, error) {
+	imageData, _, err := preprocessImage(imagePath, preprocess)
+	if err != nil {
+		return "", err
 	}
-
-	encoded := base64.StdEncoding.EncodeToString(imageData)
-	return fmt.Sprintf("data:%s;base64,%s", mimeType, encoded), nil
+	return base64.StdEncoding.EncodeToString(imageData), nil
 }
 
 func parseVLMJSON(raw string) (*vlmStructuredResult, error) {
@@ -251,130 +358,6 @@ func sanitizeJSONBlock(raw string) string {
 	return trimmed
 }
 
-// normalizeTime 标准化时间格式，转换为 YYYY-MM-DD HH:MM:SS 格式
-func normalizeTime(timeStr string) string {
-	// 移除中文字符并替换为标准分隔符
-	timeStr = strings.ReplaceAll(timeStr, "年", "-")
-	timeStr = strings.ReplaceAll(timeStr, "月", "-")
-	timeStr = strings.ReplaceAll(timeStr, "日", "")
-
-	// 清理多余空格
-	timeStr = strings.TrimSpace(timeStr)
-
-	// 处理常见的OCR错误：日期和时间之间缺少空格
-	// 例如: "2023-07-2015:47" -> "2023-07-20 15:47"
-	// 匹配 YYYY-MM-DD或YYYY-MM-D后面直接跟数字的情况
-	timeStr = regexp.MustCompile(`^(\d{4}-\d{2}-\d{1,2})(\d{2}:\d{2})`).ReplaceAllString(timeStr, "$1 $2")
-	timeStr = regexp.MustCompile(`^(\d{4}/\d{2}/\d{1,2})(\d{2}:\d{2})`).ReplaceAllString(timeStr, "$1 $2")
-
-	// 清理多余空格
-	timeStr = regexp.MustCompile(`\s+`).ReplaceAllString(timeStr, " ")
-
-	// 处理紧凑格式 YYYYMMDDHHMMSS (14位)
-	if matched, _ := regexp.MatchString(`^\d{14}$`, timeStr); matched {
-		return fmt.Sprintf("%s-%s-%s %s:%s:%s",
-			timeStr[0:4], timeStr[4:6], timeStr[6:8],
-			timeStr[8:10], timeStr[10:12], timeStr[12:14])
-	}
-
-	// 处理紧凑格式 YYYYMMDD HHMMSS (带空格的14位)
-	if matched, _ := regexp.MatchString(`^\d{8}\s+\d{6}$`, timeStr); matched {
-		cleaned := strings.ReplaceAll(timeStr, " ", "")
-		return fmt.Sprintf("%s-%s-%s %s:%s:%s",
-			cleaned[0:4], cleaned[4:6], cleaned[6:8],
-			cleaned[8:10], cleaned[10:12], cleaned[12:14])
-	}
-
-	// 处理紧凑格式 YYYYMMDDHHMM (12位，没有秒)
-	if matched, _ := regexp.MatchString(`^\d{12}$`, timeStr); matched {
-		return fmt.Sprintf("%s-%s-%s %s:%s:00",
-			timeStr[0:4], timeStr[4:6], timeStr[6:8],
-			timeStr[8:10], timeStr[10:12])
-	}
-
-	// 处理紧凑格式 YYYYMMDD HHMM (带空格的12位)
-	if matched, _ := regexp.MatchString(`^\d{8}\s+\d{4}$`, timeStr); matched {
-		cleaned := strings.ReplaceAll(timeStr, " ", "")
-		return fmt.Sprintf("%s-%s-%s %s:%s:00",
-			cleaned[0:4], cleaned[4:6], cleaned[6:8],
-			cleaned[8:10], cleaned[10:12])
-	}
-
-	// 处理只有日期的格式 YYYYMMDD (8位)
-	if matched, _ := regexp.MatchString(`^\d{8}$`, timeStr); matched {
-		return fmt.Sprintf("%s-%s-%s 00:00:00",
-			timeStr[0:4], timeStr[4:6], timeStr[6:8])
-	}
-
-	// 处理标准格式带秒 YYYY-MM-DD HH:MM:SS (已经是标准格式)
-	if matched, _ := regexp.MatchString(`^\d{4}-\d{1,2}-\d{1,2}\s+\d{1,2}:\d{2}:\d{2}$`, timeStr); matched {
-		// 补齐单位数的月、日、时
-		parts := regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})\s+(\d{1,2}):(\d{2}):(\d{2})$`).FindStringSubmatch(timeStr)
-		if len(parts) == 7 {
-			return fmt.Sprintf("%s-%02s-%02s %02s:%s:%s",
-				parts[1], parts[2], parts[3], parts[4], parts[5], parts[6])
-		}
-		return timeStr
-	}
-
-	// 处理标准格式但没有秒 YYYY-MM-DD HH:MM 或 YYYY-MM-D HH:MM
-	if matched, _ := regexp.MatchString(`^\d{4}-\d{1,2}-\d{1,2}\s+\d{1,2}:\d{2}$`, timeStr); matched {
-		parts := regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})\s+(\d{1,2}):(\d{2})$`).FindStringSubmatch(timeStr)
-		if len(parts) == 6 {
-			return fmt.Sprintf("%s-%02s-%02s %02s:%s:00",
-				parts[1], parts[2], parts[3], parts[4], parts[5])
-		}
-		return timeStr + ":00"
-	}
-
-	// 处理只有日期 YYYY-MM-DD 或 YYYY-MM-D
-	if matched, _ := regexp.MatchString(`^\d{4}-\d{1,2}-\d{1,2}$`, timeStr); matched {
-		parts := regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`).FindStringSubmatch(timeStr)
-		if len(parts) == 4 {
-			return fmt.Sprintf("%s-%02s-%02s 00:00:00", parts[1], parts[2], parts[3])
-		}
-		return timeStr + " 00:00:00"
-	}
-
-	// 处理斜杠格式带秒 YYYY/MM/DD HH:MM:SS
-	if matched, _ := regexp.MatchString(`^\d{4}/\d{1,2}/\d{1,2}\s+\d{1,2}:\d{2}:\d{2}$`, timeStr); matched {
-		parts := regexp.MustCompile(`^(\d{4})/(\d{1,2})/(\d{1,2})\s+(\d{1,2}):(\d{2}):(\d{2})$`).FindStringSubmatch(timeStr)
-		if len(parts) == 7 {
-			return fmt.Sprintf("%s-%02s-%02s %02s:%s:%s",
-				parts[1], parts[2], parts[3], parts[4], parts[5], parts[6])
-		}
-		timeStr = strings.ReplaceAll(timeStr, "/", "-")
-		return timeStr
-	}
-
-	// 处理斜杠格式但没有秒 YYYY/MM/DD HH:MM
-	if matched, _ := regexp.MatchString(`^\d{4}/\d{1,2}/\d{1,2}\s+\d{1,2}:\d{2}$`, timeStr); matched {
-		parts := regexp.MustCompile(`^(\d{4})/(\d{1,2})/(\d{1,2})\s+(\d{1,2}):(\d{2})$`).FindStringSubmatch(timeStr)
-		if len(parts) == 6 {
-			return fmt.Sprintf("%s-%02s-%02s %02s:%s:00",
-				parts[1], parts[2], parts[3], parts[4], parts[5])
-		}
-		timeStr = strings.ReplaceAll(timeStr, "/", "-")
-		return timeStr + ":00"
-	}
-
-	// 处理斜杠格式只有日期 YYYY/MM/DD 或 YYYY/MM/D
-	if matched, _ := regexp.MatchString(`^\d{4}/\d{1,2}/\d{1,2}`, timeStr); matched {
-		parts := regexp.MustCompile(`^(\d{4})/(\d{1,2})/(\d{1,2})`).FindStringSubmatch(timeStr)
-		if len(parts) == 4 {
-			return fmt.Sprintf("%s-%02s-%02s 00:00:00", parts[1], parts[2], parts[3])
-		}
-		timeStr = strings.ReplaceAll(timeStr, "/", "-")
-	}
-
-	// 如果已经是标准格式，直接返回
-	if matched, _ := regexp.MatchString(`^\d{4}-\d{2}-\d{2}\s+\d{2}:\d{2}:\d{2}$`, timeStr); matched {
-		return timeStr
-	}
-
-	return timeStr
-}
-
 // cleanLocationText 清理地点文字
 func cleanLocationText(text string) string {
 	// 移除可能的特殊字符和多余空格
@@ -383,36 +366,90 @@ func cleanLocationText(text string) string {
 	return text
 }
 
-// ProcessImageOCR 处理图片OCR（主入口函数）
-func ProcessImageOCR(imagePath string) (*OCRResult, error) {
-	apiKey := getEnv("QWEN_VLM_API_KEY", "")
-	if apiKey == "" {
-		log.Println("Warning: Qwen VLM API key not configured, skipping OCR processing")
-		return &OCRResult{IsStandard: false}, nil
+// setLocation 清理模型返回的地点并按行政区划拆分；Location 保留清理后的原文，
+// 规范化结果只写入 LocationParts（location_* 列）
+func (r *OCRResult) setLocation(raw string) {
+	r.Location = cleanLocationText(raw)
+	parts := gazetteer.Normalize(r.Location)
+	r.LocationParts = &parts
+}
+
+// applyEXIFFromFile 读取图片 EXIF 补全或校验识别结果，没有 EXIF 时不做处理
+func applyEXIFFromFile(result *OCRResult, imagePath string) {
+	exif, err := readEXIFFile(imagePath)
+	if err != nil {
+		if !errors.Is(err, errNoEXIF) && !os.IsNotExist(err) {
+			slog.Warn("Failed to read EXIF", "path", imagePath, "error", err)
+		}
+		return
 	}
+	applyEXIF(result, exif)
+}
 
-	baseURL := getEnv("QWEN_VLM_BASE_URL", defaultQwenBaseURL)
-	model := getEnv("QWEN_VLM_MODEL", defaultQwenModel)
-	enableThinking := strings.EqualFold(getEnv("QWEN_VLM_ENABLE_THINKING", ""), "true")
-	thinkingBudget := getEnvInt("QWEN_VLM_THINKING_BUDGET", 0)
+// ProcessImageOCR 处理图片OCR（主入口函数），ctx 取消时中止模型调用
+func ProcessImageOCR(ctx context.Context, imagePath string) (*OCRResult, error) {
+	extractor := metadataExtractor
+	if extractor == nil {
+		slog.WarnContext(ctx, "VLM extractor not configured, skipping OCR processing", "path", imagePath)
+		result := &OCRResult{IsStandard: false, Status: OCRStatusNonStandard}
+		applyEXIFFromFile(result, imagePath)
+		return result, nil
+	}
 
-	client := NewQwenVLMClient(apiKey, baseURL, model, enableThinking, thinkingBudget)
-	structured, err := client.ExtractMetadata(imagePath)
+	if vlmCallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, vlmCallTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	structured, err := extractor.ExtractMetadata(ctx, imagePath)
+	latency := time.Since(start)
 	if err != nil {
-		return nil, fmt.Errorf("VLM extraction failed: %w", err)
+		attemptErr := &OCRAttemptError{
+			Provider: extractor.Name(),
+			Latency:  latency,
+			Err:      fmt.Errorf("VLM extraction failed: %w", err),
+		}
+		var respErr *vlmResponseError
+		if errors.As(err, &respErr) {
+			attemptErr.Model, attemptErr.Usage, attemptErr.RawResponse = respErr.Model, respErr.Usage, respErr.RawResponse
+		}
+		return nil, attemptErr
 	}
 
-	result := &OCRResult{}
-	if strings.TrimSpace(structured.Time) != "" {
-		result.Time = normalizeTime(structured.Time)
+	result := &OCRResult{
+		Confidence:         structured.Confidence,
+		TimeConfidence:     structured.TimeConfidence,
+		LocationConfidence: structured.LocationConfidence,
+		Notes:              structured.Notes,
+		Provider:           extractor.Name(),
+		Model:              structured.Model,
+		Thinking:           structured.Thinking,
+		Latency:            latency,
+		Usage:              structured.Usage,
+		RawResponse:        structured.RawResponse,
 	}
+	if clampConfidences(result) {
+		result.Notes = appendNote(result.Notes, "模型置信度超出 [0,1]，已截断")
+	}
+	timeErr := result.setTime(structured.Time)
 	if strings.TrimSpace(structured.Location) != "" {
-		result.Location = cleanLocationText(structured.Location)
+		result.setLocation(structured.Location)
+	}
+	result.Status = classifyOCR(result, ocrThresholds)
+	if timeErr != nil {
+		// 保留原文供人工核对，但不能作为标准图片
+		result.Notes = appendNote(result.Notes, "时间无法解析："+timeErr.Error())
+		if result.Status == OCRStatusStandard {
+			result.Status = OCRStatusUncertain
+		}
 	}
-	result.IsStandard = result.Time != "" && result.Location != ""
+	result.IsStandard = result.Status == OCRStatusStandard
+	applyEXIFFromFile(result, imagePath)
 
-	log.Printf("VLM Result - Time: %s, Location: %s, Confidence: %.2f, IsStandard: %v",
-		result.Time, result.Location, structured.Confidence, result.IsStandard)
+	slog.DebugContext(ctx, "VLM result", "path", imagePath, "provider", result.Provider, "time", result.Time,
+		"location", result.Location, "confidence", result.Confidence, "status", result.Status, "latency", latency)
 
 	return result, nil
 }
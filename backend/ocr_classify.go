@@ -0,0 +1,110 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+)
+
+// OCR 识别状态，对应 images.ocr_status
+const (
+	OCRStatusStandard    = "standard"     // 时间、地点齐全且置信度达标
+	OCRStatusNonStandard = "non_standard" // 缺少时间或地点
+	OCRStatusUncertain   = "uncertain"    // 字段齐全但置信度不足，需要人工复核
+)
+
+const defaultOCRMinConfidence = 0.6
+
+// OCRThresholds 为判定标准图片所需的最低置信度
+type OCRThresholds struct {
+	Overall  float64 // 整体置信度
+	Time     float64 // 时间字段置信度，模型未单独给出时使用整体置信度
+	Location float64 // 地点字段置信度，模型未单独给出时使用整体置信度
+}
+
+// ocrThresholds 为当前生效的阈值，main 中按环境变量加载
+var ocrThresholds = OCRThresholds{
+	Overall:  defaultOCRMinConfidence,
+	Time:     defaultOCRMinConfidence,
+	Location: defaultOCRMinConfidence,
+}
+
+// loadOCRThresholds 读取 OCR_MIN_CONFIDENCE 及按字段覆盖的阈值
+func loadOCRThresholds() OCRThresholds {
+	overall := getEnvConfidence("OCR_MIN_CONFIDENCE", defaultOCRMinConfidence)
+	return OCRThresholds{
+		Overall:  overall,
+		Time:     getEnvConfidence("OCR_MIN_TIME_CONFIDENCE", overall),
+		Location: getEnvConfidence("OCR_MIN_LOCATION_CONFIDENCE", overall),
+	}
+}
+
+// getEnvConfidence 读取 [0,1] 范围内的置信度阈值，超出范围时使用默认值
+func getEnvConfidence(key string, fallback float64) float64 {
+	v := getEnvFloat(key, fallback)
+	if _, ok := clampConfidence(v); !ok {
+		slog.Warn("Confidence setting out of range [0,1], using default", "key", key, "value", v, "default", fallback)
+		return fallback
+	}
+	return v
+}
+
+// clampConfidence 将模型给出的置信度限制在 [0,1]，超出范围时返回 false
+// 列类型为 DECIMAL(5,4)，越界值会导致结果无法入库
+func clampConfidence(v float64) (float64, bool) {
+	switch {
+	case math.IsNaN(v):
+		return 0, false
+	case v < 0:
+		return 0, false
+	case v > 1:
+		return 1, false
+	}
+	return v, true
+}
+
+// clampConfidences 修正结果中所有置信度字段，返回是否有字段被修正
+func clampConfidences(result *OCRResult) bool {
+	changed := false
+	for _, p := range []*float64{&result.Confidence, result.TimeConfidence, result.LocationConfidence} {
+		if p == nil {
+			continue
+		}
+		var ok bool
+		if *p, ok = clampConfidence(*p); !ok {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// isValidOCRStatus 检查查询参数中的状态值
+func isValidOCRStatus(status string) bool {
+	switch status {
+	case OCRStatusStandard, OCRStatusNonStandard, OCRStatusUncertain:
+		return true
+	}
+	return false
+}
+
+// classifyOCR 根据字段完整性与置信度判定识别状态
+func classifyOCR(result *OCRResult, thresholds OCRThresholds) string {
+	if result.Time == "" || result.Location == "" {
+		return OCRStatusNonStandard
+	}
+
+	timeConfidence := result.Confidence
+	if result.TimeConfidence != nil {
+		timeConfidence = *result.TimeConfidence
+	}
+	locationConfidence := result.Confidence
+	if result.LocationConfidence != nil {
+		locationConfidence = *result.LocationConfidence
+	}
+
+	if result.Confidence < thresholds.Overall ||
+		timeConfidence < thresholds.Time ||
+		locationConfidence < thresholds.Location {
+		return OCRStatusUncertain
+	}
+	return OCRStatusStandard
+}
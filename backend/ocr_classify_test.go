@@ -0,0 +1,96 @@
+package main
+
+import "testing"
+
+func TestClassifyOCR(t *testing.T) {
+	low := 0.3
+	high := 0.95
+	thresholds := OCRThresholds{Overall: 0.6, Time: 0.6, Location: 0.8}
+
+	tests := []struct {
+		name     string
+		result   OCRResult
+		expected string
+	}{
+		{
+			name:     "missing location",
+			result:   OCRResult{Time: "2024-01-15 14:30:00", Confidence: 0.99},
+			expected: OCRStatusNonStandard,
+		},
+		{
+			name:     "confident extraction",
+			result:   OCRResult{Time: "2024-01-15 14:30:00", Location: "羊尖镇", Confidence: 0.9},
+			expected: OCRStatusStandard,
+		},
+		{
+			name:     "overall confidence below threshold",
+			result:   OCRResult{Time: "2024-01-15 14:30:00", Location: "羊尖镇", Confidence: 0.5},
+			expected: OCRStatusUncertain,
+		},
+		{
+			name:     "overall falls back for location threshold",
+			result:   OCRResult{Time: "2024-01-15 14:30:00", Location: "羊尖镇", Confidence: 0.7},
+			expected: OCRStatusUncertain,
+		},
+		{
+			name: "per-field confidence overrides overall",
+			result: OCRResult{Time: "2024-01-15 14:30:00", Location: "羊尖镇", Confidence: 0.7,
+				LocationConfidence: &high},
+			expected: OCRStatusStandard,
+		},
+		{
+			name: "low time confidence",
+			result: OCRResult{Time: "2024-01-15 14:30:00", Location: "羊尖镇", Confidence: 0.9,
+				TimeConfidence: &low},
+			expected: OCRStatusUncertain,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.result
+			if got := classifyOCR(&result, thresholds); got != tt.expected {
+				t.Errorf("classifyOCR() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadOCRThresholds(t *testing.T) {
+	t.Setenv("OCR_MIN_CONFIDENCE", "0.7")
+	t.Setenv("OCR_MIN_TIME_CONFIDENCE", "")
+	t.Setenv("OCR_MIN_LOCATION_CONFIDENCE", "0.9")
+
+	thresholds := loadOCRThresholds()
+	if thresholds.Overall != 0.7 || thresholds.Time != 0.7 || thresholds.Location != 0.9 {
+		t.Errorf("unexpected thresholds: %+v", thresholds)
+	}
+}
+
+func TestClampConfidences(t *testing.T) {
+	timeConfidence := 85.0
+	locationConfidence := 0.4
+	result := OCRResult{Confidence: -0.2, TimeConfidence: &timeConfidence, LocationConfidence: &locationConfidence}
+
+	if !clampConfidences(&result) {
+		t.Fatalf("expected out-of-range confidences to be reported")
+	}
+	if result.Confidence != 0 || *result.TimeConfidence != 1 || *result.LocationConfidence != 0.4 {
+		t.Errorf("unexpected confidences: %v %v %v", result.Confidence, *result.TimeConfidence, *result.LocationConfidence)
+	}
+	if clampConfidences(&result) {
+		t.Errorf("expected in-range confidences to be left alone")
+	}
+}
+
+func TestLoadOCRThresholdsRejectsOutOfRange(t *testing.T) {
+	t.Setenv("OCR_MIN_CONFIDENCE", "60")
+	t.Setenv("OCR_MIN_TIME_CONFIDENCE", "-0.1")
+	t.Setenv("OCR_MIN_LOCATION_CONFIDENCE", "NaN")
+
+	thresholds := loadOCRThresholds()
+	want := defaultOCRMinConfidence
+	if thresholds.Overall != want || thresholds.Time != want || thresholds.Location != want {
+		t.Errorf("expected out-of-range thresholds to fall back to %v, got %+v", want, thresholds)
+	}
+}
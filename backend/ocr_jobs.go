@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// OCR 任务状态
+const (
+	OCRJobPending = "pending"
+	OCRJobRunning = "running"
+	OCRJobDone    = "done"
+	OCRJobFailed  = "failed"
+)
+
+const (
+	defaultOCRWorkers      = 4
+	defaultOCRMaxAttempts  = 3
+	defaultOCRPollInterval = 5 * time.Second
+	defaultOCRRetryDelay   = 10 * time.Second
+)
+
+// errOCRJobActive 表示图片已有待处理或处理中的任务
+var errOCRJobActive = errors.New("OCR job already queued for this image")
+
+// OCRJob 对应 ocr_jobs 表中的一条任务
+type OCRJob struct {
+	ID        int64     `json:"id"`
+	ImageID   int       `json:"image_id"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ocrJobStore 负责任务状态的持久化，便于重启后恢复
+type ocrJobStore interface {
+	Enqueue(imageID int) (int64, error) // 图片已有未完成任务时返回 errOCRJobActive
+	ResetRunning() (int64, error)
+	FetchPending(limit int) ([]OCRJob, error)
+	Claim(jobID int64) (bool, error)
+	ImagePath(imageID int) (string, error)
+	Complete(job OCRJob, result *OCRResult) error
+	Fail(job OCRJob, errMsg string, retryAt *time.Time) error
+	RecordAttempt(job OCRJob, result *OCRResult, cause error) error
+}
+
+// OCRQueue 是基于 MySQL 持久化的有界 OCR 工作池
+type OCRQueue struct {
+	store        ocrJobStore
+	process      func(ctx context.Context, imagePath string) (*OCRResult, error)
+	workers      int
+	maxAttempts  int
+	pollInterval time.Duration
+	retryDelay   time.Duration
+
+	jobs chan OCRJob
+	wake chan struct{}
+	wg   sync.WaitGroup
+}
+
+// ocrQueue 为全局任务队列，main 中初始化
+var ocrQueue *OCRQueue
+
+// NewOCRQueue 创建任务队列，workers 为并发处理的最大图片数
+func NewOCRQueue(store ocrJobStore, workers, maxAttempts int) *OCRQueue {
+	if workers <= 0 {
+		workers = defaultOCRWorkers
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultOCRMaxAttempts
+	}
+	return &OCRQueue{
+		store:        store,
+		process:      ProcessImageOCR,
+		workers:      workers,
+		maxAttempts:  maxAttempts,
+		pollInterval: defaultOCRPollInterval,
+		retryDelay:   defaultOCRRetryDelay,
+		jobs:         make(chan OCRJob),
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Start 恢复上次中断的任务并启动调度器与工作协程，ctx 取消后停止
+func (q *OCRQueue) Start(ctx context.Context) {
+	if n, err := q.store.ResetRunning(); err != nil {
+		slog.Error("Failed to reset running OCR jobs", "error", err)
+	} else if n > 0 {
+		slog.Info("Re-queued interrupted OCR jobs", "count", n)
+	}
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+
+	q.wg.Add(1)
+	go q.dispatch(ctx)
+	q.notify()
+}
+
+// Wait 阻塞直到所有协程退出
+func (q *OCRQueue) Wait() {
+	q.wg.Wait()
+}
+
+// Enqueue 为图片创建一条待处理任务并唤醒调度器
+func (q *OCRQueue) Enqueue(imageID int) (int64, error) {
+	id, err := q.store.Enqueue(imageID)
+	if err != nil {
+		return 0, err
+	}
+	q.notify()
+	return id, nil
+}
+
+func (q *OCRQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *OCRQueue) dispatch(ctx context.Context) {
+	defer q.wg.Done()
+	defer close(q.jobs)
+
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+
+		for {
+			pending, err := q.store.FetchPending(q.workers)
+			if err != nil {
+				slog.Error("Failed to fetch pending OCR jobs", "error", err)
+				break
+			}
+			dispatched := 0
+			for _, job := range pending {
+				claimed, err := q.store.Claim(job.ID)
+				if err != nil {
+					slog.Error("Failed to claim OCR job", "job_id", job.ID, "image_id", job.ImageID, "error", err)
+					continue
+				}
+				if !claimed {
+					continue
+				}
+				job.Status = OCRJobRunning
+				job.Attempts++
+
+				select {
+				case q.jobs <- job:
+					dispatched++
+				case <-ctx.Done():
+					return
+				}
+			}
+			if dispatched == 0 {
+				break
+			}
+		}
+	}
+}
+
+func (q *OCRQueue) worker(ctx context.Context) {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		q.run(ctx, job)
+	}
+}
+
+func (q *OCRQueue) run(ctx context.Context, job OCRJob) {
+	imagePath, err := q.store.ImagePath(job.ImageID)
+	if err != nil {
+		q.fail(job, fmt.Errorf("failed to load image %d: %w", job.ImageID, err))
+		return
+	}
+
+	result, err := q.process(ctx, imagePath)
+	if ctx.Err() != nil {
+		// 服务关闭导致的中断不计为失败，任务保持 running，下次启动时由 ResetRunning 重新排队
+		slog.Info("OCR job interrupted by shutdown", "job_id", job.ID, "image_id", job.ImageID)
+		return
+	}
+	if recordErr := q.store.RecordAttempt(job, result, err); recordErr != nil {
+		slog.Error("Failed to record OCR attempt", "job_id", job.ID, "image_id", job.ImageID, "error", recordErr)
+	}
+	if err != nil {
+		q.fail(job, err)
+		return
+	}
+
+	// 结果写入失败时按失败处理，避免任务一直停留在 running
+	if err := q.store.Complete(job, result); err != nil {
+		q.fail(job, fmt.Errorf("failed to store OCR result: %w", err))
+		return
+	}
+	slog.Info("OCR job completed", "job_id", job.ID, "image_id", job.ImageID, "provider", result.Provider,
+		"status", result.Status, "confidence", result.Confidence, "latency", result.Latency)
+}
+
+func (q *OCRQueue) fail(job OCRJob, cause error) {
+	slog.Warn("OCR job failed", "job_id", job.ID, "image_id", job.ImageID,
+		"attempt", job.Attempts, "max_attempts", q.maxAttempts, "error", cause)
+
+	var retryAt *time.Time
+	if job.Attempts < q.maxAttempts {
+		next := time.Now().Add(q.retryDelay << (job.Attempts - 1))
+		retryAt = &next
+	}
+	if err := q.store.Fail(job, cause.Error(), retryAt); err != nil {
+		slog.Error("Failed to update OCR job", "job_id", job.ID, "image_id", job.ImageID, "error", err)
+	}
+}
+
+// mysqlOCRJobStore 使用 ocr_jobs 表保存任务状态
+type mysqlOCRJobStore struct {
+	db *sql.DB
+}
+
+func newMySQLOCRJobStore(db *sql.DB) *mysqlOCRJobStore {
+	return &mysqlOCRJobStore{db: db}
+}
+
+// Enqueue 仅在图片没有未完成任务时插入，并发插入由 active_image_id 唯一键兜底
+func (s *mysqlOCRJobStore) Enqueue(imageID int) (int64, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO ocr_jobs (image_id, status)
+		SELECT ?, ? FROM DUAL
+		WHERE NOT EXISTS (
+			SELECT 1 FROM ocr_jobs WHERE image_id = ? AND status IN (?, ?)
+		)
+	`, imageID, OCRJobPending, imageID, OCRJobPending, OCRJobRunning)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return 0, errOCRJobActive
+		}
+		return 0, err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return 0, errOCRJobActive
+	}
+	return result.LastInsertId()
+}
+
+func (s *mysqlOCRJobStore) ResetRunning() (int64, error) {
+	result, err := s.db.Exec("UPDATE ocr_jobs SET status = ? WHERE status = ?", OCRJobPending, OCRJobRunning)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *mysqlOCRJobStore) FetchPending(limit int) ([]OCRJob, error) {
+	rows, err := s.db.Query(`
+		SELECT id, image_id, status, attempts
+		FROM ocr_jobs
+		WHERE status = ? AND (next_run_at IS NULL OR next_run_at <= NOW())
+		ORDER BY id
+		LIMIT ?
+	`, OCRJobPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []OCRJob{}
+	for rows.Next() {
+		var job OCRJob
+		if err := rows.Scan(&job.ID, &job.ImageID, &job.Status, &job.Attempts); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *mysqlOCRJobStore) Claim(jobID int64) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE ocr_jobs
+		SET status = ?, attempts = attempts + 1, started_at = NOW()
+		WHERE id = ? AND status = ?
+	`, OCRJobRunning, jobID, OCRJobPending)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (s *mysqlOCRJobStore) ImagePath(imageID int) (string, error) {
+	var path string
+	err := s.db.QueryRow("SELECT filepath FROM images WHERE id = ?", imageID).Scan(&path)
+	return path, err
+}
+
+func (s *mysqlOCRJobStore) Complete(job OCRJob, result *OCRResult) error {
+	// 未匹配到行政区划时清空拆分字段
+	parts := ParsedLocation{}
+	if result.LocationParts != nil && result.LocationParts.Matched {
+		parts = *result.LocationParts
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 保留被覆盖的旧结果，避免重新识别时静默丢失
+	if _, err := tx.Exec(`
+		INSERT INTO ocr_history (image_id, job_id, is_standard, ocr_status, ocr_time, ocr_datetime, ocr_location, ocr_confidence)
+		SELECT id, ?, is_standard, ocr_status, ocr_time, ocr_datetime, ocr_location, ocr_confidence
+		FROM images
+		WHERE id = ? AND is_standard IS NOT NULL
+	`, job.ID, job.ImageID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE images SET is_standard = ?, ocr_status = ?, ocr_time = ?, ocr_datetime = ?, ocr_location = ?, ocr_confidence = ?,
+		       location_province = ?, location_city = ?, location_county = ?, location_town = ?, location_detail = ?
+		WHERE id = ?
+	`, result.IsStandard, nullString(result.Status), nullString(result.Time), nullTime(result.DateTime),
+		nullString(result.Location), nullConfidence(result), nullString(parts.Province), nullString(parts.City),
+		nullString(parts.County), nullString(parts.Town), nullString(parts.Detail), job.ImageID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE ocr_jobs SET status = ?, last_error = NULL, finished_at = NOW()
+		WHERE id = ?
+	`, OCRJobDone, job.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *mysqlOCRJobStore) Fail(job OCRJob, errMsg string, retryAt *time.Time) error {
+	if retryAt != nil {
+		_, err := s.db.Exec(`
+			UPDATE ocr_jobs SET status = ?, last_error = ?, next_run_at = ?
+			WHERE id = ?
+		`, OCRJobPending, errMsg, *retryAt, job.ID)
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE ocr_jobs SET status = ?, last_error = ?, finished_at = NOW()
+		WHERE id = ?
+	`, OCRJobFailed, errMsg, job.ID); err != nil {
+		return err
+	}
+
+	// 首次识别失败时标记为非标准图片，并用 EXIF 拍摄时间预填 ocr_time；重新识别失败则保留原有结果
+	if _, err := tx.Exec(`
+		UPDATE images
+		SET is_standard = FALSE, ocr_status = ?,
+		    ocr_time = COALESCE(ocr_time, DATE_FORMAT(exif_time, '%Y-%m-%d %H:%i:%s')),
+		    ocr_datetime = COALESCE(ocr_datetime, exif_time)
+		WHERE id = ? AND is_standard IS NULL
+	`, OCRStatusNonStandard, job.ImageID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// initOCRQueue 创建全局任务队列并启动工作池
+func initOCRQueue(ctx context.Context) {
+	ocrQueue = NewOCRQueue(newMySQLOCRJobStore(db),
+		getEnvInt("OCR_WORKERS", defaultOCRWorkers),
+		getEnvInt("OCR_MAX_ATTEMPTS", defaultOCRMaxAttempts))
+	ocrQueue.Start(ctx)
+	slog.Info("OCR queue started", "workers", ocrQueue.workers)
+}
+
+func getOCRJobs(w http.ResponseWriter, r *http.Request) {
+	query := `
+		SELECT id, image_id, status, attempts, last_error, created_at, updated_at
+		FROM ocr_jobs
+		WHERE 1 = 1
+	`
+	args := []interface{}{}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	if imageIDStr := r.URL.Query().Get("image_id"); imageIDStr != "" {
+		imageID, err := strconv.Atoi(imageIDStr)
+		if err != nil {
+			http.Error(w, "Invalid image ID", http.StatusBadRequest)
+			return
+		}
+		query += " AND image_id = ?"
+		args = append(args, imageID)
+	}
+	query += " ORDER BY id DESC LIMIT 500"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	jobs := []OCRJob{}
+	for rows.Next() {
+		var job OCRJob
+		var lastError sql.NullString
+		if err := rows.Scan(&job.ID, &job.ImageID, &job.Status, &job.Attempts, &lastError,
+			&job.CreatedAt, &job.UpdatedAt); err != nil {
+			continue
+		}
+		job.LastError = lastError.String
+		jobs = append(jobs, job)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryOCRJobStore 是 ocrJobStore 的内存实现，仅用于测试
+type memoryOCRJobStore struct {
+	mu      sync.Mutex
+	nextID  int64
+	jobs    map[int64]*OCRJob
+	retryAt map[int64]time.Time
+	results map[int]*OCRResult
+	records []*OCRAttempt
+	// completeErr 不为 nil 时 Complete 返回该错误
+	completeErr error
+}
+
+func newMemoryOCRJobStore() *memoryOCRJobStore {
+	return &memoryOCRJobStore{
+		jobs:    map[int64]*OCRJob{},
+		retryAt: map[int64]time.Time{},
+		results: map[int]*OCRResult{},
+	}
+}
+
+func (s *memoryOCRJobStore) Enqueue(imageID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if job.ImageID == imageID && (job.Status == OCRJobPending || job.Status == OCRJobRunning) {
+			return 0, errOCRJobActive
+		}
+	}
+	s.nextID++
+	s.jobs[s.nextID] = &OCRJob{ID: s.nextID, ImageID: imageID, Status: OCRJobPending}
+	return s.nextID, nil
+}
+
+func (s *memoryOCRJobStore) ResetRunning() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, job := range s.jobs {
+		if job.Status == OCRJobRunning {
+			job.Status = OCRJobPending
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryOCRJobStore) FetchPending(limit int) ([]OCRJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := []OCRJob{}
+	for id := int64(1); id <= s.nextID && len(jobs) < limit; id++ {
+		job, ok := s.jobs[id]
+		if !ok || job.Status != OCRJobPending {
+			continue
+		}
+		if at, ok := s.retryAt[id]; ok && at.After(time.Now()) {
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+func (s *memoryOCRJobStore) Claim(jobID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[jobID]
+	if job == nil || job.Status != OCRJobPending {
+		return false, nil
+	}
+	job.Status = OCRJobRunning
+	job.Attempts++
+	return true, nil
+}
+
+func (s *memoryOCRJobStore) ImagePath(imageID int) (string, error) {
+	return fmt.Sprintf("image-%d.jpg", imageID), nil
+}
+
+func (s *memoryOCRJobStore) Complete(job OCRJob, result *OCRResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.completeErr != nil {
+		return s.completeErr
+	}
+	s.jobs[job.ID].Status = OCRJobDone
+	s.results[job.ImageID] = result
+	return nil
+}
+
+func (s *memoryOCRJobStore) Fail(job OCRJob, errMsg string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.jobs[job.ID]
+	stored.LastError = errMsg
+	if retryAt != nil {
+		stored.Status = OCRJobPending
+		s.retryAt[job.ID] = *retryAt
+		return nil
+	}
+	stored.Status = OCRJobFailed
+	return nil
+}
+
+func (s *memoryOCRJobStore) RecordAttempt(job OCRJob, result *OCRResult, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if attempt := newOCRAttempt(job, result, cause); attempt != nil {
+		s.records = append(s.records, attempt)
+	}
+	return nil
+}
+
+func (s *memoryOCRJobStore) snapshot(id int64) OCRJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.jobs[id]
+}
+
+func waitForJobs(t *testing.T, store *memoryOCRJobStore, ids []int64, done func(OCRJob) bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		finished := true
+		for _, id := range ids {
+			if !done(store.snapshot(id)) {
+				finished = false
+				break
+			}
+		}
+		if finished {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for OCR jobs")
+}
+
+func TestOCRQueueProcessesJobsWithBoundedWorkers(t *testing.T) {
+	store := newMemoryOCRJobStore()
+	queue := NewOCRQueue(store, 2, 1)
+
+	var running, peak int32
+	queue.process = func(ctx context.Context, imagePath string) (*OCRResult, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return &OCRResult{Time: "2024-01-15 14:30:00", Location: imagePath, IsStandard: true}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue.Start(ctx)
+
+	ids := []int64{}
+	for i := 1; i <= 6; i++ {
+		id, err := queue.Enqueue(i)
+		if err != nil {
+			t.Fatalf("Enqueue returned error: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	waitForJobs(t, store, ids, func(job OCRJob) bool { return job.Status == OCRJobDone })
+	cancel()
+	queue.Wait()
+
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent jobs, got %d", peak)
+	}
+	if got := store.results[3].Location; got != "image-3.jpg" {
+		t.Errorf("unexpected result for image 3: %q", got)
+	}
+}
+
+func TestOCRQueueRetriesThenFails(t *testing.T) {
+	store := newMemoryOCRJobStore()
+	queue := NewOCRQueue(store, 1, 2)
+	queue.retryDelay = time.Millisecond
+	queue.pollInterval = 5 * time.Millisecond
+
+	var calls int32
+	queue.process = func(context.Context, string) (*OCRResult, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("upstream unavailable")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue.Start(ctx)
+
+	id, _ := queue.Enqueue(1)
+	waitForJobs(t, store, []int64{id}, func(job OCRJob) bool { return job.Status == OCRJobFailed })
+	cancel()
+	queue.Wait()
+
+	job := store.snapshot(id)
+	if job.Attempts != 2 || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("expected 2 attempts, got %d (calls %d)", job.Attempts, calls)
+	}
+	if job.LastError != "upstream unavailable" {
+		t.Errorf("unexpected last error: %q", job.LastError)
+	}
+	if len(store.records) != 2 || store.records[1].Error != "upstream unavailable" {
+		t.Errorf("expected every failed attempt to be recorded, got %+v", store.records)
+	}
+}
+
+func TestOCRQueueResumesInterruptedJobs(t *testing.T) {
+	store := newMemoryOCRJobStore()
+	id, _ := store.Enqueue(7)
+	store.jobs[id].Status = OCRJobRunning
+
+	queue := NewOCRQueue(store, 1, 1)
+	queue.process = func(context.Context, string) (*OCRResult, error) {
+		return &OCRResult{}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue.Start(ctx)
+	waitForJobs(t, store, []int64{id}, func(job OCRJob) bool { return job.Status == OCRJobDone })
+	cancel()
+	queue.Wait()
+}
+
+func TestOCRQueueEnqueueRejectsActiveImage(t *testing.T) {
+	store := newMemoryOCRJobStore()
+	queue := NewOCRQueue(store, 1, 1)
+
+	id, err := queue.Enqueue(5)
+	if err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+	if _, err := queue.Enqueue(5); !errors.Is(err, errOCRJobActive) {
+		t.Fatalf("expected errOCRJobActive for a pending image, got %v", err)
+	}
+
+	store.jobs[id].Status = OCRJobDone
+	if _, err := queue.Enqueue(5); err != nil {
+		t.Fatalf("expected a finished image to be re-queued, got %v", err)
+	}
+}
+
+func TestOCRQueueFailsWhenResultCannotBeStored(t *testing.T) {
+	store := newMemoryOCRJobStore()
+	store.completeErr = errors.New("Out of range value for column 'ocr_confidence'")
+	queue := NewOCRQueue(store, 1, 1)
+	queue.process = func(context.Context, string) (*OCRResult, error) {
+		return &OCRResult{}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queue.Start(ctx)
+	id, _ := queue.Enqueue(3)
+	waitForJobs(t, store, []int64{id}, func(job OCRJob) bool { return job.Status == OCRJobFailed })
+	cancel()
+	queue.Wait()
+
+	if job := store.snapshot(id); !strings.Contains(job.LastError, "failed to store OCR result") {
+		t.Errorf("unexpected last error: %q", job.LastError)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const maxRerunImages = 1000
+
+// OCRRerunFilter 描述批量重新识别时的筛选条件，未设置的字段不参与筛选
+type OCRRerunFilter struct {
+	IsStandard   *bool  `json:"is_standard,omitempty"`
+	OCRStatus    string `json:"ocr_status,omitempty"`
+	Annotated    *bool  `json:"annotated,omitempty"`
+	OCRTimeNull  bool   `json:"ocr_time_null,omitempty"`
+	Unprocessed  bool   `json:"unprocessed,omitempty"` // is_standard IS NULL
+	UploadedFrom string `json:"uploaded_from,omitempty"`
+	UploadedTo   string `json:"uploaded_to,omitempty"`
+	ImageIDs     []int  `json:"image_ids,omitempty"`
+	Limit        int    `json:"limit,omitempty"`
+}
+
+// OCRRerunResponse 为批量重新识别的返回结果
+type OCRRerunResponse struct {
+	Matched int     `json:"matched"`
+	JobIDs  []int64 `json:"job_ids"`
+}
+
+// OCRHistoryEntry 为被新结果替换前的 OCR 值
+type OCRHistoryEntry struct {
+	ID          int64      `json:"id"`
+	ImageID     int        `json:"image_id"`
+	JobID       *int64     `json:"job_id,omitempty"`
+	IsStandard  *bool      `json:"is_standard,omitempty"`
+	OCRStatus   string     `json:"ocr_status,omitempty"`
+	OCRTime     string     `json:"ocr_time,omitempty"`
+	OCRDateTime *time.Time `json:"ocr_datetime,omitempty"`
+	OCRLocation string     `json:"ocr_location,omitempty"`
+	Confidence  *float64   `json:"ocr_confidence,omitempty"`
+	ReplacedAt  time.Time  `json:"replaced_at"`
+}
+
+// parseFilterTime 支持 YYYY-MM-DD、YYYY-MM-DD HH:MM:SS 与 RFC3339
+func parseFilterTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// errEmptyRerunFilter 表示批量重新识别未给出任何筛选条件
+var errEmptyRerunFilter = errors.New("at least one filter is required")
+
+// isEmpty 判断是否未设置任何筛选条件，Limit 不算筛选条件
+func (f OCRRerunFilter) isEmpty() bool {
+	return f.IsStandard == nil && f.OCRStatus == "" && f.Annotated == nil && !f.OCRTimeNull &&
+		!f.Unprocessed && f.UploadedFrom == "" && f.UploadedTo == "" && len(f.ImageIDs) == 0
+}
+
+// buildRerunQuery 根据筛选条件生成查询 SQL，自动排除已有待处理任务的图片；
+// 未显式指定 annotated 时只选择未标注的图片，避免覆盖已人工确认的数据
+func buildRerunQuery(filter OCRRerunFilter) (string, []interface{}, error) {
+	if filter.isEmpty() {
+		return "", nil, errEmptyRerunFilter
+	}
+
+	query := `
+		SELECT i.id
+		FROM images i
+		WHERE NOT EXISTS (
+			SELECT 1 FROM ocr_jobs j
+			WHERE j.image_id = i.id AND j.status IN (?, ?)
+		)`
+	args := []interface{}{OCRJobPending, OCRJobRunning}
+
+	if filter.IsStandard != nil {
+		query += " AND i.is_standard = ?"
+		args = append(args, *filter.IsStandard)
+	}
+	if filter.OCRStatus != "" {
+		if !isValidOCRStatus(filter.OCRStatus) {
+			return "", nil, fmt.Errorf("invalid ocr_status %q", filter.OCRStatus)
+		}
+		query += " AND i.ocr_status = ?"
+		args = append(args, filter.OCRStatus)
+	}
+	if filter.Unprocessed {
+		query += " AND i.is_standard IS NULL"
+	}
+	if filter.Annotated != nil {
+		query += " AND i.annotated = ?"
+		args = append(args, *filter.Annotated)
+	} else {
+		query += " AND (i.annotated IS NULL OR i.annotated = FALSE)"
+	}
+	if filter.OCRTimeNull {
+		query += " AND i.ocr_time IS NULL"
+	}
+	if filter.UploadedFrom != "" {
+		from, err := parseFilterTime(filter.UploadedFrom)
+		if err != nil {
+			return "", nil, err
+		}
+		query += " AND i.uploaded_at >= ?"
+		args = append(args, from)
+	}
+	if filter.UploadedTo != "" {
+		to, err := parseFilterTime(filter.UploadedTo)
+		if err != nil {
+			return "", nil, err
+		}
+		// 仅有日期时包含当天
+		if !strings.Contains(filter.UploadedTo, ":") {
+			to = to.AddDate(0, 0, 1)
+		}
+		query += " AND i.uploaded_at < ?"
+		args = append(args, to)
+	}
+	if len(filter.ImageIDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filter.ImageIDs)), ", ")
+		query += " AND i.id IN (" + placeholders + ")"
+		for _, id := range filter.ImageIDs {
+			args = append(args, id)
+		}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > maxRerunImages {
+		limit = maxRerunImages
+	}
+	query += " ORDER BY i.id LIMIT ?"
+	args = append(args, limit)
+
+	return query, args, nil
+}
+
+// rerunImageOCR 重新为单张图片投递 OCR 任务
+func rerunImageOCR(w http.ResponseWriter, r *http.Request) {
+	imageID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		return
+	}
+
+	var exists int
+	if err := db.QueryRow("SELECT COUNT(*) FROM images WHERE id = ?", imageID).Scan(&exists); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if exists == 0 {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+
+	jobID, err := ocrQueue.Enqueue(imageID)
+	if errors.Is(err, errOCRJobActive) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(OCRJob{ID: jobID, ImageID: imageID, Status: OCRJobPending})
+}
+
+// rerunOCR 按筛选条件批量重新投递 OCR 任务
+func rerunOCR(w http.ResponseWriter, r *http.Request) {
+	var filter OCRRerunFilter
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	query, args, err := buildRerunQuery(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	imageIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			continue
+		}
+		imageIDs = append(imageIDs, id)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := OCRRerunResponse{Matched: len(imageIDs), JobIDs: []int64{}}
+	for _, id := range imageIDs {
+		jobID, err := ocrQueue.Enqueue(id)
+		if errors.Is(err, errOCRJobActive) {
+			// 查询之后被其他请求抢先投递
+			continue
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response.JobIDs = append(response.JobIDs, jobID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// getOCRHistory 返回图片历次被替换的 OCR 结果
+func getOCRHistory(w http.ResponseWriter, r *http.Request) {
+	imageID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, image_id, job_id, is_standard, ocr_status, ocr_time, ocr_datetime, ocr_location, ocr_confidence, replaced_at
+		FROM ocr_history
+		WHERE image_id = ?
+		ORDER BY id DESC
+	`, imageID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := []OCRHistoryEntry{}
+	for rows.Next() {
+		var entry OCRHistoryEntry
+		var jobID sql.NullInt64
+		var isStandard sql.NullBool
+		var ocrStatus, ocrTime, ocrLocation sql.NullString
+		var confidence sql.NullFloat64
+		var ocrDateTime sql.NullTime
+		if err := rows.Scan(&entry.ID, &entry.ImageID, &jobID, &isStandard, &ocrStatus, &ocrTime, &ocrDateTime,
+			&ocrLocation, &confidence, &entry.ReplacedAt); err != nil {
+			continue
+		}
+		if jobID.Valid {
+			entry.JobID = &jobID.Int64
+		}
+		if isStandard.Valid {
+			entry.IsStandard = &isStandard.Bool
+		}
+		if confidence.Valid {
+			entry.Confidence = &confidence.Float64
+		}
+		if ocrDateTime.Valid {
+			entry.OCRDateTime = &ocrDateTime.Time
+		}
+		entry.OCRStatus = ocrStatus.String
+		entry.OCRTime = ocrTime.String
+		entry.OCRLocation = ocrLocation.String
+		history = append(history, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
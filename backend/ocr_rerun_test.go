@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildRerunQuery(t *testing.T) {
+	t.Run("empty filter", func(t *testing.T) {
+		if _, _, err := buildRerunQuery(OCRRerunFilter{Limit: 10}); !errors.Is(err, errEmptyRerunFilter) {
+			t.Fatalf("expected errEmptyRerunFilter, got %v", err)
+		}
+	})
+
+	t.Run("excludes annotated by default", func(t *testing.T) {
+		query, args, err := buildRerunQuery(OCRRerunFilter{OCRStatus: OCRStatusUncertain})
+		if err != nil {
+			t.Fatalf("buildRerunQuery returned error: %v", err)
+		}
+		if !strings.Contains(query, "NOT EXISTS") {
+			t.Errorf("expected active jobs to be excluded: %s", query)
+		}
+		if !strings.Contains(query, "i.annotated IS NULL OR i.annotated = FALSE") {
+			t.Errorf("expected annotated images to be excluded: %s", query)
+		}
+		if len(args) != 4 || args[3] != maxRerunImages {
+			t.Errorf("unexpected args: %v", args)
+		}
+
+		annotated := true
+		query, _, err = buildRerunQuery(OCRRerunFilter{Annotated: &annotated})
+		if err != nil {
+			t.Fatalf("buildRerunQuery returned error: %v", err)
+		}
+		if strings.Contains(query, "i.annotated IS NULL") || !strings.Contains(query, "i.annotated = ?") {
+			t.Errorf("explicit annotated filter should override the default: %s", query)
+		}
+	})
+
+	t.Run("combined filter", func(t *testing.T) {
+		isStandard := false
+		query, args, err := buildRerunQuery(OCRRerunFilter{
+			IsStandard:   &isStandard,
+			OCRTimeNull:  true,
+			UploadedFrom: "2024-01-01",
+			UploadedTo:   "2024-01-31",
+			ImageIDs:     []int{3, 5},
+			Limit:        10,
+		})
+		if err != nil {
+			t.Fatalf("buildRerunQuery returned error: %v", err)
+		}
+		for _, fragment := range []string{
+			"i.is_standard = ?", "i.ocr_time IS NULL", "i.uploaded_at >= ?",
+			"i.uploaded_at < ?", "i.id IN (?, ?)",
+		} {
+			if !strings.Contains(query, fragment) {
+				t.Errorf("query missing %q: %s", fragment, query)
+			}
+		}
+		if got := strings.Count(query, "?"); got != len(args) {
+			t.Fatalf("placeholder count %d != args %d", got, len(args))
+		}
+		to := args[4].(time.Time)
+		if to.Format("2006-01-02") != "2024-02-01" {
+			t.Errorf("date-only upper bound should include the whole day, got %v", to)
+		}
+		if args[len(args)-1] != 10 {
+			t.Errorf("unexpected limit: %v", args[len(args)-1])
+		}
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		if _, _, err := buildRerunQuery(OCRRerunFilter{UploadedFrom: "yesterday"}); err == nil {
+			t.Fatalf("expected error for invalid date")
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// OCRAttempt 对应 ocr_results 表，记录每次 VLM 调用的完整输出
+type OCRAttempt struct {
+	ID                 int64     `json:"id"`
+	ImageID            int       `json:"image_id"`
+	JobID              *int64    `json:"job_id,omitempty"`
+	Provider           string    `json:"provider"`
+	Model              string    `json:"model,omitempty"`
+	Thinking           bool      `json:"thinking"`
+	Confidence         *float64  `json:"confidence,omitempty"`
+	TimeConfidence     *float64  `json:"time_confidence,omitempty"`
+	LocationConfidence *float64  `json:"location_confidence,omitempty"`
+	Status             string    `json:"status,omitempty"`
+	Notes              string    `json:"notes,omitempty"`
+	OCRTime            string    `json:"ocr_time,omitempty"`
+	OCRLocation        string    `json:"ocr_location,omitempty"`
+	LatencyMS          int64     `json:"latency_ms"`
+	PromptTokens       int       `json:"prompt_tokens"`
+	CompletionTokens   int       `json:"completion_tokens"`
+	TotalTokens        int       `json:"total_tokens"`
+	RawResponse        string    `json:"raw_response,omitempty"`
+	Error              string    `json:"error,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// nullConfidence 未调用模型时置信度写入 NULL
+func nullConfidence(result *OCRResult) interface{} {
+	if result == nil || result.Provider == "" {
+		return nil
+	}
+	return result.Confidence
+}
+
+// newOCRAttempt 根据识别结果或失败原因构造一条调用记录，未调用模型时返回 nil
+func newOCRAttempt(job OCRJob, result *OCRResult, cause error) *OCRAttempt {
+	jobID := job.ID
+	attempt := &OCRAttempt{ImageID: job.ImageID, JobID: &jobID}
+
+	if cause != nil {
+		attempt.Error = cause.Error()
+		var attemptErr *OCRAttemptError
+		if errors.As(cause, &attemptErr) {
+			attempt.Provider = attemptErr.Provider
+			attempt.Model = attemptErr.Model
+			attempt.LatencyMS = attemptErr.Latency.Milliseconds()
+			attempt.PromptTokens = attemptErr.Usage.PromptTokens
+			attempt.CompletionTokens = attemptErr.Usage.CompletionTokens
+			attempt.TotalTokens = attemptErr.Usage.TotalTokens
+			attempt.RawResponse = attemptErr.RawResponse
+		}
+		return attempt
+	}
+
+	if result == nil || result.Provider == "" {
+		return nil
+	}
+
+	confidence := result.Confidence
+	attempt.Provider = result.Provider
+	attempt.Model = result.Model
+	attempt.Thinking = result.Thinking
+	attempt.Confidence = &confidence
+	attempt.TimeConfidence = result.TimeConfidence
+	attempt.LocationConfidence = result.LocationConfidence
+	attempt.Status = result.Status
+	attempt.Notes = result.Notes
+	attempt.OCRTime = result.Time
+	attempt.OCRLocation = result.Location
+	attempt.LatencyMS = result.Latency.Milliseconds()
+	attempt.PromptTokens = result.Usage.PromptTokens
+	attempt.CompletionTokens = result.Usage.CompletionTokens
+	attempt.TotalTokens = result.Usage.TotalTokens
+	attempt.RawResponse = result.RawResponse
+	return attempt
+}
+
+func (s *mysqlOCRJobStore) RecordAttempt(job OCRJob, result *OCRResult, cause error) error {
+	attempt := newOCRAttempt(job, result, cause)
+	if attempt == nil {
+		return nil
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO ocr_results (image_id, job_id, provider, model, thinking, confidence,
+		                         time_confidence, location_confidence, status, notes,
+		                         ocr_time, ocr_location, latency_ms, prompt_tokens, completion_tokens,
+		                         total_tokens, raw_response, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, attempt.ImageID, *attempt.JobID, nullString(attempt.Provider), nullString(attempt.Model),
+		attempt.Thinking, nullFloat(attempt.Confidence), nullFloat(attempt.TimeConfidence),
+		nullFloat(attempt.LocationConfidence), nullString(attempt.Status), nullString(attempt.Notes),
+		nullString(attempt.OCRTime), nullString(attempt.OCRLocation), attempt.LatencyMS,
+		attempt.PromptTokens, attempt.CompletionTokens, attempt.TotalTokens,
+		nullString(attempt.RawResponse), nullString(attempt.Error))
+	return err
+}
+
+// nullTime 将 nil 指针转换为NULL值
+func nullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// nullFloat 将 nil 指针转换为NULL值
+func nullFloat(f *float64) interface{} {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
+
+// loadOCRAttempts 按时间倒序返回图片的全部 VLM 调用记录
+func loadOCRAttempts(imageID int) ([]OCRAttempt, error) {
+	rows, err := db.Query(`
+		SELECT id, image_id, job_id, provider, model, thinking, confidence, time_confidence,
+		       location_confidence, status, notes, ocr_time,
+		       ocr_location, latency_ms, prompt_tokens, completion_tokens, total_tokens,
+		       raw_response, error, created_at
+		FROM ocr_results
+		WHERE image_id = ?
+		ORDER BY id DESC
+	`, imageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []OCRAttempt{}
+	for rows.Next() {
+		var attempt OCRAttempt
+		var jobID sql.NullInt64
+		var confidence, timeConfidence, locationConfidence sql.NullFloat64
+		var provider, model, status, notes, ocrTime, ocrLocation, raw, errMsg sql.NullString
+		if err := rows.Scan(&attempt.ID, &attempt.ImageID, &jobID, &provider, &model, &attempt.Thinking,
+			&confidence, &timeConfidence, &locationConfidence, &status, &notes, &ocrTime, &ocrLocation, &attempt.LatencyMS, &attempt.PromptTokens,
+			&attempt.CompletionTokens, &attempt.TotalTokens, &raw, &errMsg, &attempt.CreatedAt); err != nil {
+			return nil, err
+		}
+		if jobID.Valid {
+			attempt.JobID = &jobID.Int64
+		}
+		if confidence.Valid {
+			attempt.Confidence = &confidence.Float64
+		}
+		if timeConfidence.Valid {
+			attempt.TimeConfidence = &timeConfidence.Float64
+		}
+		if locationConfidence.Valid {
+			attempt.LocationConfidence = &locationConfidence.Float64
+		}
+		attempt.Status = status.String
+		attempt.Provider = provider.String
+		attempt.Model = model.String
+		attempt.Notes = notes.String
+		attempt.OCRTime = ocrTime.String
+		attempt.OCRLocation = ocrLocation.String
+		attempt.RawResponse = raw.String
+		attempt.Error = errMsg.String
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
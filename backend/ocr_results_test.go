@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewOCRAttempt(t *testing.T) {
+	job := OCRJob{ID: 9, ImageID: 4}
+
+	t.Run("successful extraction", func(t *testing.T) {
+		attempt := newOCRAttempt(job, &OCRResult{
+			Time:        "2024-01-15 14:30:00",
+			Location:    "羊尖镇",
+			Confidence:  0.42,
+			Notes:       "水印模糊",
+			Provider:    "qwen",
+			Model:       "qwen3-vl-plus",
+			Thinking:    true,
+			Latency:     1500 * time.Millisecond,
+			Usage:       vlmUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+			RawResponse: `{"choices":[]}`,
+		}, nil)
+		if attempt == nil {
+			t.Fatalf("expected attempt record")
+		}
+		if *attempt.JobID != 9 || attempt.ImageID != 4 {
+			t.Errorf("unexpected ids: %+v", attempt)
+		}
+		if attempt.Confidence == nil || *attempt.Confidence != 0.42 || attempt.Notes != "水印模糊" {
+			t.Errorf("confidence/notes not kept: %+v", attempt)
+		}
+		if attempt.LatencyMS != 1500 || attempt.TotalTokens != 120 || !attempt.Thinking {
+			t.Errorf("unexpected metadata: %+v", attempt)
+		}
+	})
+
+	t.Run("failed extraction", func(t *testing.T) {
+		cause := &OCRAttemptError{Provider: "openai", Latency: time.Second, Err: errors.New("timeout")}
+		attempt := newOCRAttempt(job, nil, cause)
+		if attempt.Provider != "openai" || attempt.LatencyMS != 1000 || attempt.Error != "timeout" {
+			t.Errorf("unexpected failure record: %+v", attempt)
+		}
+		if attempt.Confidence != nil {
+			t.Errorf("failed attempt should not carry confidence")
+		}
+	})
+
+	t.Run("extractor not configured", func(t *testing.T) {
+		if attempt := newOCRAttempt(job, &OCRResult{}, nil); attempt != nil {
+			t.Errorf("expected no record when no model was called, got %+v", attempt)
+		}
+	})
+}
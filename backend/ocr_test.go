@@ -4,7 +4,7 @@ import (
 	"testing"
 )
 
-func TestNormalizeTime(t *testing.T) {
+func TestSetTime(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
@@ -60,13 +60,27 @@ func TestNormalizeTime(t *testing.T) {
 			input:    "2023/7/9 9:30",
 			expected: "2023-07-09 09:30:00",
 		},
+		{
+			name:     "Afternoon without date keeps raw text",
+			input:    "下午3点",
+			expected: "下午3点",
+		},
+		{
+			name:     "Out of range values keep raw text",
+			input:    "2023-13-45 25:99",
+			expected: "2023-13-45 25:99",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := normalizeTime(tt.input)
-			if result != tt.expected {
-				t.Errorf("normalizeTime(%q) = %q, want %q", tt.input, result, tt.expected)
+			var result OCRResult
+			err := result.setTime(tt.input)
+			if result.Time != tt.expected {
+				t.Errorf("setTime(%q) stored %q, want %q", tt.input, result.Time, tt.expected)
+			}
+			if parsed := err == nil; parsed != (result.DateTime != nil) {
+				t.Errorf("setTime(%q) returned %v with DateTime %v", tt.input, err, result.DateTime)
 			}
 		})
 	}
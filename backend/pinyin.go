@@ -0,0 +1,194 @@
+package main
+
+import "strings"
+
+// pinyinSyllables 为站点名、无锡地名及其常见同音误识字的无声调拼音，
+// 未收录的字在比较时按原字处理
+const pinyinSyllables = `a:阿
+an:安岸庵
+ba:八坝巴
+bai:白百柏
+bang:浜邦
+bao:保宝堡
+bei:北贝
+ben:本
+bin:滨宾
+cai:菜蔡
+cha:茶查
+chan:产禅
+chang:长厂场常昌
+chao:潮巢
+che:车
+chen:陈辰
+cheng:城澄堘塍成程承
+chong:崇冲
+chun:春
+cun:村存
+da:大达
+dai:代埭戴
+dang:荡当
+dao:道岛稻
+de:德
+di:地堤
+ding:丁定顶
+dong:东冬董洞
+du:渎杜都
+duan:段
+dun:墩
+e:鹅娥
+er:儿二
+fang:房放芳方坊
+feng:锋丰峰枫凤
+fu:伏父福辅㳇富
+gang:港岗钢
+gao:高
+ge:滆葛
+geng:更
+gong:公宫工
+gu:古顾谷
+guan:官观
+guang:广光
+guo:国郭
+hai:海
+he:合和河荷
+heng:横
+hong:红鸿洪
+hou:厚后
+hu:胡湖虎
+hua:华花
+huan:环
+huang:璜黄皇
+hui:惠挥会汇
+ji:基急吉
+jia:家嘉
+jian:尖建坚间
+jiang:江蒋
+jie:街
+jin:金锦
+jing:泾精经京景
+jiu:九
+ju:具
+ka:卡
+kai:开
+ke:客
+ku:库
+kuang:匡
+kui:匮
+lan:兰蓝
+lang:浪
+li:利蠡里李
+lian:莲连
+liang:辆梁良
+lie:埒
+lin:林临
+ling:岭陵
+long:龙
+lu:陆路鹿
+luo:洛罗
+ma:马
+mei:梅
+mi:蜜
+ming:铭名明
+nan:南
+ni:坭泥
+nong:农
+pi:匹
+ping:平
+pu:圃浦
+qi:七屺岐祁
+qian:乾前钱
+qiao:峭桥乔
+qing:清青
+qu:区屈
+quan:泉
+ren:仁
+rong:荣
+rui:瑞
+shan:善山
+shang:上尚
+she:社舍
+shen:申
+sheng:生省
+shi:士市时湿石
+shou:寿
+shu:蔬蜀书
+shuang:双
+shui:水
+shuo:硕
+si:寺
+su:苏
+tai:台太态
+tang:塘唐堂
+tao:桃陶
+te:特
+tian:田天
+tie:铁
+ting:亭停庭
+tong:桐通
+tou:头
+tu:土
+wan:万湾
+wang:汪旺王
+wei:微
+wen:文
+wu:无吴五武
+xi:溪细西锡希
+xia:下夏霞
+xian:仙先现贤
+xiang:巷
+xiao:小校
+xin:心新鑫
+xing:兴幸星
+xu:徐
+xue:学雪
+yan:严堰晏
+yang:杨洋羊阳扬
+ye:业
+yi:宜益义
+yin:阴
+ying:应迎
+you:囿幼
+yu:域玉语
+yuan:原园院元源
+yue:月
+yun:云
+zha:闸
+zhan:站
+zhang:张漳
+zhen:镇
+zhi:指智
+zhong:中种
+zhou:周洲州
+zhu:渚祝竹朱
+zhuan:专
+zhuang:庄
+zuo:作`
+
+var pinyinTable = buildPinyinTable(pinyinSyllables)
+
+func buildPinyinTable(data string) map[rune]string {
+	table := map[rune]string{}
+	for _, line := range strings.Split(data, "\n") {
+		syllable, chars, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		for _, r := range chars {
+			table[r] = syllable
+		}
+	}
+	return table
+}
+
+// toPinyin 将文字转换为无声调拼音音节序列，ASCII 字母按原样转为小写
+func toPinyin(text string) []string {
+	syllables := []string{}
+	for _, r := range text {
+		if py, ok := pinyinTable[r]; ok {
+			syllables = append(syllables, py)
+		} else {
+			syllables = append(syllables, strings.ToLower(string(r)))
+		}
+	}
+	return syllables
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"log/slog"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultVLMImageMaxEdge      = 1600
+	defaultVLMImageQuality      = 85
+	defaultVLMImageCropFraction = 0.4
+
+	ImageCropNone    = "none"
+	ImageCropCorners = "corners" // 仅保留右上、右下两个水印角
+)
+
+// ImagePreprocessConfig 描述发送给 VLM 前的图片预处理，零值表示原样发送
+type ImagePreprocessConfig struct {
+	MaxEdge      int     // 长边上限（像素），0 表示不缩放
+	Quality      int     // 重新编码的 JPEG 质量
+	Crop         string  // none 或 corners
+	CropFraction float64 // corners 模式下每个角占原图宽高的比例
+}
+
+// loadImagePreprocessConfig 从环境变量读取预处理配置
+func loadImagePreprocessConfig() ImagePreprocessConfig {
+	return ImagePreprocessConfig{
+		MaxEdge:      getEnvInt("VLM_IMAGE_MAX_EDGE", defaultVLMImageMaxEdge),
+		Quality:      getEnvInt("VLM_IMAGE_QUALITY", defaultVLMImageQuality),
+		Crop:         strings.ToLower(getEnv("VLM_IMAGE_CROP", ImageCropNone)),
+		CropFraction: getEnvFloat("VLM_IMAGE_CROP_FRACTION", defaultVLMImageCropFraction),
+	}
+}
+
+func (c ImagePreprocessConfig) enabled() bool {
+	return c.MaxEdge > 0 || c.Crop == ImageCropCorners
+}
+
+// preprocessImage 读取图片并按配置裁剪、缩放、重新编码，返回发送用的数据与 MIME 类型。
+// 磁盘上的原图不会被修改；无法解码的格式原样返回。
+func preprocessImage(imagePath string, cfg ImagePreprocessConfig) ([]byte, string, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(imagePath)))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	if !cfg.enabled() {
+		return data, mimeType, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		slog.Warn("Skipping preprocessing", "path", imagePath, "error", err)
+		return data, mimeType, nil
+	}
+
+	changed := false
+	// 重新编码会丢失 EXIF，先按方向标签转正，否则裁剪会取到错误的角
+	if exif, err := parseEXIF(data); err == nil && exif.Orientation > 1 && exif.Orientation <= 8 {
+		img = applyOrientation(img, exif.Orientation)
+		changed = true
+	}
+	if cfg.Crop == ImageCropCorners {
+		img = cropWatermarkCorners(img, cfg.CropFraction)
+		changed = true
+	}
+	if cfg.MaxEdge > 0 {
+		bounds := img.Bounds()
+		if w, h := bounds.Dx(), bounds.Dy(); w > cfg.MaxEdge || h > cfg.MaxEdge {
+			nw, nh := fitWithin(w, h, cfg.MaxEdge)
+			img = resizeImage(img, nw, nh)
+			changed = true
+		}
+	}
+	if !changed {
+		return data, mimeType, nil
+	}
+
+	quality := cfg.Quality
+	if quality <= 0 || quality > 100 {
+		quality = defaultVLMImageQuality
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// fitWithin 等比缩放使长边不超过 maxEdge
+func fitWithin(w, h, maxEdge int) (int, int) {
+	if w >= h {
+		nh := h * maxEdge / w
+		if nh < 1 {
+			nh = 1
+		}
+		return maxEdge, nh
+	}
+	nw := w * maxEdge / h
+	if nw < 1 {
+		nw = 1
+	}
+	return nw, maxEdge
+}
+
+// applyOrientation 按 EXIF Orientation（2-8）翻转或旋转图片，使其以正常方向显示
+func applyOrientation(src image.Image, orientation int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	// at 返回输出像素 (x, y) 对应的原图坐标
+	var at func(x, y int) (int, int)
+	dw, dh := w, h
+	switch orientation {
+	case 2: // 水平翻转
+		at = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3: // 旋转 180°
+		at = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4: // 垂直翻转
+		at = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5: // 沿主对角线转置
+		at = func(x, y int) (int, int) { return y, x }
+		dw, dh = h, w
+	case 6: // 顺时针旋转 90°
+		at = func(x, y int) (int, int) { return y, h - 1 - x }
+		dw, dh = h, w
+	case 7: // 沿副对角线转置
+		at = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+		dw, dh = h, w
+	case 8: // 逆时针旋转 90°
+		at = func(x, y int) (int, int) { return w - 1 - y, x }
+		dw, dh = h, w
+	default:
+		return src
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := at(x, y)
+			so := sy*rgba.Stride + sx*4
+			do := y*dst.Stride + x*4
+			copy(dst.Pix[do:do+4], rgba.Pix[so:so+4])
+		}
+	}
+	return dst
+}
+
+// cropWatermarkCorners 截取右上与右下两个角并上下拼接
+func cropWatermarkCorners(src image.Image, fraction float64) image.Image {
+	if fraction <= 0 || fraction > 1 {
+		fraction = defaultVLMImageCropFraction
+	}
+	bounds := src.Bounds()
+	cw := int(float64(bounds.Dx()) * fraction)
+	ch := int(float64(bounds.Dy()) * fraction)
+	if cw < 1 || ch < 1 {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, cw, ch*2))
+	topRight := image.Pt(bounds.Max.X-cw, bounds.Min.Y)
+	bottomRight := image.Pt(bounds.Max.X-cw, bounds.Max.Y-ch)
+	draw.Draw(dst, image.Rect(0, 0, cw, ch), src, topRight, draw.Src)
+	draw.Draw(dst, image.Rect(0, ch, cw, ch*2), src, bottomRight, draw.Src)
+	return dst
+}
+
+// resizeImage 使用区域平均缩小图片，对水印文字比最近邻更清晰
+func resizeImage(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	} else if bounds.Min != (image.Point{}) {
+		rgba = rgba.SubImage(bounds).(*image.RGBA)
+	}
+
+	sw, sh := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := sy*rgba.Stride + x0*4
+				for sx := x0; sx < x1; sx++ {
+					p := rgba.Pix[offset : offset+4 : offset+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+					offset += 4
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestPNG 生成 w×h 的灰色图片，右上角为红色、右下角为蓝色
+func writeTestPNG(t *testing.T, w, h int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{128, 128, 128, 255}
+			if x >= w/2 && y < h/4 {
+				c = color.RGBA{255, 0, 0, 255}
+			} else if x >= w/2 && y >= h*3/4 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "sample.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write test image: %v", err)
+	}
+	return path
+}
+
+func TestPreprocessImage(t *testing.T) {
+	path := writeTestPNG(t, 400, 300)
+	original, _ := os.ReadFile(path)
+
+	tests := []struct {
+		name           string
+		cfg            ImagePreprocessConfig
+		mimeType       string
+		width, height  int
+		expectOriginal bool
+	}{
+		{"disabled", ImagePreprocessConfig{}, "image/png", 400, 300, true},
+		{"already small", ImagePreprocessConfig{MaxEdge: 800}, "image/png", 400, 300, true},
+		{"downscale", ImagePreprocessConfig{MaxEdge: 100}, "image/jpeg", 100, 75, false},
+		{"crop corners", ImagePreprocessConfig{Crop: ImageCropCorners, CropFraction: 0.25}, "image/jpeg", 100, 150, false},
+		{"crop then downscale", ImagePreprocessConfig{Crop: ImageCropCorners, CropFraction: 0.5, MaxEdge: 120}, "image/jpeg", 80, 120, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, mimeType, err := preprocessImage(path, tt.cfg)
+			if err != nil {
+				t.Fatalf("preprocessImage returned error: %v", err)
+			}
+			if mimeType != tt.mimeType {
+				t.Errorf("mime type = %q, want %q", mimeType, tt.mimeType)
+			}
+			if tt.expectOriginal != bytes.Equal(data, original) {
+				t.Errorf("expectOriginal=%v but output differs", tt.expectOriginal)
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("output is not a decodable image: %v", err)
+			}
+			if cfg.Width != tt.width || cfg.Height != tt.height {
+				t.Errorf("size = %dx%d, want %dx%d", cfg.Width, cfg.Height, tt.width, tt.height)
+			}
+		})
+	}
+
+	onDisk, _ := os.ReadFile(path)
+	if !bytes.Equal(onDisk, original) {
+		t.Errorf("original file was modified")
+	}
+}
+
+func TestCropWatermarkCornersKeepsBothCorners(t *testing.T) {
+	path := writeTestPNG(t, 200, 200)
+	data, _, err := preprocessImage(path, ImagePreprocessConfig{Crop: ImageCropCorners, CropFraction: 0.25, Quality: 100})
+	if err != nil {
+		t.Fatalf("preprocessImage returned error: %v", err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+
+	top := color.RGBAModel.Convert(img.At(25, 10)).(color.RGBA)
+	bottom := color.RGBAModel.Convert(img.At(25, 90)).(color.RGBA)
+	if top.R < 200 || top.B > 60 {
+		t.Errorf("expected top half to come from the top-right corner, got %v", top)
+	}
+	if bottom.B < 200 || bottom.R > 60 {
+		t.Errorf("expected bottom half to come from the bottom-right corner, got %v", bottom)
+	}
+}
+
+func TestPreprocessImageUndecodable(t *testing.T) {
+	path := writeTestImage(t)
+	data, mimeType, err := preprocessImage(path, ImagePreprocessConfig{MaxEdge: 10})
+	if err != nil {
+		t.Fatalf("preprocessImage returned error: %v", err)
+	}
+	if string(data) != "fake-image" || mimeType != "image/jpeg" {
+		t.Errorf("expected undecodable input to pass through, got %q (%s)", data, mimeType)
+	}
+}
+
+func TestResizeImageAveragesPixels(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.SetRGBA(0, 0, color.RGBA{0, 0, 0, 255})
+	src.SetRGBA(1, 0, color.RGBA{200, 0, 0, 255})
+	src.SetRGBA(0, 1, color.RGBA{0, 200, 0, 255})
+	src.SetRGBA(1, 1, color.RGBA{0, 0, 200, 255})
+
+	got := resizeImage(src, 1, 1).RGBAAt(0, 0)
+	want := color.RGBA{50, 50, 50, 255}
+	if got != want {
+		t.Errorf("resizeImage average = %v, want %v", got, want)
+	}
+}
+
+func TestPreprocessImageAppliesOrientation(t *testing.T) {
+	// 竖拍照片以横向像素存储：右上角的水印在原始像素的左上角，Orientation=6 需顺时针旋转
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			c := color.RGBA{128, 128, 128, 255}
+			if x < 50 && y < 50 {
+				c = color.RGBA{255, 0, 0, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	exif := wrapJPEG(buildTestTIFF(binary.LittleEndian, []testIFDEntry{shortEntry(binary.LittleEndian, tagOrientation, 6)}, nil, nil))
+	// 保留 wrapJPEG 的 SOI+APP0+APP1，接上真实图片去掉 SOI 后的数据
+	data := append(exif[:len(exif)-6], encoded.Bytes()[2:]...)
+	path := filepath.Join(t.TempDir(), "rotated.jpg")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write test image: %v", err)
+	}
+
+	out, _, err := preprocessImage(path, ImagePreprocessConfig{Crop: ImageCropCorners, CropFraction: 0.5, Quality: 100})
+	if err != nil {
+		t.Fatalf("preprocessImage returned error: %v", err)
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	if b := decoded.Bounds(); b.Dx() != 50 || b.Dy() != 200 {
+		t.Fatalf("size = %dx%d, want 50x200", b.Dx(), b.Dy())
+	}
+	top := color.RGBAModel.Convert(decoded.At(25, 25)).(color.RGBA)
+	if top.R < 200 || top.G > 60 {
+		t.Errorf("expected the rotated top-right corner to hold the watermark, got %v", top)
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.SetRGBA(0, 0, color.RGBA{255, 0, 0, 255})
+
+	tests := []struct {
+		orientation int
+		width, x, y int
+	}{
+		{1, 3, 0, 0},
+		{2, 3, 2, 0},
+		{3, 3, 2, 1},
+		{4, 3, 0, 1},
+		{5, 2, 0, 0},
+		{6, 2, 1, 0},
+		{7, 2, 1, 2},
+		{8, 2, 0, 2},
+	}
+	for _, tt := range tests {
+		dst := applyOrientation(src, tt.orientation)
+		if dst.Bounds().Dx() != tt.width {
+			t.Errorf("orientation %d: width = %d, want %d", tt.orientation, dst.Bounds().Dx(), tt.width)
+		}
+		if r, _, _, _ := dst.At(tt.x, tt.y).RGBA(); r>>8 != 255 {
+			t.Errorf("orientation %d: expected red pixel at (%d, %d)", tt.orientation, tt.x, tt.y)
+		}
+	}
+}
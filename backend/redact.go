@@ -0,0 +1,131 @@
+package main
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const redactedMask = "***"
+
+// minSecretLength 以下的值不按原文替换，避免误伤日志中的普通短字符串
+const minSecretLength = 6
+
+// secretEnvSuffixes 结尾的环境变量视为密钥，启动时登记其值
+var secretEnvSuffixes = []string{"_AK", "_KEY", "_TK", "_TOKEN", "_SECRET", "_PASSWORD"}
+
+// secretPatterns 按格式识别未登记的密钥：URL 查询参数、Authorization 头与 DSN 中的密码
+var secretPatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\b(ak|key|tk|token|api_key|apikey|access_token)=[^&\s"']+`), "${1}=" + redactedMask},
+	{regexp.MustCompile(`(?i)\b(bearer)\s+[A-Za-z0-9._~+/=-]+`), "${1} " + redactedMask},
+	{regexp.MustCompile(`([^\s:/@"']+):[^\s@"']+@(tcp|unix)\(`), "${1}:" + redactedMask + "@${2}("},
+}
+
+// secretRedactor 将日志与错误信息中的密钥替换为 ***
+type secretRedactor struct {
+	mu      sync.RWMutex
+	secrets []string // 按长度降序，先替换较长的值
+}
+
+// logRedactor 为全局脱敏器，initLogger 登记环境变量中的密钥并让日志输出经过脱敏
+var logRedactor = &secretRedactor{}
+
+// Register 登记需要按原文脱敏的值
+func (r *secretRedactor) Register(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < minSecretLength || containsString(r.secrets, v) {
+			continue
+		}
+		r.secrets = append(r.secrets, v)
+	}
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// Redact 返回脱敏后的文本
+func (r *secretRedactor) Redact(s string) string {
+	r.mu.RLock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedMask)
+	}
+	r.mu.RUnlock()
+	for _, p := range secretPatterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// RegisterEnv 登记 secretEnvSuffixes 结尾的环境变量，以及 DB_DSN 中的密码
+func (r *secretRedactor) RegisterEnv(environ []string) {
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		for _, suffix := range secretEnvSuffixes {
+			if strings.HasSuffix(strings.ToUpper(name), suffix) {
+				r.Register(value)
+				break
+			}
+		}
+		if name == "DB_DSN" {
+			r.Register(dsnPassword(value))
+		}
+	}
+}
+
+// dsnPassword 取出 user:password@tcp(...) 格式 DSN 中的密码
+func dsnPassword(dsn string) string {
+	at := strings.LastIndex(dsn, "@")
+	if at < 0 {
+		return ""
+	}
+	_, password, ok := strings.Cut(dsn[:at], ":")
+	if !ok {
+		return ""
+	}
+	return password
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// redactingWriter 在写入前脱敏，用作日志的输出
+type redactingWriter struct {
+	w        io.Writer
+	redactor *secretRedactor
+}
+
+func newRedactingWriter(w io.Writer, redactor *secretRedactor) *redactingWriter {
+	return &redactingWriter{w: w, redactor: redactor}
+}
+
+// Write 总是返回 len(p)，脱敏后长度变化不影响调用方
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, rw.redactor.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactError 去掉 *url.Error 中带密钥的请求地址，避免错误信息经日志、接口或数据库泄露
+func redactError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return &url.Error{Op: urlErr.Op, URL: logRedactor.Redact(urlErr.URL), Err: urlErr.Err}
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureLog 将默认 slog 日志经新的脱敏器以 debug 级别写入缓冲区，测试结束后恢复
+func captureLog(t *testing.T, secrets ...string) *bytes.Buffer {
+	t.Helper()
+	previousRedactor, previousLogger := logRedactor, slog.Default()
+	previousOutput, previousFlags := log.Writer(), log.Flags()
+	logRedactor = &secretRedactor{}
+	logRedactor.Register(secrets...)
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(newLogHandler(newRedactingWriter(&buf, logRedactor), logFormatText, slog.LevelDebug)))
+	t.Cleanup(func() {
+		logRedactor = previousRedactor
+		slog.SetDefault(previousLogger)
+		log.SetOutput(previousOutput)
+		log.SetFlags(previousFlags)
+	})
+	return &buf
+}
+
+func TestSecretRedactor(t *testing.T) {
+	r := &secretRedactor{}
+	r.Register("sk-qwen-registered", "short", "")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"https://api.map.baidu.com/geocoding/v3/?address=无锡&ak=unregistered-ak&output=json",
+			"https://api.map.baidu.com/geocoding/v3/?address=无锡&ak=***&output=json"},
+		{"http://api.tianditu.gov.cn/geocoder?ds={\"keyWord\":\"无锡\"}&tk=abcdef123", "http://api.tianditu.gov.cn/geocoder?ds={\"keyWord\":\"无锡\"}&tk=***"},
+		{"Authorization: Bearer sk-live-abcdef", "Authorization: Bearer ***"},
+		{"root:hunter2@tcp(127.0.0.1:3306)/weather_label_db", "root:***@tcp(127.0.0.1:3306)/weather_label_db"},
+		{"invalid api key sk-qwen-registered", "invalid api key ***"},
+		// 过短的值不按原文替换
+		{"short circuit", "short circuit"},
+		{"monkey=banana output=json", "monkey=banana output=json"},
+	}
+	for _, tt := range tests {
+		if got := r.Redact(tt.input); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterEnv(t *testing.T) {
+	r := &secretRedactor{}
+	r.RegisterEnv([]string{
+		"BAIDU_MAP_AK=baidu-env-ak",
+		"QWEN_VLM_API_KEY=sk-env-qwen",
+		"DB_PASSWORD=db-env-password",
+		"DB_DSN=app:dsn-env-password@unix(/tmp/mysql.sock)/weather",
+		"PORT=8080",
+	})
+	line := r.Redact("baidu-env-ak sk-env-qwen db-env-password dsn-env-password 8080")
+	if line != "*** *** *** *** 8080" {
+		t.Errorf("unexpected redaction: %q", line)
+	}
+}
+
+func TestGeocodeLogsRedactKey(t *testing.T) {
+	const ak = "baidu-secret-ak"
+	logs := captureLog(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":0,"result":{"location":{"lng":120.3615,"lat":31.6188},"level":"乡镇"}}`))
+	}))
+	g, err := NewGeocoder(GeocoderConfig{Provider: "baidu", APIKey: ak, BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewGeocoder returned error: %v", err)
+	}
+	if _, err := g.Geocode(context.Background(), "江苏省无锡市锡山区羊尖镇"); err != nil {
+		t.Fatalf("Geocode returned error: %v", err)
+	}
+
+	// 连接失败时 *url.Error 带有完整请求地址
+	server.Close()
+	_, err = g.Geocode(context.Background(), "江苏省无锡市锡山区羊尖镇")
+	if err == nil {
+		t.Fatal("expected error after server closed")
+	}
+	if strings.Contains(err.Error(), ak) {
+		t.Errorf("error leaks the AK: %v", err)
+	}
+	slog.Error("Geocoding failed", "error", err)
+
+	if strings.Contains(logs.String(), ak) {
+		t.Errorf("log output leaks the AK:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "ak=***") {
+		t.Errorf("expected redacted request URL in logs:\n%s", logs.String())
+	}
+}
+
+func TestVLMErrorsRedactKey(t *testing.T) {
+	const apiKey = "sk-qwen-secret-key"
+	logs := captureLog(t, apiKey)
+
+	// 部分网关在鉴权失败时原样回显 Authorization 头
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid credential: ` + r.Header.Get("Authorization") + `"}`))
+	}))
+	defer server.Close()
+
+	extractor, err := NewMetadataExtractor(ExtractorConfig{Provider: "qwen", APIKey: apiKey, BaseURL: server.URL, Retry: RetryPolicy{}})
+	if err != nil {
+		t.Fatalf("NewMetadataExtractor returned error: %v", err)
+	}
+	_, err = extractor.ExtractMetadata(context.Background(), writeTestImage(t))
+	if err == nil {
+		t.Fatal("expected error for 401")
+	}
+	if strings.Contains(err.Error(), apiKey) {
+		t.Errorf("error leaks the API key: %v", err)
+	}
+	slog.Error("OCR failed", "error", err)
+	// 标准库 log 的输出同样经过脱敏
+	log.Printf("Connecting with Authorization: Bearer %s", apiKey)
+	if strings.Contains(logs.String(), apiKey) {
+		t.Errorf("log output leaks the API key:\n%s", logs.String())
+	}
+}
+
+func TestBuildDSNPasswordNotLogged(t *testing.T) {
+	t.Setenv("DB_DSN", "")
+	t.Setenv("DB_USER", "weather")
+	t.Setenv("DB_PASSWORD", "db-secret-password")
+	logs := captureLog(t)
+	logRedactor.RegisterEnv([]string{"DB_PASSWORD=db-secret-password"})
+
+	slog.Info("Connecting to database", "dsn", buildDSN())
+	if strings.Contains(logs.String(), "db-secret-password") || !strings.Contains(logs.String(), "weather:***@tcp(") {
+		t.Errorf("unexpected log output: %s", logs.String())
+	}
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxStationImportSize 为站点导入文件的大小上限
+const maxStationImportSize = 8 << 20
+
+// StationImportIssue 为导入文件中某一行的校验错误
+type StationImportIssue struct {
+	Line    int    `json:"line"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// StationChange 为导入时一条站点的变更，Before 为空表示新增
+type StationChange struct {
+	Before *StationWithAliases `json:"before,omitempty"`
+	After  StationWithAliases  `json:"after"`
+}
+
+// StationImportReport 为导入结果；DryRun 或存在错误时不写入数据库
+type StationImportReport struct {
+	DryRun    bool                 `json:"dry_run"`
+	Applied   bool                 `json:"applied"`
+	Total     int                  `json:"total"`
+	Created   []StationChange      `json:"created"`
+	Updated   []StationChange      `json:"updated"`
+	Unchanged int                  `json:"unchanged"`
+	Errors    []StationImportIssue `json:"errors"`
+}
+
+// validateStation 校验站点字段，坐标需已由 normalizeStation 转换为 storageCoordSystem
+func validateStation(s StationWithAliases) error {
+	switch {
+	case s.CoordSystem != "" && s.CoordSystem != storageCoordSystem:
+		return fmt.Errorf("invalid coord_system %q (wgs84, gcj02, bd09)", s.CoordSystem)
+	case strings.TrimSpace(s.ID) == "":
+		return fmt.Errorf("id is required")
+	case len(s.ID) > 255:
+		return fmt.Errorf("id is too long")
+	case strings.TrimSpace(s.Name) == "":
+		return fmt.Errorf("name is required")
+	case len(s.Name) > 255:
+		return fmt.Errorf("name is too long")
+	case !isFinite(s.Longitude) || !isFinite(s.Latitude):
+		// NaN 与任何数比较都为 false，需在范围检查前单独排除
+		return fmt.Errorf("longitude and latitude must be finite numbers")
+	case s.Longitude < -180 || s.Longitude > 180:
+		return fmt.Errorf("longitude %v out of range [-180, 180]", s.Longitude)
+	case s.Latitude < -90 || s.Latitude > 90:
+		return fmt.Errorf("latitude %v out of range [-90, 90]", s.Latitude)
+	case s.Longitude == 0 && s.Latitude == 0:
+		return fmt.Errorf("longitude and latitude are required")
+	}
+	for _, alias := range s.Aliases {
+		if strings.TrimSpace(alias) == "" || len(alias) > 255 {
+			return fmt.Errorf("invalid alias %q", alias)
+		}
+	}
+	return validateStationLifecycle(s.Station)
+}
+
+// normalizeStation 去除字段首尾空白、别名去重，坐标转换为 storageCoordSystem 并与 stations 表一致保留 5 位小数；
+// Aliases 为 nil 表示未提供别名，保持为 nil
+func normalizeStation(s StationWithAliases) StationWithAliases {
+	if system, err := parseCoordSystem(s.CoordSystem); err == nil {
+		s.Longitude, s.Latitude = convertCoordinate(s.Longitude, s.Latitude, system, storageCoordSystem)
+		s.CoordSystem = storageCoordSystem
+	}
+	s.ID = strings.TrimSpace(s.ID)
+	s.Name = strings.TrimSpace(s.Name)
+	s.Type = strings.ToLower(strings.TrimSpace(s.Type))
+	s.ActiveFrom = strings.TrimSpace(s.ActiveFrom)
+	s.ActiveTo = strings.TrimSpace(s.ActiveTo)
+	s.Longitude = roundCoordinate(s.Longitude, 5)
+	s.Latitude = roundCoordinate(s.Latitude, 5)
+	if s.Aliases == nil {
+		return s
+	}
+	aliases := []string{}
+	seen := map[string]bool{}
+	for _, alias := range s.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias != "" && !seen[alias] && alias != s.Name {
+			seen[alias] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	s.Aliases = aliases
+	return s
+}
+
+func roundCoordinate(value float64, digits int) float64 {
+	parsed, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'f', digits, 64), 64)
+	return parsed
+}
+
+// parseStationCSV 解析 id,name,longitude,latitude[,aliases,type,active_from,active_to,coord_system] 格式的站点表，首行为表头；
+// 支持逗号或制表符分隔（从 Excel 复制），多个别名以 | 分隔。返回按行号对应的站点、文件包含的列与错误
+func parseStationCSV(data []byte) (map[int]StationWithAliases, map[string]bool, []StationImportIssue, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Excel 导出的 UTF-8 BOM
+	firstLine, _, _ := bufio.NewReader(bytes.NewReader(data)).ReadLine()
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(firstLine, []byte("\t")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil, fmt.Errorf("empty file")
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	columns := map[string]int{}
+	present := map[string]bool{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		columns[name] = i
+		present[name] = true
+	}
+	for _, required := range []string{"id", "name", "longitude", "latitude"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	stations := map[int]StationWithAliases{}
+	issues := []StationImportIssue{}
+	firstSeen := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		station := StationWithAliases{Station: Station{
+			ID:          field(record, "id"),
+			Name:        field(record, "name"),
+			Type:        field(record, "type"),
+			ActiveFrom:  field(record, "active_from"),
+			ActiveTo:    field(record, "active_to"),
+			CoordSystem: field(record, "coord_system"),
+		}}
+		if _, ok := columns["aliases"]; ok {
+			station.Aliases = strings.Split(field(record, "aliases"), "|")
+		}
+		var parseErr error
+		if station.Longitude, parseErr = strconv.ParseFloat(field(record, "longitude"), 64); parseErr != nil {
+			issues = append(issues, StationImportIssue{Line: line, ID: station.ID, Message: "invalid longitude"})
+			continue
+		}
+		if station.Latitude, parseErr = strconv.ParseFloat(field(record, "latitude"), 64); parseErr != nil {
+			issues = append(issues, StationImportIssue{Line: line, ID: station.ID, Message: "invalid latitude"})
+			continue
+		}
+		station = normalizeStation(station)
+		if err := validateStation(station); err != nil {
+			issues = append(issues, StationImportIssue{Line: line, ID: station.ID, Message: err.Error()})
+			continue
+		}
+		if prev, ok := firstSeen[station.ID]; ok {
+			issues = append(issues, StationImportIssue{Line: line, ID: station.ID,
+				Message: fmt.Sprintf("duplicate id, first seen on line %d", prev)})
+			continue
+		}
+		firstSeen[station.ID] = line
+		stations[line] = station
+	}
+	return stations, present, issues, nil
+}
+
+// planStationImport 将导入的站点与现有站点比较，得出新增与修改；
+// 文件中没有 aliases、active_from、active_to 列或 type 为空时保留现有值，新增站点类型默认为区域站
+func planStationImport(incoming map[int]StationWithAliases, columns map[string]bool, existing map[string]StationWithAliases) StationImportReport {
+	lines := make([]int, 0, len(incoming))
+	for line := range incoming {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	report := StationImportReport{Total: len(incoming), Created: []StationChange{}, Updated: []StationChange{}, Errors: []StationImportIssue{}}
+	for _, line := range lines {
+		station := incoming[line]
+		current, ok := existing[station.ID]
+		if station.Aliases == nil {
+			station.Aliases = current.Aliases
+		}
+		if !columns["active_from"] {
+			station.ActiveFrom = current.ActiveFrom
+		}
+		if !columns["active_to"] {
+			station.ActiveTo = current.ActiveTo
+		}
+		if station.Type == "" {
+			station.Type = current.Type
+		}
+		if station.Type == "" {
+			station.Type = StationTypeRegional
+		}
+		switch {
+		case !ok:
+			report.Created = append(report.Created, StationChange{After: station})
+		case stationEqual(current, station):
+			report.Unchanged++
+		default:
+			before := current
+			report.Updated = append(report.Updated, StationChange{Before: &before, After: station})
+		}
+	}
+	return report
+}
+
+func stationEqual(a, b StationWithAliases) bool {
+	if a.Station != b.Station || len(a.Aliases) != len(b.Aliases) {
+		return false
+	}
+	aliases := map[string]bool{}
+	for _, alias := range a.Aliases {
+		aliases[alias] = true
+	}
+	for _, alias := range b.Aliases {
+		if !aliases[alias] {
+			return false
+		}
+	}
+	return true
+}
+
+// saveStation 在事务中新增（before 为 nil）或更新站点，并将别名替换为 station.Aliases；
+// 更新时坐标变化则将旧位置记入位置历史，movedAt 为迁移时间
+func saveStation(tx *sql.Tx, station StationWithAliases, before *StationWithAliases, movedAt time.Time) error {
+	if before == nil {
+		if _, err := tx.Exec(`INSERT INTO stations (id, name, longitude, latitude, station_type, active_from, active_to, coord_system)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			station.ID, station.Name, station.Longitude, station.Latitude, station.Type,
+			nullString(station.ActiveFrom), nullString(station.ActiveTo), storageCoordSystem); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.Exec(`UPDATE stations SET name = ?, longitude = ?, latitude = ?, station_type = ?,
+			active_from = ?, active_to = ?, coord_system = ? WHERE id = ?`,
+			station.Name, station.Longitude, station.Latitude, station.Type,
+			nullString(station.ActiveFrom), nullString(station.ActiveTo), storageCoordSystem, station.ID); err != nil {
+			return err
+		}
+		if err := recordStationMove(tx, before.Station, station.Station, movedAt); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM station_aliases WHERE station_id = ?", station.ID); err != nil {
+			return err
+		}
+	}
+	for _, alias := range station.Aliases {
+		if _, err := tx.Exec("INSERT INTO station_aliases (station_id, alias) VALUES (?, ?)", station.ID, alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadStationByID 读取单个站点及别名，不存在时返回 sql.ErrNoRows
+func loadStationByID(id string) (StationWithAliases, error) {
+	var station StationWithAliases
+	var err error
+	if station.Station, err = scanStation(db.QueryRow("SELECT "+stationColumns+" FROM stations WHERE id = ?", id)); err != nil {
+		return station, err
+	}
+	rows, err := db.Query("SELECT alias FROM station_aliases WHERE station_id = ? ORDER BY id", id)
+	if err != nil {
+		return station, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return station, err
+		}
+		station.Aliases = append(station.Aliases, alias)
+	}
+	return station, rows.Err()
+}
+
+// refreshStationIndexAfterChange 站点变更后刷新内存索引，失败时等待定期刷新
+func refreshStationIndexAfterChange() {
+	if err := refreshStationIndex(); err != nil {
+		slog.Error("Failed to refresh station index", "error", err)
+	}
+}
+
+// createStation 新增站点：POST /api/stations
+func createStation(w http.ResponseWriter, r *http.Request) {
+	var station StationWithAliases
+	if err := json.NewDecoder(r.Body).Decode(&station); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	station = normalizeStation(station)
+	if station.Type == "" {
+		station.Type = StationTypeRegional
+	}
+	if err := validateStation(station); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := loadStationByID(station.ID); err == nil {
+		http.Error(w, "Station already exists", http.StatusConflict)
+		return
+	} else if err != sql.ErrNoRows {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if err := saveStation(tx, station, nil, time.Time{}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	refreshStationIndexAfterChange()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(station)
+}
+
+// updateStation 修改站点：PUT /api/stations/{id}?moved_at=...，请求体中未提供的字段保留原值；
+// 坐标变化视为站点迁移，旧位置按 moved_at（默认当前时间）记入位置历史
+func updateStation(w http.ResponseWriter, r *http.Request) {
+	movedAt, err := parseMovedAt(r)
+	if err != nil {
+		http.Error(w, "Invalid moved_at", http.StatusBadRequest)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	current, err := loadStationByID(id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Station not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	station := current
+	station.Aliases = append([]string(nil), current.Aliases...)
+	if err := json.NewDecoder(r.Body).Decode(&station); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if station.ID != "" && station.ID != id {
+		http.Error(w, "Station ID cannot be changed", http.StatusBadRequest)
+		return
+	}
+	station.ID = id
+	if station.Aliases == nil {
+		station.Aliases = current.Aliases
+	}
+	// coord_system 只作用于请求体中提供的坐标
+	if station.Longitude == current.Longitude && station.Latitude == current.Latitude {
+		station.CoordSystem = current.CoordSystem
+	}
+	station = normalizeStation(station)
+	if station.Type == "" {
+		station.Type = current.Type
+	}
+	if err := validateStation(station); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if err := saveStation(tx, station, &current, movedAt); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	refreshStationIndexAfterChange()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(station)
+}
+
+// deleteStation 删除站点：DELETE /api/stations/{id}，已被标注引用的站点不能删除
+func deleteStation(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := loadStationByID(id); err == sql.ErrNoRows {
+		http.Error(w, "Station not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var annotationCount int
+	if err := db.QueryRow("SELECT COUNT(*) FROM annotations WHERE station_id = ?", id).Scan(&annotationCount); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if annotationCount > 0 {
+		http.Error(w, fmt.Sprintf("Station is referenced by %d annotations and cannot be deleted", annotationCount), http.StatusConflict)
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM stations WHERE id = ?", id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	refreshStationIndexAfterChange()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// importStations 批量导入站点：POST /api/stations/import?dry_run=true&moved_at=...，
+// 请求体为 CSV 文本或 multipart 的 file 字段；新增不存在的站点、更新已有站点，不删除站点。
+// 坐标变化的站点按 moved_at 记录位置历史。存在任何校验错误时整批不写入并返回 422
+func importStations(w http.ResponseWriter, r *http.Request) {
+	movedAt, err := parseMovedAt(r)
+	if err != nil {
+		http.Error(w, "Invalid moved_at", http.StatusBadRequest)
+		return
+	}
+	data, err := readStationImport(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	incoming, columns, issues, err := parseStationCSV(data)
+	if err != nil {
+		http.Error(w, "Invalid CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existingList, err := loadStationsWithAliases()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	existing := map[string]StationWithAliases{}
+	for _, station := range existingList {
+		existing[station.ID] = station
+	}
+
+	report := planStationImport(incoming, columns, existing)
+	report.Errors = issues
+	report.Total += len(issues)
+	report.DryRun = r.URL.Query().Get("dry_run") == "true"
+
+	status := http.StatusOK
+	switch {
+	case len(report.Errors) > 0:
+		status = http.StatusUnprocessableEntity
+	case !report.DryRun && len(report.Created)+len(report.Updated) > 0:
+		if err := applyStationImport(report, movedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		report.Applied = true
+		refreshStationIndexAfterChange()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// readStationImport 读取 multipart 的 file 字段或原始请求体
+func readStationImport(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxStationImportSize)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		if err := r.ParseMultipartForm(maxStationImportSize); err != nil {
+			return nil, err
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("no file provided")
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+	return io.ReadAll(r.Body)
+}
+
+func applyStationImport(report StationImportReport, movedAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, change := range report.Created {
+		if err := saveStation(tx, change.After, nil, time.Time{}); err != nil {
+			return fmt.Errorf("station %s: %w", change.After.ID, err)
+		}
+	}
+	for _, change := range report.Updated {
+		if err := saveStation(tx, change.After, change.Before, movedAt); err != nil {
+			return fmt.Errorf("station %s: %w", change.After.ID, err)
+		}
+	}
+	return tx.Commit()
+}
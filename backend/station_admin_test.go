@@ -0,0 +1,212 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateStation(t *testing.T) {
+	valid := StationWithAliases{Station: Station{ID: "M2873", Name: "定波", Longitude: 120.2453, Latitude: 31.9242}}
+
+	tests := []struct {
+		name    string
+		modify  func(*StationWithAliases)
+		wantErr string
+	}{
+		{"valid", func(*StationWithAliases) {}, ""},
+		{"missing id", func(s *StationWithAliases) { s.ID = " " }, "id is required"},
+		{"missing name", func(s *StationWithAliases) { s.Name = "" }, "name is required"},
+		{"longitude out of range", func(s *StationWithAliases) { s.Longitude = 200 }, "longitude"},
+		{"latitude out of range", func(s *StationWithAliases) { s.Latitude = -91 }, "latitude"},
+		{"nan longitude", func(s *StationWithAliases) { s.Longitude = math.NaN() }, "finite"},
+		{"infinite latitude", func(s *StationWithAliases) { s.Latitude = math.Inf(1) }, "finite"},
+		{"missing coordinates", func(s *StationWithAliases) { s.Longitude, s.Latitude = 0, 0 }, "required"},
+		{"empty alias", func(s *StationWithAliases) { s.Aliases = []string{""} }, "alias"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			station := valid
+			tt.modify(&station)
+			err := validateStation(station)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseStationCSV(t *testing.T) {
+	data := "\xef\xbb\xbfid,name,longitude,latitude,aliases\n" +
+		"M2873, 定波 ,120.245301,31.9242,定波村|dingbo\n" +
+		"M2874,北国,abc,31.76440,\n" +
+		"M2875,应急指挥中心,120.0862,95,\n" +
+		"\n" +
+		"M2873,重复,120.1,31.1,\n" +
+		"M2876,桐岐,120.2043,31.7503,\n" +
+		"M2877,查桥,NaN,31.6,\n"
+
+	stations, _, issues, err := parseStationCSV([]byte(data))
+	if err != nil {
+		t.Fatalf("parseStationCSV returned error: %v", err)
+	}
+
+	want := StationWithAliases{
+		Station: Station{ID: "M2873", Name: "定波", Longitude: 120.2453, Latitude: 31.9242, CoordSystem: CoordWGS84},
+		Aliases: []string{"定波村", "dingbo"},
+	}
+	if got := stations[2]; !reflect.DeepEqual(got, want) {
+		t.Errorf("line 2 = %+v, want %+v", got, want)
+	}
+	if got := stations[7]; got.ID != "M2876" || len(got.Aliases) != 0 || got.Aliases == nil {
+		t.Errorf("line 7 = %+v, want M2876 with empty aliases", got)
+	}
+	if len(stations) != 2 {
+		t.Errorf("expected 2 valid stations, got %d", len(stations))
+	}
+
+	wantIssues := []StationImportIssue{
+		{Line: 3, ID: "M2874", Message: "invalid longitude"},
+		{Line: 4, ID: "M2875", Message: "latitude 95 out of range [-90, 90]"},
+		{Line: 6, ID: "M2873", Message: "duplicate id, first seen on line 2"},
+		{Line: 8, ID: "M2877", Message: "longitude and latitude must be finite numbers"},
+	}
+	if !reflect.DeepEqual(issues, wantIssues) {
+		t.Errorf("issues = %+v, want %+v", issues, wantIssues)
+	}
+}
+
+func TestParseStationCSVTabsAndHeaders(t *testing.T) {
+	stations, _, issues, err := parseStationCSV([]byte("ID\tName\tLongitude\tLatitude\nM2877\t西石桥\t120.0817\t31.8866\n"))
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("unexpected error %v / issues %v", err, issues)
+	}
+	if got := stations[2]; got.Name != "西石桥" || got.Aliases != nil {
+		t.Errorf("unexpected station: %+v", got)
+	}
+
+	if _, _, _, err := parseStationCSV([]byte("id,name,longitude\n")); err == nil {
+		t.Error("expected error for missing latitude column")
+	}
+	if _, _, _, err := parseStationCSV(nil); err == nil {
+		t.Error("expected error for empty file")
+	}
+}
+
+func TestPlanStationImport(t *testing.T) {
+	existing := map[string]StationWithAliases{
+		"A": {Station: Station{ID: "A", Name: "甲", Longitude: 120, Latitude: 31, Type: StationTypeRegional}, Aliases: []string{"jia"}},
+		"B": {Station: Station{ID: "B", Name: "乙", Longitude: 120.1, Latitude: 31.1, Type: StationTypeRegional}},
+		"C": {Station: Station{ID: "C", Name: "丙", Longitude: 120.2, Latitude: 31.2, Type: StationTypeRegional}, Aliases: []string{"bing"}},
+	}
+	incoming := map[int]StationWithAliases{
+		2: {Station: Station{ID: "A", Name: "甲", Longitude: 120, Latitude: 31}},                                // 未提供别名，视为不变
+		3: {Station: Station{ID: "B", Name: "乙站", Longitude: 120.1, Latitude: 31.1}},                           // 改名
+		4: {Station: Station{ID: "C", Name: "丙", Longitude: 120.2, Latitude: 31.2}, Aliases: []string{}},       // 清空别名
+		5: {Station: Station{ID: "D", Name: "丁", Longitude: 120.3, Latitude: 31.3}, Aliases: []string{"ding"}}, // 新增
+	}
+
+	report := planStationImport(incoming, map[string]bool{}, existing)
+	if report.Total != 4 || report.Unchanged != 1 {
+		t.Errorf("unexpected totals: %+v", report)
+	}
+	if len(report.Created) != 1 || report.Created[0].After.ID != "D" || report.Created[0].Before != nil {
+		t.Errorf("unexpected created: %+v", report.Created)
+	}
+	if len(report.Updated) != 2 || report.Updated[0].After.ID != "B" || report.Updated[1].After.ID != "C" {
+		t.Fatalf("unexpected updated: %+v", report.Updated)
+	}
+	if report.Updated[0].Before.Name != "乙" {
+		t.Errorf("expected before snapshot, got %+v", report.Updated[0].Before)
+	}
+}
+
+func TestParseStationCSVLifecycleColumns(t *testing.T) {
+	data := "id,name,longitude,latitude,type,active_to\n" +
+		"58354,无锡本站,120.3544,31.6128,National,\n" +
+		"M2874,北国,120.5135,31.7644,,2023-06-30\n" +
+		"M2875,应急指挥中心,120.0862,31.9233,county,\n" +
+		"M2876,桐岐,120.2043,31.7503,micro,2023/06/30\n"
+
+	stations, columns, issues, err := parseStationCSV([]byte(data))
+	if err != nil {
+		t.Fatalf("parseStationCSV returned error: %v", err)
+	}
+	if !columns["type"] || !columns["active_to"] || columns["active_from"] {
+		t.Errorf("unexpected columns: %v", columns)
+	}
+	if got := stations[2]; got.Type != StationTypeNational {
+		t.Errorf("line 2 type = %q, want national", got.Type)
+	}
+	if got := stations[3]; got.Type != "" || got.ActiveTo != "2023-06-30" {
+		t.Errorf("line 3 = %+v", got)
+	}
+	if len(issues) != 2 || !strings.Contains(issues[0].Message, "type") || !strings.Contains(issues[1].Message, "active_to") {
+		t.Errorf("unexpected issues: %+v", issues)
+	}
+
+	existing := map[string]StationWithAliases{
+		"M2874": {Station: Station{ID: "M2874", Name: "北国", Longitude: 120.5135, Latitude: 31.7644,
+			Type: StationTypeMicro, ActiveFrom: "2020-01-01"}},
+	}
+	report := planStationImport(stations, columns, existing)
+	if len(report.Updated) != 1 || len(report.Created) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	// type 为空时沿用现有类型，文件中没有 active_from 列时保留现有启用日期
+	if got := report.Updated[0].After.Station; got.Type != StationTypeMicro || got.ActiveFrom != "2020-01-01" || got.ActiveTo != "2023-06-30" {
+		t.Errorf("unexpected update: %+v", got)
+	}
+}
+
+func TestNormalizeStationCoordSystem(t *testing.T) {
+	// 百度坐标写入前转换为 WGS-84
+	station := normalizeStation(StationWithAliases{Station: Station{ID: "58354", Name: "无锡本站",
+		Longitude: 120.36550, Latitude: 31.61736, CoordSystem: "BD-09"}})
+	if station.CoordSystem != CoordWGS84 || math.Abs(station.Longitude-120.3544) > 0.00002 || math.Abs(station.Latitude-31.6128) > 0.00002 {
+		t.Errorf("unexpected station: %+v", station.Station)
+	}
+	if err := validateStation(station); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := normalizeStation(StationWithAliases{Station: Station{ID: "58354", Name: "无锡本站",
+		Longitude: 120.3544, Latitude: 31.6128, CoordSystem: "cgcs2000"}})
+	if err := validateStation(invalid); err == nil || !strings.Contains(err.Error(), "coord_system") {
+		t.Errorf("expected coord_system error, got %v", err)
+	}
+}
+
+func TestValidateStationLifecycle(t *testing.T) {
+	base := Station{ID: "M2873", Name: "定波", Longitude: 120.2453, Latitude: 31.9242}
+	tests := []struct {
+		name     string
+		typ      string
+		from, to string
+		wantErr  string
+	}{
+		{"defaults", "", "", "", ""},
+		{"full period", StationTypeMicro, "2021-03-01", "2023-06-30", ""},
+		{"single day", StationTypeRegional, "2023-06-30", "2023-06-30", ""},
+		{"unknown type", "county", "", "", "invalid type"},
+		{"bad date", "", "2021-3-1", "", "active_from"},
+		{"reversed", "", "2023-07-01", "2023-06-30", "before"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := base
+			s.Type, s.ActiveFrom, s.ActiveTo = tt.typ, tt.from, tt.to
+			err := validateStation(StationWithAliases{Station: s})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 网格边长按站点密度自动选择，使每个网格平均约 stationsPerCell 个站点
+const (
+	stationsPerCell       = 4
+	minStationCellDegrees = 0.001
+	maxStationCellDegrees = 0.5
+)
+
+// errStationIndexNotLoaded 表示站点索引尚未加载
+var errStationIndexNotLoaded = errors.New("station index not loaded")
+
+type gridCell struct {
+	x, y int
+}
+
+// indexedStation 为站点在 [from, to) 期间所在的位置，零值表示不限
+type indexedStation struct {
+	Station
+	from, to time.Time
+}
+
+// StationIndex 为按经纬度网格划分的站点内存索引，查询只访问查询点附近的网格；
+// 迁移过的站点按位置历史分别索引，查询时只返回在指定时刻运行的站点及其当时的位置
+type StationIndex struct {
+	cellDeg float64
+	cells   map[gridCell][]indexedStation
+	count   int
+	// 网格范围，用于判断是否已搜索全部站点
+	minCell, maxCell gridCell
+	// 站点所在纬度中最小的 cos 值，用于估算经度方向的最短距离
+	minCos float64
+}
+
+// NewStationIndex 根据站点列表与位置历史构建索引，cellDeg <= 0 时按站点密度选择网格大小
+func NewStationIndex(stations []Station, history []StationPosition, cellDeg float64) *StationIndex {
+	if cellDeg <= 0 {
+		cellDeg = adaptiveCellDegrees(stations)
+	}
+	idx := &StationIndex{cellDeg: cellDeg, cells: map[gridCell][]indexedStation{}, minCos: 1}
+	for i, entry := range stationTimeline(stations, history) {
+		cell := idx.cellOf(entry.Longitude, entry.Latitude)
+		idx.cells[cell] = append(idx.cells[cell], entry)
+		if i == 0 {
+			idx.minCell, idx.maxCell = cell, cell
+		}
+		idx.minCell = gridCell{min(idx.minCell.x, cell.x), min(idx.minCell.y, cell.y)}
+		idx.maxCell = gridCell{max(idx.maxCell.x, cell.x), max(idx.maxCell.y, cell.y)}
+		idx.minCos = min(idx.minCos, latitudeCos(entry.Latitude))
+	}
+	idx.count = len(stations)
+	return idx
+}
+
+// stationTimeline 将站点的当前位置与历史位置展开为带有效期的索引项，有效期与站点的启用时间段取交集；
+// 当前位置自最后一次迁移起生效，历史记录中缺少对应站点的会被忽略
+func stationTimeline(stations []Station, history []StationPosition) []indexedStation {
+	byStation := map[string][]StationPosition{}
+	for _, p := range history {
+		byStation[p.StationID] = append(byStation[p.StationID], p)
+	}
+
+	entries := make([]indexedStation, 0, len(stations)+len(history))
+	for _, station := range stations {
+		activeFrom, activeTo := stationActivePeriod(station)
+		positions := byStation[station.ID]
+		linkStationPositions(positions)
+
+		var movedAt time.Time
+		for _, p := range positions {
+			from := activeFrom
+			if p.ValidFrom != nil && p.ValidFrom.After(from) {
+				from = *p.ValidFrom
+			}
+			to := p.ValidTo
+			if !activeTo.IsZero() && activeTo.Before(to) {
+				to = activeTo
+			}
+			if to.After(from) {
+				past := station
+				past.Longitude, past.Latitude = p.Longitude, p.Latitude
+				entries = append(entries, indexedStation{Station: past, from: from, to: to})
+			}
+			movedAt = p.ValidTo
+		}
+
+		from := activeFrom
+		if movedAt.After(from) {
+			from = movedAt
+		}
+		entries = append(entries, indexedStation{Station: station, from: from, to: activeTo})
+	}
+	return entries
+}
+
+func (s indexedStation) activeAt(at time.Time) bool {
+	return inPeriod(at, s.from, s.to)
+}
+
+// adaptiveCellDegrees 根据站点分布范围与数量计算网格边长，站点越密网格越小，查询访问的站点数保持稳定
+func adaptiveCellDegrees(stations []Station) float64 {
+	if len(stations) < 2 {
+		return maxStationCellDegrees
+	}
+	minLon, maxLon := stations[0].Longitude, stations[0].Longitude
+	minLat, maxLat := stations[0].Latitude, stations[0].Latitude
+	for _, s := range stations[1:] {
+		minLon, maxLon = math.Min(minLon, s.Longitude), math.Max(maxLon, s.Longitude)
+		minLat, maxLat = math.Min(minLat, s.Latitude), math.Max(maxLat, s.Latitude)
+	}
+	area := math.Max(maxLon-minLon, minStationCellDegrees) * math.Max(maxLat-minLat, minStationCellDegrees)
+	cell := math.Sqrt(area * stationsPerCell / float64(len(stations)))
+	return math.Min(math.Max(cell, minStationCellDegrees), maxStationCellDegrees)
+}
+
+// Len 返回索引中的站点数（不含历史位置）
+func (idx *StationIndex) Len() int {
+	return idx.count
+}
+
+func (idx *StationIndex) cellOf(lon, lat float64) gridCell {
+	return gridCell{int(math.Floor(lon / idx.cellDeg)), int(math.Floor(lat / idx.cellDeg))}
+}
+
+// queryCell 返回查询点所在网格，并限制在索引范围外一圈以内：更远的网格不含站点，
+// 限制后逐圈搜索最多经过索引范围大小的圈数，且极大的坐标不会在转换为整数时溢出
+func (idx *StationIndex) queryCell(lon, lat float64) gridCell {
+	clamp := func(v float64, low, high int) int {
+		f := math.Floor(v / idx.cellDeg)
+		return int(math.Max(float64(low-1), math.Min(f, float64(high+1))))
+	}
+	return gridCell{clamp(lon, idx.minCell.x, idx.maxCell.x), clamp(lat, idx.minCell.y, idx.maxCell.y)}
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func latitudeCos(lat float64) float64 {
+	return math.Max(math.Cos(lat*math.Pi/180.0), 1e-6)
+}
+
+// Nearest 按距离返回 at 时刻运行的至多 k 个站点，at 为零值时取当前时间；maxKM > 0 时只返回该半径内的站点。
+// 从查询点所在网格逐圈向外搜索，已找到的第 k 个站点比未搜索区域更近时停止
+func (idx *StationIndex) Nearest(lon, lat float64, k int, maxKM float64, at time.Time) []NearbyStation {
+	if idx.count == 0 || !isFinite(lon) || !isFinite(lat) {
+		return []NearbyStation{}
+	}
+	if k <= 0 && maxKM > 0 {
+		return idx.WithinRadius(lon, lat, maxKM, at)
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	center := idx.queryCell(lon, lat)
+	cosLat := math.Min(idx.minCos, latitudeCos(lat))
+	// 相邻一圈网格外的点到查询点的最短距离，略微缩小以抵消球面近似误差
+	ringKM := idx.cellDeg * kmPerDegreeLat * cosLat * 0.99
+
+	candidates := []Station{}
+	distances := []float64{}
+	for ring := 0; ; ring++ {
+		idx.collectRing(center, ring, at, &candidates)
+		for _, station := range candidates[len(distances):] {
+			distances = append(distances, haversineDistance(lon, lat, station.Longitude, station.Latitude))
+		}
+
+		// 未搜索区域与查询点的最短距离
+		bound := float64(ring) * ringKM
+		if maxKM > 0 && bound > maxKM {
+			break
+		}
+		if k > 0 && len(distances) >= k && kthSmallest(distances, k) <= bound {
+			break
+		}
+		if idx.covers(center, ring) {
+			break
+		}
+	}
+
+	// 只对可能进入前 k 的站点计算方位并排序
+	if k > 0 && len(distances) > k {
+		// rankNearestStations 按保留 3 位小数的距离排序，阈值放宽半个单位以保留并列的站点
+		limit := kthSmallest(distances, k) + 0.0005
+		closest := []Station{}
+		for i, station := range candidates {
+			if distances[i] <= limit {
+				closest = append(closest, station)
+			}
+		}
+		candidates = closest
+	}
+	return rankNearestStations(lon, lat, candidates, k, maxKM)
+}
+
+// kthSmallest 返回第 k 小的值（k 从 1 开始）
+func kthSmallest(values []float64, k int) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted[k-1]
+}
+
+// WithinRadius 返回 at 时刻半径 km 内运行的全部站点，按距离排序
+func (idx *StationIndex) WithinRadius(lon, lat, km float64, at time.Time) []NearbyStation {
+	if km <= 0 {
+		return []NearbyStation{}
+	}
+	minLon, maxLon, minLat, maxLat := boundingBox(lon, lat, km)
+	return rankNearestStations(lon, lat, idx.InBoundingBox(minLon, maxLon, minLat, maxLat, at), 0, km)
+}
+
+// InBoundingBox 返回 at 时刻位于经纬度范围内的运行站点，at 为零值时取当前时间
+func (idx *StationIndex) InBoundingBox(minLon, maxLon, minLat, maxLat float64, at time.Time) []Station {
+	stations := []Station{}
+	if idx.count == 0 || minLon > maxLon || minLat > maxLat {
+		return stations
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	low, high := idx.cellOf(minLon, minLat), idx.cellOf(maxLon, maxLat)
+	low = gridCell{max(low.x, idx.minCell.x), max(low.y, idx.minCell.y)}
+	high = gridCell{min(high.x, idx.maxCell.x), min(high.y, idx.maxCell.y)}
+	for x := low.x; x <= high.x; x++ {
+		for y := low.y; y <= high.y; y++ {
+			for _, station := range idx.cells[gridCell{x, y}] {
+				if station.Longitude >= minLon && station.Longitude <= maxLon &&
+					station.Latitude >= minLat && station.Latitude <= maxLat && station.activeAt(at) {
+					stations = append(stations, station.Station)
+				}
+			}
+		}
+	}
+	return stations
+}
+
+// collectRing 收集与 center 切比雪夫距离恰为 ring 的网格中 at 时刻运行的站点，只访问索引范围内的网格
+func (idx *StationIndex) collectRing(center gridCell, ring int, at time.Time, out *[]Station) {
+	if ring == 0 {
+		idx.collectCell(center, at, out)
+		return
+	}
+	xLow, xHigh := max(center.x-ring, idx.minCell.x), min(center.x+ring, idx.maxCell.x)
+	for _, y := range []int{center.y - ring, center.y + ring} {
+		if y < idx.minCell.y || y > idx.maxCell.y {
+			continue
+		}
+		for x := xLow; x <= xHigh; x++ {
+			idx.collectCell(gridCell{x, y}, at, out)
+		}
+	}
+	yLow, yHigh := max(center.y-ring+1, idx.minCell.y), min(center.y+ring-1, idx.maxCell.y)
+	for _, x := range []int{center.x - ring, center.x + ring} {
+		if x < idx.minCell.x || x > idx.maxCell.x {
+			continue
+		}
+		for y := yLow; y <= yHigh; y++ {
+			idx.collectCell(gridCell{x, y}, at, out)
+		}
+	}
+}
+
+func (idx *StationIndex) collectCell(cell gridCell, at time.Time, out *[]Station) {
+	for _, station := range idx.cells[cell] {
+		if station.activeAt(at) {
+			*out = append(*out, station.Station)
+		}
+	}
+}
+
+// covers 判断以 center 为中心、半径 ring 的网格块是否已包含全部站点
+func (idx *StationIndex) covers(center gridCell, ring int) bool {
+	return center.x-ring <= idx.minCell.x && center.x+ring >= idx.maxCell.x &&
+		center.y-ring <= idx.minCell.y && center.y+ring >= idx.maxCell.y
+}
+
+var (
+	stationIndexMu sync.RWMutex
+	stationIndex   *StationIndex
+)
+
+// currentStationIndex 返回当前生效的站点索引
+func currentStationIndex() (*StationIndex, error) {
+	stationIndexMu.RLock()
+	defer stationIndexMu.RUnlock()
+	if stationIndex == nil {
+		return nil, errStationIndexNotLoaded
+	}
+	return stationIndex, nil
+}
+
+func setStationIndex(idx *StationIndex) {
+	stationIndexMu.Lock()
+	stationIndex = idx
+	stationIndexMu.Unlock()
+}
+
+// refreshStationIndex 从数据库重新加载站点与位置历史并替换索引，站点变更后调用
+func refreshStationIndex() error {
+	rows, err := db.Query("SELECT " + stationColumns + " FROM stations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	stations := []Station{}
+	for rows.Next() {
+		station, err := scanStation(rows)
+		if err != nil {
+			return err
+		}
+		stations = append(stations, station)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	history, err := loadStationPositions("")
+	if err != nil {
+		return err
+	}
+	setStationIndex(NewStationIndex(stations, history, 0))
+	return nil
+}
+
+// initStationIndex 加载站点索引，并按 STATION_INDEX_REFRESH_SECONDS 定期刷新以发现直接修改数据库的变更
+func initStationIndex(ctx context.Context) error {
+	if err := refreshStationIndex(); err != nil {
+		return err
+	}
+	idx, _ := currentStationIndex()
+	slog.Info("Station index loaded", "stations", idx.Len())
+
+	interval := time.Duration(getEnvInt("STATION_INDEX_REFRESH_SECONDS", 300)) * time.Second
+	if interval <= 0 {
+		return nil
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := refreshStationIndex(); err != nil {
+					slog.Error("Failed to refresh station index", "error", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// randomStations 在无锡附近均匀生成 n 个站点
+func randomStations(rng *rand.Rand, n int) []Station {
+	stations := make([]Station, n)
+	for i := range stations {
+		stations[i] = Station{
+			ID:        fmt.Sprintf("S%06d", i),
+			Longitude: 119.5 + rng.Float64()*1.2,
+			Latitude:  31.0 + rng.Float64()*1.1,
+		}
+	}
+	return stations
+}
+
+func stationIDs(nearby []NearbyStation) []string {
+	ids := []string{}
+	for _, s := range nearby {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestStationIndexMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	stations := randomStations(rng, 1000)
+	idx := NewStationIndex(stations, nil, 0)
+
+	queries := []struct {
+		k     int
+		maxKM float64
+	}{
+		{1, 0}, {5, 0}, {20, 0}, {5, 3}, {0, 5}, {3, 0.5},
+	}
+	for i := 0; i < 100; i++ {
+		// 部分查询点落在站点范围之外
+		lon, lat := 119.3+rng.Float64()*1.6, 30.8+rng.Float64()*1.5
+		for _, q := range queries {
+			got := stationIDs(idx.Nearest(lon, lat, q.k, q.maxKM, time.Time{}))
+			want := stationIDs(rankNearestStations(lon, lat, stations, q.k, q.maxKM))
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Nearest(%f, %f, k=%d, max_km=%v) = %v, want %v", lon, lat, q.k, q.maxKM, got, want)
+			}
+		}
+	}
+}
+
+func TestStationIndexFarQueryAndEmpty(t *testing.T) {
+	stations := []Station{
+		{ID: "A", Longitude: 120.30, Latitude: 31.50},
+		{ID: "B", Longitude: 120.40, Latitude: 31.60},
+	}
+	idx := NewStationIndex(stations, nil, 0)
+	if got := stationIDs(idx.Nearest(0, 0, 1, 0, time.Time{})); !reflect.DeepEqual(got, []string{"A"}) {
+		t.Errorf("far query = %v, want [A]", got)
+	}
+	if got := idx.Nearest(0, 0, 1, 10, time.Time{}); len(got) != 0 {
+		t.Errorf("expected no stations within 10 km, got %v", got)
+	}
+
+	empty := NewStationIndex(nil, nil, 0)
+	if got := empty.Nearest(120.3, 31.5, 5, 0, time.Time{}); len(got) != 0 {
+		t.Errorf("expected empty result, got %v", got)
+	}
+}
+
+func TestStationIndexNonFiniteAndExtremeQueries(t *testing.T) {
+	stations := []Station{
+		{ID: "A", Longitude: 120.30, Latitude: 31.50},
+		{ID: "B", Longitude: 120.40, Latitude: 31.60},
+	}
+	idx := NewStationIndex(stations, nil, 0)
+
+	queries := []struct {
+		lon, lat float64
+		want     []string
+	}{
+		{math.NaN(), 31, []string{}},
+		{math.Inf(1), 31, []string{}},
+		{120.3, math.Inf(-1), []string{}},
+		{1e300, 31, []string{"B", "A"}},
+		{-1e300, -1e300, []string{"A", "B"}},
+	}
+	for _, q := range queries {
+		done := make(chan []string, 1)
+		go func() { done <- stationIDs(idx.Nearest(q.lon, q.lat, 2, 0, time.Time{})) }()
+		select {
+		case got := <-done:
+			// 极端坐标的距离无意义，只要求返回索引中的站点
+			if len(got) != len(q.want) {
+				t.Errorf("Nearest(%v, %v) = %v, want %d stations", q.lon, q.lat, got, len(q.want))
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Nearest(%v, %v) did not terminate", q.lon, q.lat)
+		}
+	}
+}
+
+func TestStationIndexInBoundingBox(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	stations := randomStations(rng, 500)
+	idx := NewStationIndex(stations, nil, 0.05)
+
+	got := idx.InBoundingBox(120.0, 120.2, 31.3, 31.4, time.Time{})
+	want := []Station{}
+	for _, s := range stations {
+		if s.Longitude >= 120.0 && s.Longitude <= 120.2 && s.Latitude >= 31.3 && s.Latitude <= 31.4 {
+			want = append(want, s)
+		}
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].ID < got[j].ID })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InBoundingBox returned %d stations, want %d", len(got), len(want))
+	}
+}
+
+// 站点数量增加 100 倍时，索引查询耗时应基本不变，而线性扫描随数量线性增长；
+// 半径查询的耗时只随半径内的站点数（结果数量）增长
+func BenchmarkStationIndexNearest(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		rng := rand.New(rand.NewSource(3))
+		idx := NewStationIndex(randomStations(rng, n), nil, 0)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				idx.Nearest(119.6+float64(i%100)*0.01, 31.1+float64(i%97)*0.01, 5, 0, time.Time{})
+			}
+		})
+	}
+}
+
+func BenchmarkStationIndexWithinRadius(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		rng := rand.New(rand.NewSource(3))
+		idx := NewStationIndex(randomStations(rng, n), nil, 0)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				idx.WithinRadius(119.6+float64(i%100)*0.01, 31.1+float64(i%97)*0.01, 0.5, time.Time{})
+			}
+		})
+	}
+}
+
+func BenchmarkLinearNearest(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		rng := rand.New(rand.NewSource(3))
+		stations := randomStations(rng, n)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rankNearestStations(119.6+float64(i%100)*0.01, 31.1+float64(i%97)*0.01, stations, 5, 0)
+			}
+		})
+	}
+}
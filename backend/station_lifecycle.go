@@ -0,0 +1,208 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// 站点类型
+const (
+	StationTypeNational = "national" // 国家站
+	StationTypeRegional = "regional" // 区域站
+	StationTypeMicro    = "micro"    // 微型站
+)
+
+// stationDateLayout 为启用与撤销日期的格式
+const stationDateLayout = "2006-01-02"
+
+// stationColumns 为 stations 表的查询列，与 scanStation 的顺序一致
+const stationColumns = "id, name, longitude, latitude, station_type, active_from, active_to, coord_system"
+
+func isValidStationType(stationType string) bool {
+	switch stationType {
+	case StationTypeNational, StationTypeRegional, StationTypeMicro:
+		return true
+	}
+	return false
+}
+
+// scanStation 扫描 stationColumns 对应的一行，extra 为其后附加列的接收变量；坐标转换为 storageCoordSystem
+func scanStation(row rowScanner, extra ...interface{}) (Station, error) {
+	var station Station
+	var activeFrom, activeTo sql.NullTime
+	dest := []interface{}{&station.ID, &station.Name, &station.Longitude, &station.Latitude,
+		&station.Type, &activeFrom, &activeTo, &station.CoordSystem}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return station, err
+	}
+	station.Longitude, station.Latitude = convertCoordinate(station.Longitude, station.Latitude, station.CoordSystem, storageCoordSystem)
+	station.CoordSystem = storageCoordSystem
+	if activeFrom.Valid {
+		station.ActiveFrom = activeFrom.Time.Format(stationDateLayout)
+	}
+	if activeTo.Valid {
+		station.ActiveTo = activeTo.Time.Format(stationDateLayout)
+	}
+	return station, nil
+}
+
+// stationActivePeriod 返回站点的有效时间段 [from, to)，零值表示不限；撤销日期当天仍有效
+func stationActivePeriod(s Station) (from, to time.Time) {
+	if s.ActiveFrom != "" {
+		from, _ = time.ParseInLocation(stationDateLayout, s.ActiveFrom, time.Local)
+	}
+	if s.ActiveTo != "" {
+		if t, err := time.ParseInLocation(stationDateLayout, s.ActiveTo, time.Local); err == nil {
+			to = t.AddDate(0, 0, 1)
+		}
+	}
+	return from, to
+}
+
+// stationActiveAt 判断站点在 at 时刻是否在运行
+func stationActiveAt(s Station, at time.Time) bool {
+	from, to := stationActivePeriod(s)
+	return inPeriod(at, from, to)
+}
+
+func inPeriod(at, from, to time.Time) bool {
+	return (from.IsZero() || !at.Before(from)) && (to.IsZero() || at.Before(to))
+}
+
+// validateStationLifecycle 校验站点类型与启用、撤销日期，类型为空表示沿用现有值或默认值
+func validateStationLifecycle(s Station) error {
+	if s.Type != "" && !isValidStationType(s.Type) {
+		return fmt.Errorf("invalid type %q (national, regional, micro)", s.Type)
+	}
+	var from, to time.Time
+	var err error
+	if s.ActiveFrom != "" {
+		if from, err = time.Parse(stationDateLayout, s.ActiveFrom); err != nil {
+			return fmt.Errorf("invalid active_from %q, expected YYYY-MM-DD", s.ActiveFrom)
+		}
+	}
+	if s.ActiveTo != "" {
+		if to, err = time.Parse(stationDateLayout, s.ActiveTo); err != nil {
+			return fmt.Errorf("invalid active_to %q, expected YYYY-MM-DD", s.ActiveTo)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return fmt.Errorf("active_to is before active_from")
+	}
+	return nil
+}
+
+// StationPosition 为站点迁移前的位置，有效期为 [ValidFrom, ValidTo)
+type StationPosition struct {
+	StationID   string     `json:"station_id"`
+	Longitude   float64    `json:"longitude"`
+	Latitude    float64    `json:"latitude"`
+	CoordSystem string     `json:"coord_system"`
+	ValidFrom   *time.Time `json:"valid_from,omitempty"`
+	ValidTo     time.Time  `json:"valid_to"`
+}
+
+// loadStationPositions 读取位置历史，stationID 为空时读取全部；同一站点按 valid_to 升序，
+// ValidFrom 取上一条记录的 valid_to
+func loadStationPositions(stationID string) ([]StationPosition, error) {
+	query := "SELECT station_id, longitude, latitude, coord_system, valid_to FROM station_positions"
+	args := []interface{}{}
+	if stationID != "" {
+		query += " WHERE station_id = ?"
+		args = append(args, stationID)
+	}
+	rows, err := db.Query(query+" ORDER BY station_id, valid_to, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := []StationPosition{}
+	for rows.Next() {
+		var p StationPosition
+		if err := rows.Scan(&p.StationID, &p.Longitude, &p.Latitude, &p.CoordSystem, &p.ValidTo); err != nil {
+			return nil, err
+		}
+		p.Longitude, p.Latitude = convertCoordinate(p.Longitude, p.Latitude, p.CoordSystem, storageCoordSystem)
+		p.CoordSystem = storageCoordSystem
+		positions = append(positions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	linkStationPositions(positions)
+	return positions, nil
+}
+
+// linkStationPositions 按站点与 valid_to 排序，并以上一条记录的 valid_to 作为 ValidFrom
+func linkStationPositions(positions []StationPosition) {
+	sort.SliceStable(positions, func(i, j int) bool {
+		if positions[i].StationID != positions[j].StationID {
+			return positions[i].StationID < positions[j].StationID
+		}
+		return positions[i].ValidTo.Before(positions[j].ValidTo)
+	})
+	for i := range positions {
+		positions[i].ValidFrom = nil
+		if i > 0 && positions[i-1].StationID == positions[i].StationID {
+			from := positions[i-1].ValidTo
+			positions[i].ValidFrom = &from
+		}
+	}
+}
+
+// recordStationMove 站点坐标变化时记录旧位置，movedAt 为迁移时间
+func recordStationMove(tx *sql.Tx, before Station, after Station, movedAt time.Time) error {
+	if before.Longitude == after.Longitude && before.Latitude == after.Latitude {
+		return nil
+	}
+	_, err := tx.Exec("INSERT INTO station_positions (station_id, longitude, latitude, coord_system, valid_to) VALUES (?, ?, ?, ?, ?)",
+		before.ID, before.Longitude, before.Latitude, storageCoordSystem, movedAt)
+	return err
+}
+
+// parseMovedAt 读取 moved_at 参数，未提供时为当前时间
+func parseMovedAt(r *http.Request) (time.Time, error) {
+	value := r.URL.Query().Get("moved_at")
+	if value == "" {
+		return time.Now(), nil
+	}
+	return parseFilterTime(value)
+}
+
+// getStationPositions 返回站点的位置历史（不含当前位置）：GET /api/stations/{id}/positions?coord_system=
+func getStationPositions(w http.ResponseWriter, r *http.Request) {
+	coordSystem, err := coordSystemParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if _, err := loadStationByID(id); err == sql.ErrNoRows {
+		http.Error(w, "Station not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	positions, err := loadStationPositions(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range positions {
+		p := &positions[i]
+		p.Longitude, p.Latitude = convertCoordinate(p.Longitude, p.Latitude, p.CoordSystem, coordSystem)
+		p.CoordSystem = coordSystem
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(positions)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func localTime(value string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestStationActiveAt(t *testing.T) {
+	station := Station{ID: "M2874", ActiveFrom: "2021-03-01", ActiveTo: "2023-06-30"}
+	tests := []struct {
+		at   string
+		want bool
+	}{
+		{"2021-02-28 23:59", false},
+		{"2021-03-01 00:00", true},
+		{"2023-06-30 23:59", true}, // 撤销日期当天仍在运行
+		{"2023-07-01 00:00", false},
+	}
+	for _, tt := range tests {
+		if got := stationActiveAt(station, localTime(tt.at)); got != tt.want {
+			t.Errorf("stationActiveAt(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+	if !stationActiveAt(Station{ID: "58354"}, time.Now()) {
+		t.Error("station without active period should always be active")
+	}
+}
+
+func TestLinkStationPositions(t *testing.T) {
+	positions := []StationPosition{
+		{StationID: "B", ValidTo: localTime("2022-01-01 00:00")},
+		{StationID: "A", ValidTo: localTime("2023-01-01 00:00")},
+		{StationID: "A", ValidTo: localTime("2021-01-01 00:00")},
+	}
+	linkStationPositions(positions)
+
+	ids := []string{positions[0].StationID, positions[1].StationID, positions[2].StationID}
+	if !reflect.DeepEqual(ids, []string{"A", "A", "B"}) {
+		t.Fatalf("unexpected order: %v", ids)
+	}
+	if positions[0].ValidFrom != nil || positions[2].ValidFrom != nil {
+		t.Error("first position of each station should have no valid_from")
+	}
+	if positions[1].ValidFrom == nil || !positions[1].ValidFrom.Equal(positions[0].ValidTo) {
+		t.Errorf("valid_from = %v, want %v", positions[1].ValidFrom, positions[0].ValidTo)
+	}
+}
+
+func TestStationIndexRespectsLifecycle(t *testing.T) {
+	stations := []Station{
+		// 2022 年从 120.30 迁至 120.50
+		{ID: "MOVED", Longitude: 120.50, Latitude: 31.50},
+		// 2023 年 6 月底撤销
+		{ID: "RETIRED", Longitude: 120.32, Latitude: 31.50, ActiveTo: "2023-06-30"},
+		// 2024 年新建
+		{ID: "NEW", Longitude: 120.31, Latitude: 31.50, ActiveFrom: "2024-01-01"},
+		{ID: "FAR", Longitude: 121.00, Latitude: 31.50},
+	}
+	history := []StationPosition{
+		{StationID: "MOVED", Longitude: 120.30, Latitude: 31.50, ValidTo: localTime("2022-05-01 00:00")},
+		{StationID: "GONE", Longitude: 120.30, Latitude: 31.50, ValidTo: localTime("2022-05-01 00:00")},
+	}
+	idx := NewStationIndex(stations, history, 0)
+	if idx.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", idx.Len())
+	}
+
+	tests := []struct {
+		at   string
+		want []string
+	}{
+		{"2021-06-01 12:00", []string{"MOVED", "RETIRED", "FAR"}},
+		{"2023-03-01 12:00", []string{"RETIRED", "MOVED", "FAR"}},
+		{"2023-09-01 12:00", []string{"MOVED", "FAR"}},
+		{"2024-09-01 12:00", []string{"NEW", "MOVED", "FAR"}},
+	}
+	for _, tt := range tests {
+		at := localTime(tt.at)
+		nearby := idx.Nearest(120.30, 31.50, 5, 0, at)
+		if got := stationIDs(nearby); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Nearest at %s = %v, want %v", tt.at, got, tt.want)
+		}
+		if got := stationIDs(idx.WithinRadius(120.30, 31.50, 100, at)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("WithinRadius at %s = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	// 迁移前返回当时的位置
+	nearby := idx.Nearest(120.30, 31.50, 1, 0, localTime("2021-06-01 12:00"))
+	if len(nearby) != 1 || nearby[0].Longitude != 120.30 || nearby[0].DistanceKM != 0 {
+		t.Errorf("expected MOVED at its old position, got %+v", nearby)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 站点匹配方式
+const (
+	StationMatchExact  = "exact"  // 站名或别名出现在地点文字中
+	StationMatchPinyin = "pinyin" // 拼音相同，多为同音字误识别
+	StationMatchFuzzy  = "fuzzy"  // 编辑距离相近
+	StationMatchTown   = "town"   // 站名包含地点所在的乡镇/街道
+)
+
+// 各匹配方式的得分上限
+const (
+	stationScoreExact    = 1.0
+	stationScoreContains = 0.8 // 地点文字是站名的一部分
+	stationScorePinyin   = 0.85
+	stationScoreFuzzy    = 0.75
+	stationScoreTown     = 0.6
+	stationMinScore      = 0.5
+)
+
+// stationNameSuffixes 为站名中不参与匹配的后缀
+var stationNameSuffixes = []string{"国家基本气象站", "气象站", "自动站", "本站", "站"}
+
+// StationWithAliases 为站点及其别名
+type StationWithAliases struct {
+	Station
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// StationMatch 为一条站点候选
+type StationMatch struct {
+	Station   Station `json:"station"`
+	Score     float64 `json:"score"`
+	MatchedBy string  `json:"matched_by"`
+	Matched   string  `json:"matched"` // 命中的站名或别名
+}
+
+// matchStations 将 OCR 地点文字与站名、别名比对，按得分从高到低返回至多 limit 个候选
+func matchStations(text string, stations []StationWithAliases, limit int) []StationMatch {
+	parsed := gazetteer.Normalize(text)
+	if parsed.Normalized == "" {
+		return []StationMatch{}
+	}
+	query := stationQuery{
+		text: parsed.Normalized,
+		// 去掉省市前缀，避免“无锡本站”等站名因城市名误命中
+		subject: gazetteer.TrimRegion(parsed.Normalized),
+		detail:  parsed.Detail,
+		town:    trimAnySuffix(parsed.Town, townSuffixes),
+	}
+
+	matches := []StationMatch{}
+	for _, station := range stations {
+		best := StationMatch{Station: station.Station}
+		for _, name := range append([]string{station.Name}, station.Aliases...) {
+			score, by := query.score(name)
+			if score > best.Score {
+				best.Score, best.MatchedBy, best.Matched = score, by, name
+			}
+		}
+		if best.Score >= stationMinScore {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Station.ID < matches[j].Station.ID
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// stationQuery 为规范化后的地点文字
+type stationQuery struct {
+	text    string // 补全省市后的完整地址
+	subject string // 去掉省市前缀后的地址
+	detail  string // 行政区划之后的剩余地址
+	town    string // 乡镇/街道简称
+}
+
+// score 计算单个站名或别名与地点文字的匹配得分
+func (q stationQuery) score(name string) (float64, string) {
+	name = strings.Join(strings.Fields(name), "")
+	core := trimStationName(name)
+	if core == "" {
+		return 0, ""
+	}
+	subject, detail, town := q.subject, q.detail, q.town
+
+	// 拼音别名直接与地点文字的拼音比较
+	if isASCII(core) {
+		alias := strings.ToLower(strings.ReplaceAll(core, " ", ""))
+		if len(alias) >= 4 && strings.Contains(strings.Join(toPinyin(subject), ""), alias) {
+			return stationScorePinyin, StationMatchPinyin
+		}
+		return 0, ""
+	}
+
+	coreRunes := []rune(core)
+	subjectRunes := []rune(subject)
+	if (len(coreRunes) >= 2 && strings.Contains(subject, core)) || strings.Contains(q.text, name) {
+		return stationScoreExact, StationMatchExact
+	}
+	for _, part := range []string{subject, detail} {
+		part = trimStationName(part)
+		if n := len([]rune(part)); n >= 2 && strings.Contains(core, part) {
+			// 地点文字只覆盖站名的一部分时按覆盖比例降低得分
+			return stationScoreContains * (0.5 + 0.5*float64(n)/float64(len(coreRunes))), StationMatchExact
+		}
+	}
+
+	if len(coreRunes) >= 2 && containsSequence(toPinyin(subject), toPinyin(core)) {
+		return stationScorePinyin, StationMatchPinyin
+	}
+
+	if len(coreRunes) >= 3 {
+		if d := minWindowDistance(subjectRunes, coreRunes); d <= len(coreRunes)/3 {
+			similarity := 1 - float64(d)/float64(len(coreRunes))
+			return stationScoreFuzzy * similarity, StationMatchFuzzy
+		}
+	}
+
+	if len([]rune(town)) >= 2 && strings.Contains(core, town) {
+		return stationScoreTown, StationMatchTown
+	}
+	return 0, ""
+}
+
+func trimStationName(name string) string {
+	name = strings.Join(strings.Fields(name), "")
+	for _, suffix := range stationNameSuffixes {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && len([]rune(trimmed)) >= 2 {
+			return trimmed
+		}
+	}
+	return name
+}
+
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > 127 {
+			return false
+		}
+	}
+	return true
+}
+
+// containsSequence 判断 needle 是否为 haystack 的连续子序列
+func containsSequence(haystack, needle []string) bool {
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if strings.Join(haystack[i:i+len(needle)], " ") == strings.Join(needle, " ") {
+			return true
+		}
+	}
+	return false
+}
+
+// minWindowDistance 返回 needle 与 haystack 中长度相近的各子串之间的最小编辑距离
+func minWindowDistance(haystack, needle []rune) int {
+	best := len(needle)
+	for size := len(needle) - 1; size <= len(needle)+1; size++ {
+		if size <= 0 {
+			continue
+		}
+		for i := 0; i+size <= len(haystack); i++ {
+			if d := editDistance(haystack[i:i+size], needle); d < best {
+				best = d
+			}
+		}
+	}
+	return best
+}
+
+// editDistance 计算 Levenshtein 距离
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// loadStationsWithAliases 读取全部站点及其别名
+func loadStationsWithAliases() ([]StationWithAliases, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.name, s.longitude, s.latitude, s.station_type, s.active_from, s.active_to, s.coord_system, a.alias
+		FROM stations s
+		LEFT JOIN station_aliases a ON a.station_id = s.id
+		ORDER BY s.id, a.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stations := []StationWithAliases{}
+	for rows.Next() {
+		var alias sql.NullString
+		station, err := scanStation(rows, &alias)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(stations); n == 0 || stations[n-1].ID != station.ID {
+			stations = append(stations, StationWithAliases{Station: station})
+		}
+		if alias.Valid {
+			last := &stations[len(stations)-1]
+			last.Aliases = append(last.Aliases, alias.String)
+		}
+	}
+	return stations, rows.Err()
+}
+
+// matchStationHandler 根据地点文字或图片的 ocr_location 推荐站点：
+// GET /api/stations/match?text=...&limit=5&coord_system= 或 ?image_id=...
+func matchStationHandler(w http.ResponseWriter, r *http.Request) {
+	text := strings.TrimSpace(r.URL.Query().Get("text"))
+	if imageIDStr := r.URL.Query().Get("image_id"); text == "" && imageIDStr != "" {
+		imageID, err := strconv.Atoi(imageIDStr)
+		if err != nil {
+			http.Error(w, "Invalid image_id", http.StatusBadRequest)
+			return
+		}
+		var location sql.NullString
+		err = db.QueryRow("SELECT ocr_location FROM images WHERE id = ?", imageID).Scan(&location)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Image not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		text = location.String
+	}
+	if text == "" {
+		http.Error(w, "text or image_id with ocr_location is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 5
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	coordSystem, err := coordSystemParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stations, err := loadStationsWithAliases()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	matches := matchStations(text, stations, limit)
+	for i := range matches {
+		matches[i].Station = matches[i].Station.inCoordSystem(coordSystem)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matches)
+}
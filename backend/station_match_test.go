@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func testMatchStations() []StationWithAliases {
+	return []StationWithAliases{
+		{Station: Station{ID: "58354", Name: "无锡本站"}},
+		{Station: Station{ID: "M2881", Name: "丁蜀镇兰山茶场"}},
+		{Station: Station{ID: "M2890", Name: "丁蜀镇陶都中学"}},
+		{Station: Station{ID: "M3001", Name: "羊尖"}, Aliases: []string{"严家桥", "yangjian"}},
+		{Station: Station{ID: "M3002", Name: "西石桥"}},
+		{Station: Station{ID: "M3003", Name: "鸿山街道办"}},
+	}
+}
+
+func TestMatchStations(t *testing.T) {
+	stations := testMatchStations()
+
+	tests := []struct {
+		name      string
+		text      string
+		wantID    string
+		wantBy    string
+		wantCount int // 0 表示不检查
+	}{
+		{"exact name", "江苏省无锡市锡山区羊尖镇", "M3001", StationMatchExact, 0},
+		{"alias", "锡山区严家桥村", "M3001", StationMatchExact, 0},
+		{"homophone", "杨尖", "M3001", StationMatchPinyin, 0},
+		{"one wrong character", "江阴市西右桥", "M3002", StationMatchFuzzy, 0},
+		{"partial station name", "兰山茶场", "M2881", StationMatchExact, 0},
+		{"town only", "宜兴市丁蜀镇", "M2881", StationMatchTown, 2},
+		{"city name alone does not match", "江苏省无锡市", "", "", 0},
+		{"full name containing city", "无锡本站", "58354", StationMatchExact, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := matchStations(tt.text, stations, 5)
+			if tt.wantID == "" {
+				if len(matches) != 0 {
+					t.Fatalf("expected no matches, got %+v", matches)
+				}
+				return
+			}
+			if len(matches) == 0 {
+				t.Fatalf("no matches for %q", tt.text)
+			}
+			if matches[0].Station.ID != tt.wantID || matches[0].MatchedBy != tt.wantBy {
+				t.Errorf("top match = %+v, want %s by %s", matches[0], tt.wantID, tt.wantBy)
+			}
+			if tt.wantCount > 0 && len(matches) != tt.wantCount {
+				t.Errorf("got %d matches, want %d: %+v", len(matches), tt.wantCount, matches)
+			}
+			for i := 1; i < len(matches); i++ {
+				if matches[i].Score > matches[i-1].Score {
+					t.Errorf("matches not sorted by score: %+v", matches)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchStationsLimit(t *testing.T) {
+	matches := matchStations("丁蜀镇", testMatchStations(), 1)
+	if len(matches) != 1 {
+		t.Errorf("expected limit to cap results, got %d", len(matches))
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"西石桥", "西石桥", 0},
+		{"西石桥", "西石乔", 1},
+		{"羊尖", "羊尖镇", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.expected {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestToPinyin(t *testing.T) {
+	got := toPinyin("杨尖A站")
+	want := []string{"yang", "jian", "a", "zhan"}
+	if len(got) != len(want) {
+		t.Fatalf("toPinyin = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("toPinyin = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// 每度纬度对应的距离（公里），与 haversineDistance 使用相同的地球半径
+const kmPerDegreeLat = 6371 * math.Pi / 180
+
+// NearbyStation 为附近站点及其相对查询点的距离与方位
+type NearbyStation struct {
+	Station
+	DistanceKM float64 `json:"distance_km"`
+	Bearing    float64 `json:"bearing"`   // 从查询点指向站点的方位角，正北为 0，顺时针
+	Direction  string  `json:"direction"` // 八方位，如“东北”
+}
+
+var compassDirections = []string{"北", "东北", "东", "东南", "南", "西南", "西", "西北"}
+
+// initialBearing 计算从 (lon1, lat1) 指向 (lon2, lat2) 的初始方位角（度）
+func initialBearing(lon1, lat1, lon2, lat2 float64) float64 {
+	lat1Rad := lat1 * math.Pi / 180.0
+	lat2Rad := lat2 * math.Pi / 180.0
+	dLon := (lon2 - lon1) * math.Pi / 180.0
+
+	y := math.Sin(dLon) * math.Cos(lat2Rad)
+	x := math.Cos(lat1Rad)*math.Sin(lat2Rad) - math.Sin(lat1Rad)*math.Cos(lat2Rad)*math.Cos(dLon)
+	bearing := math.Atan2(y, x) * 180.0 / math.Pi
+	return math.Mod(bearing+360, 360)
+}
+
+// compassDirection 将方位角转换为八方位
+func compassDirection(bearing float64) string {
+	index := int(math.Round(bearing/45)) % len(compassDirections)
+	return compassDirections[index]
+}
+
+// boundingBox 返回以 (lon, lat) 为中心、半径 km 的经纬度范围，用于预筛选
+func boundingBox(lon, lat, km float64) (minLon, maxLon, minLat, maxLat float64) {
+	// 沿纬线的距离略大于大圆距离，留出余量
+	km *= 1.01
+	dLat := km / kmPerDegreeLat
+	dLon := 180.0
+	if cos := math.Cos(lat * math.Pi / 180.0); cos > 1e-6 {
+		dLon = km / (kmPerDegreeLat * cos)
+	}
+	return lon - dLon, lon + dLon, lat - dLat, lat + dLat
+}
+
+// rankNearestStations 按距离从近到远返回至多 k 个站点；maxKM > 0 时只保留该半径内的站点
+func rankNearestStations(lon, lat float64, stations []Station, k int, maxKM float64) []NearbyStation {
+	nearby := []NearbyStation{}
+	for _, station := range stations {
+		distance := haversineDistance(lon, lat, station.Longitude, station.Latitude)
+		if maxKM > 0 && distance > maxKM {
+			continue
+		}
+		bearing := initialBearing(lon, lat, station.Longitude, station.Latitude)
+		nearby = append(nearby, NearbyStation{
+			Station:    station,
+			DistanceKM: math.Round(distance*1000) / 1000,
+			Bearing:    math.Round(bearing*10) / 10,
+			Direction:  compassDirection(bearing),
+		})
+	}
+
+	// 同一位置的多个微站按编号排序，保证结果稳定
+	sort.SliceStable(nearby, func(i, j int) bool {
+		if nearby[i].DistanceKM != nearby[j].DistanceKM {
+			return nearby[i].DistanceKM < nearby[j].DistanceKM
+		}
+		return nearby[i].ID < nearby[j].ID
+	})
+	if k > 0 && len(nearby) > k {
+		nearby = nearby[:k]
+	}
+	return nearby
+}
+
+// validCoordinate 判断经纬度是否为有限值且在取值范围内
+func validCoordinate(lon, lat float64) bool {
+	return isFinite(lon) && isFinite(lat) && lon >= -180 && lon <= 180 && lat >= -90 && lat <= 90
+}
+
+// findNearestStations 通过站点内存索引查询 at 时刻运行的附近站点，at 为零值时取当前时间
+func findNearestStations(lon, lat float64, k int, maxKM float64, at time.Time) ([]NearbyStation, error) {
+	idx, err := currentStationIndex()
+	if err != nil {
+		return nil, err
+	}
+	return idx.Nearest(lon, lat, k, maxKM, at), nil
+}
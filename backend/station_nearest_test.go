@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInitialBearing(t *testing.T) {
+	tests := []struct {
+		name      string
+		lon, lat  float64
+		expected  float64
+		direction string
+	}{
+		{"north", 120.3, 31.6, 0, "北"},
+		{"east", 120.4, 31.5, 90, "东"},
+		{"south", 120.3, 31.4, 180, "南"},
+		{"west", 120.2, 31.5, 270, "西"},
+		{"north east", 120.35, 31.55, 40.4, "东北"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := initialBearing(120.3, 31.5, tt.lon, tt.lat)
+			if math.Abs(got-tt.expected) > 1 {
+				t.Errorf("initialBearing = %.2f, want about %.0f", got, tt.expected)
+			}
+			if dir := compassDirection(got); dir != tt.direction {
+				t.Errorf("compassDirection(%.2f) = %s, want %s", got, dir, tt.direction)
+			}
+		})
+	}
+
+	if dir := compassDirection(359); dir != "北" {
+		t.Errorf("compassDirection(359) = %s, want 北", dir)
+	}
+}
+
+func TestRankNearestStations(t *testing.T) {
+	stations := []Station{
+		{ID: "FAR", Name: "远处", Longitude: 120.80, Latitude: 31.50},
+		{ID: "QDL27", Name: "微站27", Longitude: 120.3001, Latitude: 31.5001},
+		{ID: "QDL08", Name: "微站08", Longitude: 120.3001, Latitude: 31.5001},
+		{ID: "MID", Name: "中间", Longitude: 120.35, Latitude: 31.50},
+	}
+
+	nearby := rankNearestStations(120.30, 31.50, stations, 3, 0)
+	ids := []string{}
+	for _, s := range nearby {
+		ids = append(ids, s.ID)
+	}
+	if len(ids) != 3 || ids[0] != "QDL08" || ids[1] != "QDL27" || ids[2] != "MID" {
+		t.Fatalf("unexpected order: %v", ids)
+	}
+	if nearby[0].DistanceKM > 0.02 || nearby[2].DistanceKM < 4 || nearby[2].DistanceKM > 5 {
+		t.Errorf("unexpected distances: %+v", nearby)
+	}
+	if nearby[2].Direction != "东" {
+		t.Errorf("expected MID to the east, got %s", nearby[2].Direction)
+	}
+
+	within := rankNearestStations(120.30, 31.50, stations, 10, 10)
+	if len(within) != 3 {
+		t.Errorf("expected FAR to be excluded by max_km, got %d stations", len(within))
+	}
+}
+
+func TestBoundingBoxContainsRadius(t *testing.T) {
+	minLon, maxLon, minLat, maxLat := boundingBox(120.3, 31.5, 10)
+	// 范围边缘到中心的距离应不小于半径，避免漏掉站点
+	if d := haversineDistance(120.3, 31.5, maxLon, 31.5); d < 10 {
+		t.Errorf("longitude span too small: %.3f km", d)
+	}
+	if d := haversineDistance(120.3, 31.5, 120.3, minLat); d < 10 {
+		t.Errorf("latitude span too small: %.3f km", d)
+	}
+	if minLon >= 120.3 || maxLat <= 31.5 {
+		t.Errorf("unexpected box: %f %f %f %f", minLon, maxLon, minLat, maxLat)
+	}
+}
+
+func TestGetNearestStationRejectsInvalidCoordinates(t *testing.T) {
+	for _, query := range []string{
+		"longitude=NaN&latitude=31",
+		"longitude=120&latitude=Inf",
+		"longitude=1e300&latitude=31",
+		"longitude=120&latitude=91",
+		"longitude=-181&latitude=31",
+		"longitude=120&latitude=31&max_km=NaN",
+		"longitude=120&latitude=31&max_km=Inf",
+	} {
+		rec := httptest.NewRecorder()
+		getNearestStation(rec, httptest.NewRequest(http.MethodGet, "/api/stations/nearest?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
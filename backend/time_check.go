@@ -0,0 +1,76 @@
+package main
+
+import (
+	"time"
+)
+
+// 时间一致性标记
+const (
+	TimeFlagFuture         = "future"          // 识别时间晚于上传时间
+	TimeFlagEXIFMismatch   = "exif_mismatch"   // 与 EXIF 拍摄时间相差超过容差
+	TimeFlagUploadMismatch = "upload_mismatch" // 早于上传时间超过容差，常见于水印年份错误
+	TimeFlagUnparsable     = "unparsable"      // ocr_time 无法解析
+)
+
+const (
+	defaultTimeCheckEXIFToleranceHours  = 24
+	defaultTimeCheckUploadToleranceDays = 0 // 历史照片常在拍摄很久后才上传，默认不检查
+	defaultTimeCheckFutureSkewMinutes   = 10
+)
+
+// TimeCheckConfig 为时间一致性检查的容差
+type TimeCheckConfig struct {
+	EXIFTolerance   time.Duration // ocr_time 与 EXIF 拍摄时间的最大差值
+	UploadTolerance time.Duration // ocr_time 早于 uploaded_at 的最大差值，0 表示不检查
+	FutureSkew      time.Duration // 允许 ocr_time 晚于 uploaded_at 的时钟误差
+}
+
+var timeCheckConfig = TimeCheckConfig{
+	EXIFTolerance:   defaultTimeCheckEXIFToleranceHours * time.Hour,
+	UploadTolerance: defaultTimeCheckUploadToleranceDays * 24 * time.Hour,
+	FutureSkew:      defaultTimeCheckFutureSkewMinutes * time.Minute,
+}
+
+// loadTimeCheckConfig 从环境变量读取容差配置
+func loadTimeCheckConfig() TimeCheckConfig {
+	return TimeCheckConfig{
+		EXIFTolerance:   time.Duration(getEnvFloat("TIME_CHECK_EXIF_TOLERANCE_HOURS", defaultTimeCheckEXIFToleranceHours) * float64(time.Hour)),
+		UploadTolerance: time.Duration(getEnvFloat("TIME_CHECK_UPLOAD_TOLERANCE_DAYS", defaultTimeCheckUploadToleranceDays) * float64(24*time.Hour)),
+		FutureSkew:      time.Duration(getEnvInt("TIME_CHECK_FUTURE_SKEW_MINUTES", defaultTimeCheckFutureSkewMinutes)) * time.Minute,
+	}
+}
+
+// checkImageTime 比较 ocr_datetime、EXIF 拍摄时间与上传时间，返回可疑项；没有 ocr_time 时不做检查，
+// ocr_time 存在但 ocr_datetime 为空表示识别时无法解析
+func checkImageTime(ocrTime string, ocrDateTime, exifTime *time.Time, uploadedAt time.Time, cfg TimeCheckConfig) []string {
+	if ocrTime == "" {
+		return nil
+	}
+	if ocrDateTime == nil {
+		return []string{TimeFlagUnparsable}
+	}
+	parsed := *ocrDateTime
+
+	flags := []string{}
+	if !uploadedAt.IsZero() {
+		if parsed.After(uploadedAt.Add(cfg.FutureSkew)) {
+			flags = append(flags, TimeFlagFuture)
+		} else if cfg.UploadTolerance > 0 && uploadedAt.Sub(parsed) > cfg.UploadTolerance {
+			flags = append(flags, TimeFlagUploadMismatch)
+		}
+	}
+	if exifTime != nil && absDuration(parsed.Sub(*exifTime)) > cfg.EXIFTolerance {
+		flags = append(flags, TimeFlagEXIFMismatch)
+	}
+	if len(flags) == 0 {
+		return nil
+	}
+	return flags
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
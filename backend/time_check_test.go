@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckImageTime(t *testing.T) {
+	cfg := TimeCheckConfig{
+		EXIFTolerance:   24 * time.Hour,
+		UploadTolerance: 30 * 24 * time.Hour,
+		FutureSkew:      10 * time.Minute,
+	}
+	uploaded := time.Date(2024, 1, 20, 9, 0, 0, 0, time.Local)
+	exif := time.Date(2024, 1, 15, 14, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		ocrTime  string
+		exifTime *time.Time
+		expected []string
+	}{
+		{"no ocr time", "", &exif, nil},
+		{"consistent", "2024-01-15 14:00:00", &exif, nil},
+		{"consistent without exif", "2024-01-15 14:00:00", nil, nil},
+		{"within clock skew", "2024-01-20 09:05:00", nil, nil},
+		{"future", "2024-01-21 09:00:00", nil, []string{TimeFlagFuture}},
+		{"wrong year", "2023-01-15 14:30:00", &exif, []string{TimeFlagUploadMismatch, TimeFlagEXIFMismatch}},
+		{"exif mismatch only", "2024-01-18 14:30:00", &exif, []string{TimeFlagEXIFMismatch}},
+		{"future and exif mismatch", "2025-01-15 14:30:00", &exif, []string{TimeFlagFuture, TimeFlagEXIFMismatch}},
+		{"unparsable", "下午3点", &exif, []string{TimeFlagUnparsable}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ocrDateTime *time.Time
+			if parsed, err := parseOCRTime(tt.ocrTime, time.Local); err == nil {
+				ocrDateTime = &parsed
+			}
+			got := checkImageTime(tt.ocrTime, ocrDateTime, tt.exifTime, uploaded, cfg)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("checkImageTime(%q) = %v, want %v", tt.ocrTime, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckImageTimeUploadToleranceDisabled(t *testing.T) {
+	uploaded := time.Date(2024, 1, 20, 9, 0, 0, 0, time.Local)
+	ocrDateTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	got := checkImageTime("2020-01-01 00:00:00", &ocrDateTime, nil, uploaded, TimeCheckConfig{EXIFTolerance: time.Hour})
+	if got != nil {
+		t.Errorf("expected no flags with upload check disabled, got %v", got)
+	}
+}
+
+func TestLoadTimeCheckConfigUploadCheckOffByDefault(t *testing.T) {
+	t.Setenv("TIME_CHECK_UPLOAD_TOLERANCE_DAYS", "")
+	if cfg := loadTimeCheckConfig(); cfg.UploadTolerance != 0 {
+		t.Errorf("expected upload check disabled by default, got %v", cfg.UploadTolerance)
+	}
+}
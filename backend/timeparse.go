@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ocrTimeLayout = "2006-01-02 15:04:05"
+
+// errMissingDate 表示只识别出时刻（如“下午3点”），没有日期
+var errMissingDate = errors.New("time has no date")
+
+var (
+	chineseDigits = map[rune]int{
+		'〇': 0, '零': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4,
+		'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+	}
+	chineseNumeralRun = regexp.MustCompile(`[〇零一二两三四五六七八九十]+`)
+	weekdayPattern    = regexp.MustCompile(`(星期|周|礼拜)[一二三四五六日天1-7]`)
+	isoTSeparator     = regexp.MustCompile(`(\d)T(\d)`)
+	separatorSpaces   = regexp.MustCompile(`\s*([-/:])\s*`)
+	missingSpace      = regexp.MustCompile(`^(\d{2,4}[-/.]\d{1,2}[-/.]\d{1,2})(\d{2}:\d{2})`)
+	tzSuffixPattern   = regexp.MustCompile(`(?i)^(.*\d:\d{2}(?::\d{2})?)\s*(Z|UTC|GMT|CST|(?:UTC|GMT)?[+-]\d{1,2}(?::?\d{2})?)$`)
+	timeOnlyPattern   = regexp.MustCompile(`^\d{1,2}(:\d{1,2}){0,2}$`)
+	dateTimePattern   = regexp.MustCompile(`^(\d{2}|\d{4})[-/.](\d{1,2})[-/.](\d{1,2})(?:\s+(\d{1,2})(?::(\d{1,2}))?(?::(\d{1,2}))?)?$`)
+)
+
+// meridiem 为上午/下午等 12 小时制标记
+type meridiem int
+
+const (
+	meridiemNone meridiem = iota
+	meridiemAM
+	meridiemNoon
+	meridiemPM
+	meridiemNight // 晚上：12 点为次日零点
+)
+
+// meridiemMarkers 按匹配优先级排列，较长的标记在前
+var meridiemMarkers = []struct {
+	text  string
+	value meridiem
+}{
+	{"凌晨", meridiemAM}, {"早上", meridiemAM}, {"早晨", meridiemAM}, {"上午", meridiemAM},
+	{"中午", meridiemNoon},
+	{"下午", meridiemPM}, {"傍晚", meridiemPM}, {"晚上", meridiemNight},
+	{"A.M.", meridiemAM}, {"P.M.", meridiemPM}, {"AM", meridiemAM}, {"PM", meridiemPM},
+}
+
+// parseOCRTime 将水印中识别出的时间解析为 time.Time，未带时区时使用 loc。
+// 支持中文日期、中文数字、全角字符、上午/下午与 AM/PM、两位年份、紧凑数字格式和时区后缀，
+// 并校验年月日时分秒的取值范围。
+func parseOCRTime(raw string, loc *time.Location) (time.Time, error) {
+	s := toHalfWidth(strings.TrimSpace(raw))
+	if s == "" {
+		return time.Time{}, errors.New("empty time")
+	}
+
+	if strings.Contains(s, "北京时间") {
+		s = strings.ReplaceAll(s, "北京时间", "")
+		loc = chinaStandardTime
+	}
+
+	mer := meridiemNone
+	// 仅转换 ASCII 字母，保证 upper 与 s 的字节下标一致
+	upper := asciiUpper(s)
+	for _, marker := range meridiemMarkers {
+		if idx := strings.Index(upper, marker.text); idx >= 0 {
+			mer = marker.value
+			s = s[:idx] + " " + s[idx+len(marker.text):]
+			break
+		}
+	}
+
+	s = weekdayPattern.ReplaceAllString(s, " ")
+	s = chineseToArabic(s)
+	s = strings.NewReplacer(
+		"点半", ":30", "时半", ":30",
+		"年", "-", "月", "-", "日", " ", "号", " ",
+		"时", ":", "点", ":", "分", ":", "秒", "",
+	).Replace(s)
+	s = isoTSeparator.ReplaceAllString(s, "$1 $2")
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.TrimRight(strings.TrimSpace(s), ":-")
+	s = separatorSpaces.ReplaceAllString(s, "$1")
+
+	if m := tzSuffixPattern.FindStringSubmatch(s); m != nil {
+		zone, err := parseTimeZone(m[2])
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = zone
+		s = strings.TrimSpace(m[1])
+	}
+
+	s = expandCompactTime(s)
+	s = missingSpace.ReplaceAllString(s, "$1 $2")
+
+	if timeOnlyPattern.MatchString(s) {
+		return time.Time{}, errMissingDate
+	}
+	m := dateTimePattern.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("unrecognized time format %q", raw)
+	}
+
+	year := atoi(m[1])
+	if len(m[1]) == 2 {
+		year = expandTwoDigitYear(year, time.Now().Year())
+	}
+	month, day := atoi(m[2]), atoi(m[3])
+	hour, minute, second := atoi(m[4]), atoi(m[5]), atoi(m[6])
+
+	if year < 1970 || year > 2100 {
+		return time.Time{}, fmt.Errorf("year %d out of range", year)
+	}
+	if month < 1 || month > 12 {
+		return time.Time{}, fmt.Errorf("month %d out of range", month)
+	}
+	if day < 1 || day > daysIn(year, time.Month(month)) {
+		return time.Time{}, fmt.Errorf("day %d out of range for %d-%02d", day, year, month)
+	}
+	hour, nextDay, err := applyMeridiem(hour, mer, m[4] != "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, fmt.Errorf("time %02d:%02d:%02d out of range", hour, minute, second)
+	}
+
+	parsed := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+	if nextDay {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return parsed, nil
+}
+
+// setTime 解析模型返回的时间并写入 Time 与 DateTime；无法解析时 Time 保留原文并返回错误
+func (r *OCRResult) setTime(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	parsed, err := parseOCRTime(raw, time.Local)
+	if err != nil {
+		r.Time = raw
+		return err
+	}
+	local := parsed.In(time.Local)
+	r.Time = local.Format(ocrTimeLayout)
+	r.DateTime = &local
+	return nil
+}
+
+// chinaStandardTime 为 UTC+8，用于“北京时间”与 CST 后缀
+var chinaStandardTime = time.FixedZone("CST", 8*3600)
+
+// parseTimeZone 解析 Z、UTC、GMT、CST（视为中国标准时间）以及 +08、+0800、+08:00、UTC+8 等后缀
+func parseTimeZone(value string) (*time.Location, error) {
+	upper := strings.ToUpper(value)
+	switch upper {
+	case "Z", "UTC", "GMT":
+		return time.UTC, nil
+	case "CST":
+		return chinaStandardTime, nil
+	}
+	upper = strings.TrimPrefix(strings.TrimPrefix(upper, "UTC"), "GMT")
+	sign := 1
+	if strings.HasPrefix(upper, "-") {
+		sign = -1
+	}
+	digits := strings.ReplaceAll(upper[1:], ":", "")
+	var hours, minutes int
+	switch len(digits) {
+	case 1, 2:
+		hours = atoi(digits)
+	case 3, 4:
+		hours, minutes = atoi(digits[:len(digits)-2]), atoi(digits[len(digits)-2:])
+	default:
+		return nil, fmt.Errorf("invalid time zone %q", value)
+	}
+	if hours > 14 || minutes > 59 {
+		return nil, fmt.Errorf("invalid time zone %q", value)
+	}
+	offset := sign * (hours*3600 + minutes*60)
+	return time.FixedZone(value, offset), nil
+}
+
+// applyMeridiem 将 12 小时制转换为 24 小时制；已是 24 小时制的下午时间保持不变。
+// “晚上12点”为次日零点，此时 nextDay 为 true；“下午12点”与 12 PM 为中午
+func applyMeridiem(hour int, mer meridiem, hasHour bool) (h int, nextDay bool, err error) {
+	if mer == meridiemNone {
+		return hour, false, nil
+	}
+	if !hasHour {
+		return 0, false, errors.New("meridiem without hour")
+	}
+	switch mer {
+	case meridiemAM:
+		if hour > 12 {
+			return 0, false, fmt.Errorf("hour %d invalid in the morning", hour)
+		}
+		if hour == 12 {
+			return 0, false, nil
+		}
+	case meridiemNoon:
+		if hour >= 1 && hour <= 3 {
+			return hour + 12, false, nil
+		}
+	case meridiemPM, meridiemNight:
+		if hour >= 1 && hour < 12 {
+			return hour + 12, false, nil
+		}
+		if hour == 0 {
+			return 0, false, errors.New("hour 0 invalid in the afternoon")
+		}
+		if hour == 12 && mer == meridiemNight {
+			return 0, true, nil
+		}
+	}
+	return hour, false, nil
+}
+
+// expandCompactTime 展开 YYYYMMDD[HHMM[SS]] 紧凑格式，日期与时刻之间可有空格
+func expandCompactTime(s string) string {
+	compact := strings.Replace(s, " ", "", 1)
+	if strings.ContainsAny(compact, " -/.:") || !isDigits(compact) {
+		return s
+	}
+	switch len(compact) {
+	case 14:
+		return fmt.Sprintf("%s-%s-%s %s:%s:%s", compact[0:4], compact[4:6], compact[6:8], compact[8:10], compact[10:12], compact[12:14])
+	case 12:
+		return fmt.Sprintf("%s-%s-%s %s:%s", compact[0:4], compact[4:6], compact[6:8], compact[8:10], compact[10:12])
+	case 8:
+		return fmt.Sprintf("%s-%s-%s", compact[0:4], compact[4:6], compact[6:8])
+	}
+	return s
+}
+
+// expandTwoDigitYear 两位年份不晚于明年时视为 20xx，否则视为 19xx
+func expandTwoDigitYear(year, currentYear int) int {
+	if 2000+year <= currentYear+1 {
+		return 2000 + year
+	}
+	return 1900 + year
+}
+
+// chineseToArabic 将“二〇二四”“十二”“二十三”等中文数字替换为阿拉伯数字
+func chineseToArabic(s string) string {
+	return chineseNumeralRun.ReplaceAllStringFunc(s, func(run string) string {
+		runes := []rune(run)
+		tenIdx := strings.IndexRune(run, '十')
+		if tenIdx < 0 {
+			var b strings.Builder
+			for _, r := range runes {
+				b.WriteString(strconv.Itoa(chineseDigits[r]))
+			}
+			return b.String()
+		}
+
+		before, after := []rune(run[:tenIdx]), []rune(run[tenIdx+len("十"):])
+		if len(before) > 1 || len(after) > 1 || strings.Count(run, "十") > 1 {
+			return run
+		}
+		tens, ones := 1, 0
+		if len(before) == 1 {
+			tens = chineseDigits[before[0]]
+		}
+		if len(after) == 1 {
+			ones = chineseDigits[after[0]]
+		}
+		return strconv.Itoa(tens*10 + ones)
+	})
+}
+
+// toHalfWidth 将全角数字、字母与标点转换为半角
+func toHalfWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\u3000':
+			return ' '
+		case r >= '\uFF01' && r <= '\uFF5E':
+			return r - 0xFEE0
+		}
+		return r
+	}, s)
+}
+
+// asciiUpper 只将 a-z 转为大写，其余字符保持原样，字节长度不变
+func asciiUpper(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - ('a' - 'A')
+		}
+		return r
+	}, s)
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
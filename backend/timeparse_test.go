@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseOCRTime(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Time
+	}{
+		{"standard", "2024-01-15 14:30:45", time.Date(2024, 1, 15, 14, 30, 45, 0, shanghai)},
+		{"chinese date", "2024年1月15日 14时30分", time.Date(2024, 1, 15, 14, 30, 0, 0, shanghai)},
+		{"chinese date with seconds", "2024年01月15日14时30分20秒", time.Date(2024, 1, 15, 14, 30, 20, 0, shanghai)},
+		{"chinese numerals", "二〇二四年一月十五日 下午三点二十分", time.Date(2024, 1, 15, 15, 20, 0, 0, shanghai)},
+		{"half past", "2024年1月15日下午3点半", time.Date(2024, 1, 15, 15, 30, 0, 0, shanghai)},
+		{"morning twelve", "2024-01-15 上午12:05", time.Date(2024, 1, 15, 0, 5, 0, 0, shanghai)},
+		{"noon", "2024-01-15 中午12:10", time.Date(2024, 1, 15, 12, 10, 0, 0, shanghai)},
+		{"pm suffix", "2024/1/15 3:30 PM", time.Date(2024, 1, 15, 15, 30, 0, 0, shanghai)},
+		{"twelve pm is noon", "2024/1/15 12:30 PM", time.Date(2024, 1, 15, 12, 30, 0, 0, shanghai)},
+		{"evening", "2024-01-15 晚上8点", time.Date(2024, 1, 15, 20, 0, 0, 0, shanghai)},
+		{"evening twelve is next midnight", "2024-01-31 晚上12:20", time.Date(2024, 2, 1, 0, 20, 0, 0, shanghai)},
+		{"lower-case am", "2024/1/15 9:05 am", time.Date(2024, 1, 15, 9, 5, 0, 0, shanghai)},
+		{"24h with afternoon marker", "2024-01-15 下午15:30", time.Date(2024, 1, 15, 15, 30, 0, 0, shanghai)},
+		{"weekday", "2024-01-15 星期一 08:00", time.Date(2024, 1, 15, 8, 0, 0, 0, shanghai)},
+		{"full width", "２０２４－０１－１５　１４：３０", time.Date(2024, 1, 15, 14, 30, 0, 0, shanghai)},
+		{"two digit year", "24-01-15 14:30", time.Date(2024, 1, 15, 14, 30, 0, 0, shanghai)},
+		{"dotted date", "2024.01.15 14:30", time.Date(2024, 1, 15, 14, 30, 0, 0, shanghai)},
+		{"compact", "20240115143045", time.Date(2024, 1, 15, 14, 30, 45, 0, shanghai)},
+		{"iso utc", "2024-01-15T06:30:00Z", time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC)},
+		{"offset suffix", "2024-01-15 14:30 +0900", time.Date(2024, 1, 15, 14, 30, 0, 0, time.FixedZone("", 9*3600))},
+		{"utc offset", "2024-01-15 14:30:00 UTC+8", time.Date(2024, 1, 15, 14, 30, 0, 0, shanghai)},
+		{"beijing time", "北京时间 2024-01-15 14:30", time.Date(2024, 1, 15, 14, 30, 0, 0, shanghai)},
+		{"date only", "2024年1月15日", time.Date(2024, 1, 15, 0, 0, 0, 0, shanghai)},
+		{"leap day", "2024-02-29 10:00", time.Date(2024, 2, 29, 10, 0, 0, 0, shanghai)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOCRTime(tt.input, shanghai)
+			if err != nil {
+				t.Fatalf("parseOCRTime(%q) returned error: %v", tt.input, err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("parseOCRTime(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseOCRTimeRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", "  "},
+		{"time only", "下午3点"},
+		{"month out of range", "2023-13-05 10:00"},
+		{"day out of range", "2023-02-29 10:00"},
+		{"hour out of range", "2023-07-20 25:10"},
+		{"minute out of range", "2023-07-20 10:99"},
+		{"morning hour", "2023-07-20 上午15:00"},
+		{"year out of range", "1900-01-01 00:00"},
+		{"garbage", "无法识别"},
+		{"bad time zone", "2024-01-15 14:30 +2500"},
+		{"upper-case changes length", "ɐɐɐɐɐɐ AM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := parseOCRTime(tt.input, time.Local); err == nil {
+				t.Errorf("parseOCRTime(%q) = %v, expected error", tt.input, got)
+			}
+		})
+	}
+
+	if _, err := parseOCRTime("下午3点", time.Local); !errors.Is(err, errMissingDate) {
+		t.Errorf("expected errMissingDate, got %v", err)
+	}
+}
+
+func TestChineseToArabic(t *testing.T) {
+	tests := map[string]string{
+		"二〇二四": "2024",
+		"十":    "10",
+		"十二":   "12",
+		"二十":   "20",
+		"二十三":  "23",
+		"两点":   "2点",
+		"十二十":  "十二十",
+	}
+	for input, expected := range tests {
+		if got := chineseToArabic(input); got != expected {
+			t.Errorf("chineseToArabic(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestExpandTwoDigitYear(t *testing.T) {
+	if got := expandTwoDigitYear(24, 2024); got != 2024 {
+		t.Errorf("expandTwoDigitYear(24) = %d, want 2024", got)
+	}
+	if got := expandTwoDigitYear(25, 2024); got != 2025 {
+		t.Errorf("expandTwoDigitYear(25) = %d, want 2025", got)
+	}
+	if got := expandTwoDigitYear(98, 2024); got != 1998 {
+		t.Errorf("expandTwoDigitYear(98) = %d, want 1998", got)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultVLMMaxRetries    = 3
+	defaultVLMRetryBaseMS   = 500
+	defaultVLMRetryMaxMS    = 30000
+	defaultVLMMaxRetryAfter = 60 * time.Second
+)
+
+// RetryPolicy 描述可重试错误的指数退避策略
+type RetryPolicy struct {
+	MaxRetries    int           // 首次请求之外的最大重试次数
+	BaseDelay     time.Duration // 第一次重试前的基础等待
+	MaxDelay      time.Duration // 单次退避上限
+	MaxRetryAfter time.Duration // 服务端 Retry-After 的上限，避免被异常值长时间阻塞
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxRetries:    defaultVLMMaxRetries,
+	BaseDelay:     defaultVLMRetryBaseMS * time.Millisecond,
+	MaxDelay:      defaultVLMRetryMaxMS * time.Millisecond,
+	MaxRetryAfter: defaultVLMMaxRetryAfter,
+}
+
+// backoff 返回第 attempt 次重试（从 1 开始）前的等待时间，使用 equal jitter
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay * time.Duration(math.Pow(2, float64(attempt-1)))
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isRetryableStatus 判断 HTTP 状态码是否值得重试
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter 解析 Retry-After 头（秒数或 HTTP 日期）
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// tokenBucket 为简单的令牌桶限流器，rate 为每秒补充的令牌数
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve 预占一个令牌，返回需要等待的时间
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// vlmLimiter 在所有调用方之间共享的速率与并发限制，nil 表示不限制
+type vlmLimiter struct {
+	bucket *tokenBucket
+	slots  chan struct{}
+}
+
+func newVLMLimiter(ratePerSecond float64, burst, maxConcurrency int) *vlmLimiter {
+	if ratePerSecond <= 0 && maxConcurrency <= 0 {
+		return nil
+	}
+	limiter := &vlmLimiter{}
+	if ratePerSecond > 0 {
+		limiter.bucket = newTokenBucket(ratePerSecond, burst)
+	}
+	if maxConcurrency > 0 {
+		limiter.slots = make(chan struct{}, maxConcurrency)
+	}
+	return limiter
+}
+
+// acquire 等待令牌与并发槽位，返回释放函数；ctx 取消时返回错误
+func (l *vlmLimiter) acquire(ctx context.Context) (func(), error) {
+	noop := func() {}
+	if l == nil {
+		return noop, nil
+	}
+	if l.bucket != nil {
+		if wait := l.bucket.reserve(); wait > 0 {
+			if err := vlmSleep(ctx, wait); err != nil {
+				return noop, err
+			}
+		}
+	}
+	if l.slots == nil {
+		return noop, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return noop, ctx.Err()
+	}
+}
+
+// sleepContext 等待 d 或 ctx 取消
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// vlmSleep 便于测试替换
+var vlmSleep = sleepContext
+
+// vlmTransport 封装 HTTP 客户端、重试策略与共享限流器
+type vlmTransport struct {
+	client  *http.Client
+	retry   RetryPolicy
+	limiter *vlmLimiter
+}
+
+func newVLMTransport(cfg ExtractorConfig) vlmTransport {
+	return vlmTransport{
+		client:  &http.Client{Timeout: cfg.Timeout},
+		retry:   cfg.Retry,
+		limiter: cfg.limiter,
+	}
+}
+
+// do 发送请求并在可重试错误时按退避策略重试，newRequest 每次尝试都会被调用以重建请求体
+func (t vlmTransport) do(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) (int, []byte, error) {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		status, body, retryAfter, err := t.send(ctx, req)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, nil, ctxErr
+		}
+		if err == nil && !isRetryableStatus(status) {
+			return status, body, nil
+		}
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("status %d, body %s", status, logRedactor.Redact(string(body)))
+		}
+
+		if attempt >= t.retry.MaxRetries {
+			if err == nil {
+				// 返回最后一次的响应，由调用方生成错误信息
+				return status, body, nil
+			}
+			return 0, nil, lastErr
+		}
+
+		delay := t.retry.backoff(attempt + 1)
+		if retryAfter > 0 {
+			if t.retry.MaxRetryAfter > 0 && retryAfter > t.retry.MaxRetryAfter {
+				retryAfter = t.retry.MaxRetryAfter
+			}
+			if retryAfter > delay {
+				delay = retryAfter
+			}
+		}
+		slog.WarnContext(ctx, "VLM request failed, retrying", "attempt", attempt+1,
+			"max_attempts", t.retry.MaxRetries+1, "delay", delay, "error", lastErr)
+		if err := vlmSleep(ctx, delay); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+func (t vlmTransport) send(ctx context.Context, req *http.Request) (int, []byte, time.Duration, error) {
+	release, err := t.limiter.acquire(ctx)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	defer release()
+
+	client := t.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// 部分服务商的密钥在 URL 中，错误信息会写入日志与 ocr_results
+		return 0, nil, 0, redactError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return resp.StatusCode, body, retryAfter, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordSleeps 用记录替换 vlmSleep，测试结束后恢复
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var mu sync.Mutex
+	sleeps := []time.Duration{}
+	previous := vlmSleep
+	vlmSleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		sleeps = append(sleeps, d)
+		mu.Unlock()
+		return ctx.Err()
+	}
+	t.Cleanup(func() { vlmSleep = previous })
+	return &sleeps
+}
+
+func newTestQwenClient(t *testing.T, url string, cfg ExtractorConfig) MetadataExtractor {
+	t.Helper()
+	cfg.Provider = "qwen"
+	cfg.APIKey = "key"
+	cfg.BaseURL = url
+	extractor, err := NewMetadataExtractor(cfg)
+	if err != nil {
+		t.Fatalf("NewMetadataExtractor returned error: %v", err)
+	}
+	return extractor
+}
+
+const okChatResponse = `{"choices":[{"message":{"content":"{\"time\":\"2024-01-15 14:30\",\"location\":\"无锡\",\"confidence\":0.9}"}}]}`
+
+func TestQwenClientRetriesRetryableStatus(t *testing.T) {
+	sleeps := recordSleeps(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(okChatResponse))
+		}
+	}))
+	defer server.Close()
+
+	extractor := newTestQwenClient(t, server.URL, ExtractorConfig{
+		Retry: RetryPolicy{MaxRetries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, MaxRetryAfter: time.Minute},
+	})
+
+	result, err := extractor.ExtractMetadata(context.Background(), writeTestImage(t))
+	if err != nil {
+		t.Fatalf("ExtractMetadata returned error: %v", err)
+	}
+	if result.Location != "无锡" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	if len(*sleeps) != 2 {
+		t.Fatalf("expected 2 backoff sleeps, got %v", *sleeps)
+	}
+	if (*sleeps)[0] != 7*time.Second {
+		t.Errorf("expected Retry-After to be respected, slept %s", (*sleeps)[0])
+	}
+	if d := (*sleeps)[1]; d < 100*time.Millisecond || d > 200*time.Millisecond {
+		t.Errorf("second backoff %s outside jittered range", d)
+	}
+}
+
+func TestQwenClientDoesNotRetryClientErrors(t *testing.T) {
+	sleeps := recordSleeps(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	extractor := newTestQwenClient(t, server.URL, ExtractorConfig{Retry: defaultRetryPolicy})
+	if _, err := extractor.ExtractMetadata(context.Background(), writeTestImage(t)); err == nil {
+		t.Fatalf("expected error for 401")
+	}
+	if calls != 1 || len(*sleeps) != 0 {
+		t.Errorf("expected a single attempt, got %d calls and sleeps %v", calls, *sleeps)
+	}
+}
+
+func TestQwenClientGivesUpAfterMaxRetries(t *testing.T) {
+	recordSleeps(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	extractor := newTestQwenClient(t, server.URL, ExtractorConfig{
+		Retry: RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	})
+	if _, err := extractor.ExtractMetadata(context.Background(), writeTestImage(t)); err == nil {
+		t.Fatalf("expected error after retries are exhausted")
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestQwenClientConcurrencyCap(t *testing.T) {
+	var running, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		w.Write([]byte(okChatResponse))
+	}))
+	defer server.Close()
+
+	extractor := newTestQwenClient(t, server.URL, ExtractorConfig{MaxConcurrency: 2})
+	imagePath := writeTestImage(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := extractor.ExtractMetadata(context.Background(), imagePath); err != nil {
+				t.Errorf("ExtractMetadata returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent requests, got %d", peak)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(10, 2)
+	if wait := bucket.reserve(); wait != 0 {
+		t.Errorf("first token should be immediate, got %s", wait)
+	}
+	if wait := bucket.reserve(); wait != 0 {
+		t.Errorf("burst token should be immediate, got %s", wait)
+	}
+	wait := bucket.reserve()
+	if wait < 90*time.Millisecond || wait > 110*time.Millisecond {
+		t.Errorf("expected ~100ms wait once burst is spent, got %s", wait)
+	}
+	wait = bucket.reserve()
+	if wait < 190*time.Millisecond || wait > 210*time.Millisecond {
+		t.Errorf("expected reservations to queue up, got %s", wait)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"Mon, 15 Jan 2024 14:30:10 GMT", 10 * time.Second, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %v; want %s, %v", tt.value, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestQwenClientHonorsContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	extractor := newTestQwenClient(t, server.URL, ExtractorConfig{Retry: defaultRetryPolicy})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := extractor.ExtractMetadata(ctx, writeTestImage(t))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled call took %s", elapsed)
+	}
+}